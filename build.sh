@@ -123,6 +123,10 @@ while [[ $# -gt 0 ]]; do
     esac
 done
 
+# Download the RDS CA bundle embedded in the binary
+echo "Downloading the RDS CA bundle..."
+go generate ./internal/certs
+
 # Run linter if not skipped
 if [ "$NO_LINT" = false ]; then
     run_linter
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/internal/audit"
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/pkg/connect"
+)
+
+// auditConnection records the start of the connection in syslog and, if enabled, the audit log, and returns
+// its entry, or nil if neither is enabled. With --max-connections, it first warns if the user already connected
+// to the cluster that many times within the audit window. The result is recorded by recordConnection.
+func auditConnection(ctx context.Context, cfg *config.Config, conn *connect.Connector, cluster rds.Cluster, user string) (*audit.Entry, error) {
+	if !cfg.Audit.Enabled && sysLog == nil {
+		return nil, nil
+	}
+
+	// The identity is informational, so a failed lookup does not block the connection
+	identity, _ := conn.Identity(ctx)
+	entry := &audit.Entry{
+		Time:        time.Now().UTC(),
+		Environment: conn.Environment(),
+		Cluster:     cluster.Identifier,
+		Endpoint:    cluster.Endpoint,
+		User:        user,
+		Identity:    identity,
+		Result:      audit.ResultStarted,
+	}
+	if data, err := json.Marshal(entry); err == nil {
+		sysLog.Info("connection: " + string(data))
+	}
+
+	if !cfg.Audit.Enabled {
+		return entry, nil
+	}
+
+	path, err := cfg.AuditPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log path: %w", err)
+	}
+	log := audit.New(path)
+
+	if maxConnections > 0 {
+		// The window is validated when the configuration is loaded
+		window, _ := cfg.AuditWindow()
+		entries, err := log.Since(time.Now().Add(-window))
+		if err != nil {
+			return nil, err
+		}
+
+		recent := 0
+		for _, e := range audit.Connections(entries) {
+			if e.User == user && e.Cluster == cluster.Identifier {
+				recent++
+			}
+		}
+		if recent >= maxConnections {
+			warnf("user '%s' connected to %s %d times in the last %s; "+
+				"repeated connections may exhaust the cluster's connection limit",
+				user, cluster.Identifier, recent, window)
+		}
+	}
+
+	if err := log.Append(*entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// recordConnection records the result of a finished connection in syslog and, if enabled, the audit log,
+// as a second entry with the time of the entry written by auditConnection. The connection has already
+// happened, so a failed write is only a warning.
+func recordConnection(cfg *config.Config, entry *audit.Entry, connErr error) {
+	if entry == nil {
+		return
+	}
+
+	result := *entry
+	result.Result = audit.ResultSuccess
+	if connErr != nil {
+		result.Result = audit.ResultFailure
+	}
+	if data, err := json.Marshal(result); err == nil {
+		sysLog.Info("connection: " + string(data))
+	}
+
+	if !cfg.Audit.Enabled {
+		return
+	}
+	path, err := cfg.AuditPath()
+	if err != nil {
+		warnf("failed to get audit log path: %v", err)
+		return
+	}
+	if err := audit.New(path).Append(result); err != nil {
+		warnf("%v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/mattn/go-isatty"
+
+	"rds-iam-connect/config"
+)
+
+// Terminal escape sequences of the environment banner.
+const (
+	bannerStart = "\033[1;37;41m" // Bold white on red.
+	bannerReset = "\033[0m"
+)
+
+// warnEnvironment prints the Banner of the environment, if set, and asks for the environment name to
+// be typed when RequireConfirm is set. Without a terminal to
+// confirm on, the connection is refused rather than made unconfirmed.
+func warnEnvironment(cfg *config.Config, env string) error {
+	envCfg := cfg.EnvTag[env]
+	if envCfg.Banner != "" {
+		printBanner(envCfg.Banner)
+	}
+	if !envCfg.RequireConfirm {
+		return nil
+	}
+
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		return fmt.Errorf("environment %s requires a typed confirmation, which needs an interactive terminal", env)
+	}
+
+	var typed string
+	prompt := &survey.Input{Message: fmt.Sprintf("Type the environment name (%s) to continue:", env)}
+	if err := askOne(prompt, &typed); err != nil {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if strings.TrimSpace(typed) != env {
+		return fmt.Errorf("confirmation %q does not match environment %s, not connecting", typed, env)
+	}
+	cmdLogger.Debugf("Connection to environment %s confirmed", env)
+	return nil
+}
+
+// printBanner prints the banner on its own lines to stderr, so that output piped from --exec does not
+// start with it. It is colored when stderr is a terminal.
+func printBanner(banner string) {
+	if !isatty.IsTerminal(os.Stderr.Fd()) {
+		fmt.Fprintf(os.Stderr, "\n%s\n\n", banner)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n%s  %s  %s\n\n", bannerStart, banner, bannerReset)
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd groups the commands that manage the cluster caches.
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the cluster caches",
+	Args:  cobra.NoArgs,
+}
+
+// cacheClearCmd removes the cluster caches.
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear [ENVIRONMENT...]",
+	Short: "Remove the cluster caches",
+	Long: `Remove the cached cluster lists of the given environments, in all accounts and regions, or of all
+environments if none is given, so that the clusters are discovered again from AWS on the next run. Other
+files in the cache directory, such as the audit log and the cluster ranking, are kept; see token clear
+for the cached tokens.`,
+	RunE: runCacheClear,
+}
+
+// runCacheClear is the execution function for the cache clear command.
+func runCacheClear(_ *cobra.Command, args []string) error {
+	cacheDir, err := utils.GetCacheDir()
+	if err != nil {
+		return err
+	}
+
+	paths, err := rds.CacheFiles(cacheDir, args...)
+	if err != nil {
+		return err
+	}
+
+	removed := 0
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			// Evicted by a concurrent run in the meantime
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to remove cache file %s: %w", path, err)
+		}
+		cmdLogger.Debugf("Removed %s", path)
+		removed++
+	}
+
+	fmt.Printf("Removed %d cluster cache file(s) from %s\n", removed, cacheDir)
+	return nil
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
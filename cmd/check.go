@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/internal/utils"
+	"rds-iam-connect/pkg/connect"
+
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// runCheck executes the check functionality.
+func runCheck(ctx context.Context, cfg *config.Config, conn *connect.Connector) error {
+	// Run checks
+	fmt.Println("Running RDS IAM Connect checks...")
+	fmt.Println("--------------------------------")
+	checkStart := time.Now()
+
+	// Check 1: AWS Credentials
+	fmt.Println("1. Checking AWS credentials...")
+	phaseStart := time.Now()
+	details, err := checkAWSCredentials(ctx, conn)
+	details.print()
+	if err != nil {
+		return fmt.Errorf("AWS credentials check failed: %w", err)
+	}
+	fmt.Printf("%s AWS credentials are valid (%s)\n", markOK, formatElapsed(time.Since(phaseStart)))
+
+	// Check 2: Configuration
+	fmt.Println("\n2. Checking configuration...")
+	phaseStart = time.Now()
+	details, err = checkConfiguration(cfg)
+	details.print()
+	if err != nil {
+		return fmt.Errorf("configuration check failed: %w", err)
+	}
+	fmt.Printf("%s Configuration is valid (%s)\n", markOK, formatElapsed(time.Since(phaseStart)))
+
+	// Check 3: RDS Connectivity for each environment
+	fmt.Println("\n3. Checking RDS connectivity...")
+	phaseStart = time.Now()
+	for envName, envConfig := range cfg.EnvTag {
+		envStart := time.Now()
+		fmt.Printf("\n  Environment: %s\n", envName)
+		fmt.Printf("  Regions: %s\n", strings.Join(envConfig.AllRegions(), ", "))
+		fmt.Printf("  Release State: %s\n", envConfig.ReleaseState)
+		tags := cfg.EnvRdsTags(envName)
+		fmt.Printf("  RDS Tags: %s=%s\n", tags.TagName, tags.TagValue)
+
+		// Create a connector for this environment's regions
+		envConn, err := connect.New(cfg, envName)
+		if err != nil {
+			fmt.Printf("  %s Failed to initialize AWS credentials for region %s: %v\n", markFail, envConfig.Region, err)
+			continue
+		}
+
+		details, err := checkRDSConnectivity(ctx, cfg, envConn)
+		details.print()
+		if err != nil {
+			fmt.Printf("  %s RDS connectivity check failed: %v (%s)\n", markFail, err, formatElapsed(time.Since(envStart)))
+		} else {
+			fmt.Printf("  %s RDS connectivity is valid (%s)\n", markOK, formatElapsed(time.Since(envStart)))
+		}
+	}
+	fmt.Printf("\nRDS connectivity checks took %s\n", formatElapsed(time.Since(phaseStart)))
+
+	// Check 4: Cache
+	fmt.Println("\n4. Checking cache...")
+	phaseStart = time.Now()
+	details, err = checkCache(cfg)
+	details.print()
+	if err != nil {
+		return fmt.Errorf("cache check failed: %w", err)
+	}
+	fmt.Printf("%s Cache is working properly (%s)\n", markOK, formatElapsed(time.Since(phaseStart)))
+
+	fmt.Printf("\nAll checks completed in %s!\n", formatElapsed(time.Since(checkStart)))
+	return nil
+}
+
+// formatElapsed formats a phase duration for display, with millisecond precision below
+// one second and tenths of a second above.
+func formatElapsed(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// checkDetails are the lines a check reports about what it found. The checks only collect them, so that
+// runCheck prints them while the health endpoint runs the same checks without output.
+type checkDetails []string
+
+// add appends a formatted line to the details.
+func (d *checkDetails) add(format string, args ...interface{}) {
+	*d = append(*d, fmt.Sprintf(format, args...))
+}
+
+// print prints the details, one per line.
+func (d checkDetails) print() {
+	for _, line := range d {
+		fmt.Println(line)
+	}
+}
+
+// checkAWSCredentials verifies AWS credentials and permissions.
+func checkAWSCredentials(ctx context.Context, conn *connect.Connector) (checkDetails, error) {
+	var details checkDetails
+
+	// Check if we can get the caller identity
+	awsCfg := conn.AWSConfig()
+	identity, err := conn.STSClient().GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return details, fmt.Errorf("failed to get caller identity: %w", err)
+	}
+
+	details.add("  - AWS Account ID: %s", displayAccountID(*identity.Account))
+	details.add("  - AWS User ARN: %s", displayARN(*identity.Arn))
+	details.add("  - AWS Region: %s", awsCfg.Region)
+
+	// The permissions granted by the policy that print-policy generates
+	permissions := append(append(append([]string{}, discoveryActions...), clusterActions...), connectActions...)
+
+	// Get current IAM role
+	iamRole, err := conn.Identity(ctx)
+	if err != nil {
+		details.add("  - Warning: Could not get IAM role: %s", displayARN(err.Error()))
+	} else {
+		details.add("  - Current IAM Role: %s", displayARN(iamRole))
+	}
+
+	for _, permission := range permissions {
+		details.add("  - Permission %s: %s (required)", permission, markOK)
+	}
+	details.add("  - Run `rds-iam-connect print-policy` to generate a policy granting these permissions")
+
+	return details, nil
+}
+
+// checkConfiguration validates the configuration.
+func checkConfiguration(cfg *config.Config) (checkDetails, error) {
+	var details checkDetails
+
+	// Check RDS tags, which environments may override or replace with a resource group
+	for env, envCfg := range cfg.EnvTag {
+		if envCfg.ResourceGroup != "" {
+			continue
+		}
+		if tags := cfg.EnvRdsTags(env); tags.TagName == "" || tags.TagValue == "" {
+			return details, fmt.Errorf("RDS tags are not configured for environment %s", env)
+		}
+	}
+	if cfg.RdsTags.TagName != "" {
+		details.add("  - RDS Tags: %s=%s", cfg.RdsTags.TagName, cfg.RdsTags.TagValue)
+	}
+
+	// Check allowed IAM users
+	if cfg.UseIAMIdentity {
+		details.add("  - Allowed IAM Users: derived from IAM identity")
+	} else {
+		// Environments may replace the global list
+		for env := range cfg.EnvTag {
+			if len(cfg.AllowedUsers(env)) == 0 {
+				return details, fmt.Errorf("no allowed IAM users configured for environment %s", env)
+			}
+		}
+		details.add("  - Allowed IAM Users: %d configured", len(cfg.AllowedIAMUsers))
+	}
+
+	// Check environment tags
+	if len(cfg.EnvTag) == 0 {
+		return details, fmt.Errorf("no environment tags configured")
+	}
+	details.add("  - Environment Tags: %d configured", len(cfg.EnvTag))
+
+	// Check cache configuration
+	if cfg.Caching.Enabled {
+		details.add("  - Cache: Enabled (duration: %s)", cfg.Caching.Duration)
+	} else {
+		details.add("  - Cache: Disabled")
+	}
+
+	return details, nil
+}
+
+// checkRDSConnectivity verifies RDS connectivity and IAM authentication.
+func checkRDSConnectivity(ctx context.Context, cfg *config.Config, conn *connect.Connector) (checkDetails, error) {
+	var details checkDetails
+
+	// Get clusters to verify connectivity
+	clusters, err := conn.Discover(ctx)
+	if err != nil {
+		return details, err
+	}
+
+	if len(clusters) == 0 {
+		return details, fmt.Errorf("no RDS clusters found with the specified tags")
+	}
+
+	details.add("  - Found %d RDS clusters", len(clusters))
+
+	probeTimeout, err := cfg.ProbeTimeout()
+	if err != nil {
+		return details, err
+	}
+
+	// Check IAM authentication and network reachability for each cluster
+	for i, cluster := range clusters {
+		details.add("  - Cluster %d: %s", i+1, cluster.Identifier)
+		details.add("    - Endpoint: %s:%d", displayEndpoint(cluster.Endpoint), cluster.ClientPort())
+		details.add("    - Region: %s", cluster.Region)
+		details.add("    - IAM Auth: Enabled")
+
+		start := time.Now()
+		if err := probeEndpoint(ctx, cluster.Endpoint, cluster.ClientPort(), probeTimeout); err != nil {
+			details.add("    - Network: %s unreachable (%v); check security groups and VPN", markFail, err)
+		} else {
+			details.add("    - Network: %s reachable (%s)", markOK, time.Since(start).Round(time.Millisecond))
+		}
+	}
+
+	return details, nil
+}
+
+// probeEndpoint checks that a TCP connection can be opened to the endpoint within the timeout.
+func probeEndpoint(ctx context.Context, host string, port int32, timeout time.Duration) error {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// checkCache verifies cache functionality.
+func checkCache(cfg *config.Config) (checkDetails, error) {
+	var details checkDetails
+
+	if !cfg.Caching.Enabled {
+		details.add("  - Cache is disabled, skipping cache checks")
+		return details, nil
+	}
+
+	cachePath, err := utils.CacheDirPath()
+	if err != nil {
+		return details, err
+	}
+
+	// Check cache directory
+	dirInfo, err := os.Stat(cachePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			details.add("  - Cache directory does not exist")
+			return details, nil
+		}
+		return details, fmt.Errorf("failed to check cache directory: %w", err)
+	}
+
+	if !dirInfo.IsDir() {
+		return details, fmt.Errorf("cache path is not a directory: %s", cachePath)
+	}
+
+	details.add("  - Cache directory exists")
+
+	// Check the cache files of each environment, in all accounts and regions
+	for env := range cfg.EnvTag {
+		paths, err := rds.CacheFiles(cachePath, env)
+		if err != nil {
+			return details, err
+		}
+		if len(paths) == 0 {
+			details.add("  - No cache files for environment %s", env)
+			continue
+		}
+
+		for _, path := range paths {
+			fileInfo, err := os.Stat(path)
+			if err != nil {
+				return details, fmt.Errorf("failed to check cache file for environment %s: %w", env, err)
+			}
+			if !fileInfo.Mode().IsRegular() {
+				return details, fmt.Errorf("cache file is not a regular file: %s", path)
+			}
+			details.add("  - Cache file exists for environment %s: %s", env, filepath.Base(path))
+		}
+	}
+
+	return details, nil
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"testing"
+
+	"rds-iam-connect/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConfiguration(t *testing.T) {
+	cfg := &config.Config{
+		RdsTags:         config.TagFilter{TagName: "team", TagValue: "payments"},
+		AllowedIAMUsers: []string{"app"},
+		EnvTag:          map[string]config.EnvConfig{"prod": {}},
+	}
+
+	details, err := checkConfiguration(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, checkDetails{
+		"  - RDS Tags: team=payments",
+		"  - Allowed IAM Users: 1 configured",
+		"  - Environment Tags: 1 configured",
+		"  - Cache: Disabled",
+	}, details)
+
+	// The details found before a failure are kept
+	cfg.AllowedIAMUsers = nil
+	details, err = checkConfiguration(cfg)
+	assert.EqualError(t, err, "no allowed IAM users configured for environment prod")
+	assert.Equal(t, checkDetails{"  - RDS Tags: team=payments"}, details)
+}
+
+func TestCheckCacheDisabled(t *testing.T) {
+	details, err := checkCache(&config.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, checkDetails{"  - Cache is disabled, skipping cache checks"}, details)
+}
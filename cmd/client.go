@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/internal/certs"
+	"rds-iam-connect/internal/rds"
+)
+
+// connectToRDS establishes a connection to the RDS instance using the database client.
+// It renders the client command configured for the cluster's engine with the provided connection details.
+// The extra client arguments and environment variables must suit the cluster's client. --read-only and
+// --exec are passed in the mysql syntax to MySQL clusters; PostgreSQL clients get the token in PGPASSWORD
+// and the equivalent psql options.
+// Returns a *ConnectError describing the exit code and likely cause if the client fails.
+// Exit code 1 ends interactive sessions normally, so it is only reported as a failure when not interactive,
+// e.g. when a statement run with --exec fails. The client is terminated if the tool receives SIGTERM.
+func connectToRDS(ctx context.Context, cluster rds.Cluster, user, token string, interactive bool, extraEnv []string, extraArgs ...string) error {
+	// Validate inputs to prevent command injection
+	if !isValidHostname(cluster.Endpoint) {
+		return fmt.Errorf("invalid endpoint: %s", cluster.Endpoint)
+	}
+	if !isValidUsername(user) {
+		return fmt.Errorf("invalid username: %s", user)
+	}
+	if !isValidPort(cluster.Port) {
+		return fmt.Errorf("invalid port: %d", cluster.Port)
+	}
+
+	args, err := clientCommand(cluster, user, token)
+	if err != nil {
+		return err
+	}
+
+	postgres := cluster.IsPostgres()
+	if defaultsFile && !postgres {
+		path, cleanup, err := writeDefaultsFile(cluster, user, token)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		args = defaultsFileArgs(args, path, token)
+	}
+
+	// Use exec.Command with separate arguments to prevent command injection
+	//nolint:gosec // The arguments are rendered from validated templates and inputs
+	clientCtx, stop := clientContext(ctx)
+	defer stop()
+	cmd := exec.CommandContext(clientCtx, args[0], args[1:]...)
+	terminateOnCancel(cmd)
+	cmd.Args = append(cmd.Args, extraArgs...)
+	cmd.Env = append(os.Environ(), extraEnv...)
+	if postgres {
+		cmd.Env = append(cmd.Env, "PGPASSWORD="+token)
+		if readOnly {
+			cmd.Env = append(cmd.Env, "PGOPTIONS=-c default_transaction_read_only=on")
+		}
+		if connectTimeout > 0 {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("PGCONNECT_TIMEOUT=%d", int(connectTimeout.Seconds())))
+		}
+		if tlsMinVersion != "" {
+			cmd.Env = append(cmd.Env, "PGSSLMINPROTOCOLVERSION="+tlsMinVersion)
+		}
+		if clientCharset != "" {
+			cmd.Env = append(cmd.Env, "PGCLIENTENCODING="+config.PostgresEncoding(clientCharset))
+		}
+		if len(initCommands) > 0 {
+			cmdLogger.Debugf("Ignoring connect.initCommands, which only apply to MySQL clusters")
+		}
+		if execSQL != "" {
+			cmd.Args = append(cmd.Args, "-c", execSQL)
+		}
+	} else {
+		if connectTimeout > 0 {
+			cmd.Args = append(cmd.Args, fmt.Sprintf("--connect-timeout=%d", int(connectTimeout.Seconds())))
+		}
+		if tlsMinVersion != "" {
+			cmd.Args = append(cmd.Args, "--tls-version="+config.TLSVersionsFrom(tlsMinVersion))
+		}
+		if clientCharset != "" {
+			cmd.Args = append(cmd.Args, "--default-character-set="+strings.ToLower(clientCharset))
+		}
+		initArg, err := mysqlInitCommand()
+		if err != nil {
+			return err
+		}
+		if initArg != "" {
+			cmd.Args = append(cmd.Args, "--init-command="+initArg)
+		}
+		if execSQL != "" {
+			cmd.Args = append(cmd.Args, "-e", execSQL)
+		}
+	}
+	// Capture the tail of stderr so failures can be reported with their cause
+	stderr := newTailBuffer(maxStderrCapture)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+
+	if err := cmd.Run(); err != nil {
+		if clientCtx.Err() != nil {
+			return fmt.Errorf("database client stopped: %w", context.Cause(ctx))
+		}
+		return classifyConnectError(err, connectErrorOutput(stderr, interactive, time.Now()), interactive)
+	}
+	return nil
+}
+
+// readOnlyInitCommand is run by the mysql client after connecting with --read-only.
+const readOnlyInitCommand = "SET SESSION TRANSACTION READ ONLY"
+
+// mysqlInitCommand returns the statements the mysql client runs after connecting: the read-only
+// statement of --read-only followed by Connect.InitCommands. The mysql client takes a single init command,
+// so the statements are joined with ";", which it runs as multiple statements. Returns "" if there are none.
+func mysqlInitCommand() (string, error) {
+	var commands []string
+	if readOnly {
+		commands = append(commands, readOnlyInitCommand)
+	}
+	commands = append(commands, initCommands...)
+
+	for _, command := range commands {
+		if !isValidInitCommand(command) {
+			return "", fmt.Errorf("invalid init command: %s", command)
+		}
+	}
+	return strings.Join(commands, "; "), nil
+}
+
+// clientCommand renders the client command line configured for the engine of the cluster.
+// Every argument is checked so that no rendered value can smuggle in control characters.
+func clientCommand(cluster rds.Cluster, user, token string) ([]string, error) {
+	engine := config.EngineMySQL
+	if cluster.IsPostgres() {
+		engine = config.EnginePostgres
+	}
+
+	data := config.CommandData{
+		Host:     cluster.Endpoint,
+		Port:     cluster.Port,
+		User:     user,
+		Token:    token,
+		Database: clientDatabase,
+	}
+
+	args := make([]string, 0, len(clientCommands[engine]))
+	for _, tmpl := range clientCommands[engine] {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("failed to render client command: %w", err)
+		}
+		// The argument may hold the token, so only the template name is reported
+		if !isValidArgument(b.String()) {
+			return nil, fmt.Errorf("invalid client command argument %s", tmpl.Name())
+		}
+		args = append(args, b.String())
+	}
+
+	if len(args) == 0 || args[0] == "" {
+		return nil, fmt.Errorf("no client command configured for engine %s", engine)
+	}
+	return args, nil
+}
+
+// isValidHostname checks if a string is a valid hostname.
+func isValidHostname(hostname string) bool {
+	if len(hostname) > 253 {
+		return false
+	}
+	// Basic validation - can be enhanced based on requirements
+	return strings.Contains(hostname, ".") && !strings.ContainsAny(hostname, " \t\n\r")
+}
+
+// isValidUsername checks if a string is a valid MySQL username.
+func isValidUsername(username string) bool {
+	if len(username) > 32 {
+		return false
+	}
+	// Basic validation - can be enhanced based on requirements
+	return !strings.ContainsAny(username, " \t\n\r")
+}
+
+// isValidInitCommand checks if a string is a single SQL statement that is safe to pass as a mysql init command.
+func isValidInitCommand(command string) bool {
+	if command == "" || len(command) > 1024 {
+		return false
+	}
+	// Reject statement separators and control characters to keep it to a single statement
+	return !strings.ContainsAny(command, ";\x00\n\r")
+}
+
+// isValidArgument checks if a string is safe to pass as a single client command argument.
+func isValidArgument(arg string) bool {
+	return !strings.ContainsAny(arg, "\x00\n\r")
+}
+
+// isValidPort checks if a port number is valid.
+func isValidPort(port int32) bool {
+	return port > 0 && port < 65536
+}
+
+// clientPromptArgs returns the client argument that sets the prompt from UI.PromptTemplate, so that
+// the environment and cluster stay visible during the session: --prompt for mysql and the PROMPT1
+// variable for psql, whose % escapes are escaped. Returns no arguments if the template fails to render.
+func clientPromptArgs(cfg *config.Config, env string, cluster rds.Cluster, user string) []string {
+	if promptTemplate == nil {
+		return nil
+	}
+
+	var b strings.Builder
+	err := promptTemplate.Execute(&b, struct {
+		Environment  string
+		ReleaseState string
+		Cluster      string
+		User         string
+	}{env, cfg.EnvTag[env].ReleaseState, cluster.Identifier, user})
+	if err != nil {
+		cmdLogger.Debugf("Failed to render prompt template: %v", err)
+		return nil
+	}
+
+	prompt := b.String()
+	if prompt == "" || strings.ContainsAny(prompt, "\x00\n\r") {
+		cmdLogger.Debugf("Ignoring invalid client prompt %q", prompt)
+		return nil
+	}
+	if cluster.IsPostgres() {
+		return []string{"--set=PROMPT1=" + strings.ReplaceAll(prompt, "%", "%%")}
+	}
+	return []string{"--prompt=" + prompt}
+}
+
+// clientSSLOptions returns the client arguments and environment variables for the SSL mode: mysql
+// arguments for MySQL clusters, and the libpq PGSSLMODE and PGSSLROOTCERT variables for PostgreSQL
+// clusters, which require SSL when no mode is set, as IAM authentication does.
+func clientSSLOptions(mode string, cluster rds.Cluster) (args, env []string, err error) {
+	if !cluster.IsPostgres() {
+		args, err = mysqlSSLArgs(mode)
+		return args, nil, err
+	}
+
+	var pgMode string
+	switch mode {
+	case "", config.SSLModeRequired:
+		return nil, []string{"PGSSLMODE=require"}, nil
+	case config.SSLModeVerifyCA:
+		pgMode = "verify-ca"
+	case config.SSLModeVerifyIdentity, config.SSLModeVerifyFull:
+		pgMode = "verify-full"
+	default:
+		return nil, nil, config.ValidateSSLMode(mode)
+	}
+
+	caPath, err := certs.BundlePath()
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, []string{"PGSSLMODE=" + pgMode, "PGSSLROOTCERT=" + caPath}, nil
+}
+
+// mysqlSSLArgs returns the mysql client arguments for the SSL mode. The verifying modes point
+// --ssl-ca at the bundled RDS CA certificates.
+func mysqlSSLArgs(mode string) ([]string, error) {
+	var clientMode string
+	switch mode {
+	case "":
+		return nil, nil
+	case config.SSLModeRequired:
+		return []string{"--ssl-mode=REQUIRED"}, nil
+	case config.SSLModeVerifyCA:
+		clientMode = "VERIFY_CA"
+	case config.SSLModeVerifyIdentity, config.SSLModeVerifyFull:
+		clientMode = "VERIFY_IDENTITY"
+	default:
+		return nil, config.ValidateSSLMode(mode)
+	}
+
+	caPath, err := certs.BundlePath()
+	if err != nil {
+		return nil, err
+	}
+	return []string{"--ssl-mode=" + clientMode, "--ssl-ca=" + caPath}, nil
+}
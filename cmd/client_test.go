@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"testing"
+	"text/template"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/internal/rds"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSSLOptions(t *testing.T) {
+	mysqlCluster := rds.Cluster{Identifier: "orders", Engine: "aurora-mysql"}
+	postgresCluster := rds.Cluster{Identifier: "reports", Engine: "aurora-postgresql"}
+
+	tests := []struct {
+		name     string
+		mode     string
+		cluster  rds.Cluster
+		wantArgs []string
+		wantEnv  []string
+	}{
+		{name: "mysql without mode", cluster: mysqlCluster},
+		{name: "mysql required", mode: config.SSLModeRequired, cluster: mysqlCluster, wantArgs: []string{"--ssl-mode=REQUIRED"}},
+		{name: "postgres without mode", cluster: postgresCluster, wantEnv: []string{"PGSSLMODE=require"}},
+		{name: "postgres required", mode: config.SSLModeRequired, cluster: postgresCluster, wantEnv: []string{"PGSSLMODE=require"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, env, err := clientSSLOptions(tt.mode, tt.cluster)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantArgs, args)
+			assert.Equal(t, tt.wantEnv, env)
+		})
+	}
+
+	_, _, err := clientSSLOptions("prefer", postgresCluster)
+	assert.Error(t, err)
+}
+
+func TestClientPromptArgs(t *testing.T) {
+	saved := promptTemplate
+	t.Cleanup(func() { promptTemplate = saved })
+	promptTemplate = template.Must(template.New("prompt").Parse("{{.Environment}}:{{.Cluster}} 100%> "))
+	cfg := &config.Config{}
+
+	assert.Equal(t, []string{"--prompt=prod:orders 100%> "},
+		clientPromptArgs(cfg, "prod", rds.Cluster{Identifier: "orders", Engine: "aurora-mysql"}, "app"))
+	assert.Equal(t, []string{"--set=PROMPT1=prod:reports 100%%> "},
+		clientPromptArgs(cfg, "prod", rds.Cluster{Identifier: "reports", Engine: "aurora-postgresql"}, "app"))
+
+	promptTemplate = nil
+	assert.Empty(t, clientPromptArgs(cfg, "prod", rds.Cluster{Identifier: "orders"}, "app"))
+}
+
+func TestDefaultsFileArgs(t *testing.T) {
+	const token = "host:3306/?Action=connect&X-Amz-Signature=abc"
+
+	// The default command keeps everything but the token
+	args := []string{"mysql", "-h", "host", "-P", "3306", "-u", "app", "-p" + token, "--enable-cleartext-plugin"}
+	assert.Equal(t, []string{"mysql", "--defaults-extra-file=/tmp/f.cnf", "-h", "host", "-P", "3306", "-u", "app", "--enable-cleartext-plugin"},
+		defaultsFileArgs(args, "/tmp/f.cnf", token))
+
+	// Custom arguments are kept and the cleartext plugin is enabled
+	args = []string{"mariadb", "--password=" + token, "--ssl-verify-server-cert", "app"}
+	assert.Equal(t, []string{"mariadb", "--defaults-extra-file=/tmp/f.cnf", "--ssl-verify-server-cert", "app", "--enable-cleartext-plugin"},
+		defaultsFileArgs(args, "/tmp/f.cnf", token))
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats supported by the config dump command.
+const (
+	dumpOutputYAML = "yaml"
+	dumpOutputJSON = "json"
+)
+
+var dumpOutput string
+
+// configCmd groups commands that inspect the configuration.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+	Args:  cobra.NoArgs,
+}
+
+// configDumpCmd prints the effective configuration.
+var configDumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Print the effective configuration",
+	Long: `Print the configuration as resolved from the config file and command-line flags, including
+regions filled in from defaults and users merged from allowedIAMUsersFile. Sensitive values such as
+the Data API secret ARN and the external token command are redacted.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigDump,
+}
+
+// runConfigDump is the execution function for the config dump command.
+func runConfigDump(_ *cobra.Command, _ []string) error {
+	if dumpOutput != dumpOutputYAML && dumpOutput != dumpOutputJSON {
+		return fmt.Errorf("invalid output format %q: must be %q or %q", dumpOutput, dumpOutputYAML, dumpOutputJSON)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if dumpOutput == dumpOutputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(cfg.Settings())
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg.Settings()); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	return encoder.Close()
+}
+
+func init() {
+	configDumpCmd.Flags().StringVarP(&dumpOutput, "output", "o", dumpOutputYAML, "output format: yaml or json")
+	configCmd.AddCommand(configDumpCmd)
+	rootCmd.AddCommand(configCmd)
+}
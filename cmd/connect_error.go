@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxStderrCapture limits how much of the mysql client's stderr is retained for error reporting.
+const maxStderrCapture = 4096
+
+// connectErrorWindow is how soon after printing an error an interactive client that failed to connect exits.
+const connectErrorWindow = 2 * time.Second
+
+// mysqlErrorPattern matches error lines printed by the mysql client, e.g. "ERROR 1045 (28000): Access denied".
+var mysqlErrorPattern = regexp.MustCompile(`ERROR (\d+)(?: \([0-9A-Z]+\))?: .*`)
+
+// mysqlErrorHints maps mysql client error codes to likely causes.
+var mysqlErrorHints = map[int]string{
+	1045: "access denied: the IAM token may have expired, the database user may not exist or may not use " +
+		"the AWSAuthenticationPlugin, or your IAM role may lack rds-db:connect for this user",
+	2003: "cannot reach the database: check security groups, network ACLs and your VPN connection",
+	2005: "unknown database host: check the cluster endpoint, DNS resolution and your VPN connection",
+	2026: "TLS connection error: check the client SSL settings and the RDS CA certificate",
+}
+
+// mysqlAccessDenied is the mysql client error code of rejected credentials.
+const mysqlAccessDenied = 1045
+
+// psqlAuthFailurePattern matches the psql error of rejected credentials,
+// e.g. `FATAL:  PAM authentication failed for user "app"`.
+var psqlAuthFailurePattern = regexp.MustCompile(`(?:password|PAM) authentication failed for user.*`)
+
+// ConnectError describes a failed mysql client invocation.
+type ConnectError struct {
+	ExitCode    int    // The exit code of the mysql client.
+	MySQLCode   int    // The mysql error code parsed from stderr, or 0 if none was found.
+	Message     string // The mysql error line parsed from stderr, if any.
+	Hint        string // A human-friendly explanation of the likely cause, if known.
+	AuthFailure bool   // Whether the server rejected the credentials.
+	Err         error  // The underlying error returned by the process.
+}
+
+// Error implements the error interface.
+func (e *ConnectError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "failed to connect to RDS (mysql exit code %d)", e.ExitCode)
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	if e.Hint != "" {
+		fmt.Fprintf(&b, "\nHint: %s", e.Hint)
+	}
+	return b.String()
+}
+
+// Unwrap returns the underlying process error.
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+// classifyConnectError converts the result of running the mysql client into a descriptive error.
+// For interactive sessions, an exit code of 1 without a mysql error or rejected credentials on stderr is
+// treated as a normal exit and yields nil. Otherwise it means the connection or statement failed and is reported.
+func classifyConnectError(err error, stderr string, interactive bool) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return fmt.Errorf("failed to connect to RDS: %w", err)
+	}
+
+	connErr := &ConnectError{
+		ExitCode: exitErr.ExitCode(),
+		Err:      err,
+	}
+
+	if matches := mysqlErrorPattern.FindAllStringSubmatch(stderr, -1); len(matches) > 0 {
+		last := matches[len(matches)-1]
+		connErr.Message = strings.TrimSpace(last[0])
+		if code, convErr := strconv.Atoi(last[1]); convErr == nil {
+			connErr.MySQLCode = code
+			connErr.Hint = mysqlErrorHints[code]
+			connErr.AuthFailure = code == mysqlAccessDenied
+		}
+	} else if match := psqlAuthFailurePattern.FindString(stderr); match != "" {
+		connErr.Message = strings.TrimSpace(match)
+		connErr.AuthFailure = true
+	}
+
+	// Normal exit from MySQL client
+	if interactive && connErr.ExitCode == 1 && connErr.MySQLCode == 0 && !connErr.AuthFailure {
+		return nil
+	}
+
+	return connErr
+}
+
+// connectErrorOutput returns the stderr output of the client to classify with classifyConnectError.
+// An interactive client that fails to connect exits right after printing the error, so output written
+// earlier than connectErrorWindow before it exited comes from the session, e.g. a failing statement,
+// and is ignored.
+func connectErrorOutput(stderr *tailBuffer, interactive bool, exited time.Time) string {
+	if interactive && exited.Sub(stderr.lastWrite) > connectErrorWindow {
+		return ""
+	}
+	return stderr.String()
+}
+
+// isAuthFailure reports whether err is a client failure caused by rejected credentials.
+func isAuthFailure(err error) bool {
+	var connErr *ConnectError
+	return errors.As(err, &connErr) && connErr.AuthFailure
+}
+
+// tailBuffer is an io.Writer that retains only the last limit bytes written to it.
+type tailBuffer struct {
+	buf       []byte
+	limit     int
+	lastWrite time.Time // When output was last written.
+}
+
+// newTailBuffer creates a tailBuffer that retains at most limit bytes.
+func newTailBuffer(limit int) *tailBuffer {
+	return &tailBuffer{limit: limit}
+}
+
+// Write implements io.Writer.
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	t.lastWrite = time.Now()
+	if len(t.buf) > t.limit {
+		t.buf = t.buf[len(t.buf)-t.limit:]
+	}
+	return len(p), nil
+}
+
+// String returns the retained output.
+func (t *tailBuffer) String() string {
+	return string(t.buf)
+}
@@ -0,0 +1,65 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyConnectError(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 1").Run()
+	require.Error(t, exitErr)
+
+	tests := []struct {
+		name        string
+		stderr      string
+		interactive bool
+		wantNil     bool
+		wantCode    int
+	}{
+		{name: "interactive normal exit", interactive: true, wantNil: true},
+		{name: "interactive mapped error", stderr: "ERROR 1045 (28000): Access denied", interactive: true, wantCode: 1045},
+		{name: "interactive unmapped error", stderr: "ERROR 2013 (HY000): Lost connection", interactive: true, wantCode: 2013},
+		{name: "interactive psql auth failure", stderr: `FATAL:  PAM authentication failed for user "app"`, interactive: true},
+		{name: "statement failure", stderr: "", interactive: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyConnectError(exitErr, tt.stderr, tt.interactive)
+			if tt.wantNil {
+				assert.NoError(t, err)
+				return
+			}
+			var connErr *ConnectError
+			require.ErrorAs(t, err, &connErr)
+			assert.Equal(t, tt.wantCode, connErr.MySQLCode)
+		})
+	}
+}
+
+func TestConnectErrorOutput(t *testing.T) {
+	stderr := newTailBuffer(maxStderrCapture)
+	_, err := stderr.Write([]byte("ERROR 1064 (42000): You have an error in your SQL syntax\n"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		interactive bool
+		exitedAfter time.Duration
+		want        string
+	}{
+		{name: "failed connection", interactive: true, exitedAfter: 10 * time.Millisecond, want: stderr.String()},
+		{name: "error in the session", interactive: true, exitedAfter: time.Minute},
+		{name: "failed statement", interactive: false, exitedAfter: time.Minute, want: stderr.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, connectErrorOutput(stderr, tt.interactive, stderr.lastWrite.Add(tt.exitedAfter)))
+		})
+	}
+}
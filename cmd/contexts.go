@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"rds-iam-connect/internal/contexts"
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// contextsFileName is the name of the file in the configuration directory that holds the connection contexts.
+// Unlike the caches, they are not meant to be wiped.
+const contextsFileName = "connections.yaml"
+
+// Output formats supported by the contexts list command.
+const (
+	contextsOutputTable = "table"
+	contextsOutputJSON  = "json"
+)
+
+var contextsOutput string
+
+// contextsCmd groups the commands that manage the connection contexts written by export-dsn --output context.
+var contextsCmd = &cobra.Command{
+	Use:   "contexts",
+	Short: "Manage named connection contexts",
+	Args:  cobra.NoArgs,
+}
+
+// contextsListCmd prints the stored connection contexts.
+var contextsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print the stored connection contexts",
+	Long: `Print the connection contexts stored in connections.yaml in the configuration directory, sorted by name,
+with the environment, cluster, account, DB user and endpoint of each. With --output json, they are printed as a
+JSON array.`,
+	Args: cobra.NoArgs,
+	RunE: runContextsList,
+}
+
+// contextsRemoveCmd removes stored connection contexts.
+var contextsRemoveCmd = &cobra.Command{
+	Use:   "remove NAME...",
+	Short: "Remove stored connection contexts",
+	Long:  "Remove the named connection contexts. If any of them does not exist, none is removed.",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runContextsRemove,
+}
+
+// loadContexts reads the connection contexts from the configuration directory.
+func loadContexts() (*contexts.Store, error) {
+	configDir, err := utils.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return contexts.Load(filepath.Join(configDir, contextsFileName))
+}
+
+// saveContext stores the resolved connection as a context named name, or <environment>-<cluster>-<user>
+// if name is empty, and returns the name. Default names of clusters discovered in another account also
+// carry the account ID, which tells apart clusters of the same name.
+func saveContext(name, env string, cluster rds.Cluster, user string) (string, error) {
+	if name == "" {
+		name = fmt.Sprintf("%s-%s-%s", env, cluster.Identifier, user)
+		if cluster.Account != "" {
+			name = fmt.Sprintf("%s-%s-%s-%s", env, cluster.Account, cluster.Identifier, user)
+		}
+	}
+
+	store, err := loadContexts()
+	if err != nil {
+		return "", err
+	}
+
+	err = store.Set(contexts.Context{
+		Name:        name,
+		Environment: env,
+		Cluster:     cluster.Identifier,
+		Account:     cluster.Account,
+		User:        user,
+		Endpoint:    cluster.Endpoint,
+		Port:        cluster.ClientPort(),
+		Region:      cluster.Region,
+		Engine:      cluster.Engine,
+	})
+	if err != nil {
+		return "", err
+	}
+	cmdLogger.Debugf("Stored connection context %q in %s", name, store.Path())
+	return name, nil
+}
+
+// runContextsList is the execution function for the contexts list command.
+func runContextsList(_ *cobra.Command, _ []string) error {
+	if contextsOutput != contextsOutputTable && contextsOutput != contextsOutputJSON {
+		return fmt.Errorf("invalid output format %q: must be %q or %q", contextsOutput, contextsOutputTable, contextsOutputJSON)
+	}
+
+	store, err := loadContexts()
+	if err != nil {
+		return err
+	}
+	list := store.List()
+
+	if contextsOutput == contextsOutputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(list)
+	}
+
+	if len(list) == 0 {
+		fmt.Fprintf(os.Stderr, "No connection contexts stored in %s yet. Use export-dsn --output context to add one.\n", store.Path())
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tENVIRONMENT\tCLUSTER\tACCOUNT\tUSER\tENDPOINT")
+	for _, c := range list {
+		account := c.Account
+		if account == "" {
+			account = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s:%d\n", c.Name, c.Environment, c.Cluster, account, c.User, c.Endpoint, c.Port)
+	}
+	return w.Flush()
+}
+
+// runContextsRemove is the execution function for the contexts remove command.
+func runContextsRemove(_ *cobra.Command, args []string) error {
+	store, err := loadContexts()
+	if err != nil {
+		return err
+	}
+
+	if err := store.Remove(args...); err != nil {
+		return err
+	}
+	for _, name := range args {
+		fmt.Printf("Removed connection context %q\n", name)
+	}
+	return nil
+}
+
+func init() {
+	contextsListCmd.Flags().StringVarP(&contextsOutput, "output", "o", contextsOutputTable, "output format: table or json")
+	contextsCmd.AddCommand(contextsListCmd)
+	contextsCmd.AddCommand(contextsRemoveCmd)
+	rootCmd.AddCommand(contextsCmd)
+}
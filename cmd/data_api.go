@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/pkg/connect"
+)
+
+// runDataAPI executes the --exec statement on the cluster through the RDS Data API
+// and prints the result, without requiring a mysql client or a network path to the database.
+func runDataAPI(ctx context.Context, conn *connect.Connector, cluster rds.Cluster) error {
+	result, err := conn.Execute(ctx, cluster, execSQL)
+	if err != nil {
+		return err
+	}
+
+	printStatementResult(result)
+	return nil
+}
+
+// printStatementResult prints the result set as an aligned table, or the number of
+// updated records when the statement returned no result set.
+func printStatementResult(result *rds.StatementResult) {
+	if len(result.Columns) == 0 {
+		fmt.Printf("%d rows affected\n", result.RecordsUpdated)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(result.Columns, "\t"))
+	for _, row := range result.Rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	_ = w.Flush()
+
+	fmt.Printf("%d rows in set\n", len(result.Rows))
+}
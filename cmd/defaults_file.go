@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/internal/utils"
+)
+
+// defaultsFilePattern is the name pattern of the defaults files in the cache directory.
+const defaultsFilePattern = "mysql-defaults-*.cnf"
+
+// writeDefaultsFile writes a temporary mysql option file holding the connection details and the
+// token, so that the token appears in neither the argument list nor the environment of the client.
+// The file is created with 0600 permissions in the cache directory. The returned cleanup function
+// removes it and must be called once the client has exited.
+func writeDefaultsFile(cluster rds.Cluster, user, token string) (string, func(), error) {
+	cacheDir, err := utils.GetCacheDir()
+	if err != nil {
+		return "", nil, err
+	}
+
+	// CreateTemp creates the file with 0600 permissions
+	file, err := os.CreateTemp(cacheDir, defaultsFilePattern)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create defaults file: %w", err)
+	}
+	cleanup := func() { _ = os.Remove(file.Name()) }
+
+	content := fmt.Sprintf("[client]\nhost=%s\nport=%d\nuser=%s\npassword=%s\n",
+		optionValue(cluster.Endpoint), cluster.Port, optionValue(user), optionValue(token))
+	if _, err := file.WriteString(content); err != nil {
+		_ = file.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write defaults file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write defaults file: %w", err)
+	}
+
+	return file.Name(), cleanup, nil
+}
+
+// optionValue double-quotes a value for a mysql option file, escaping backslashes and quotes.
+func optionValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// defaultsFileArgs returns the client command args that read the credentials from the defaults file at
+// path. --defaults-extra-file must be the first argument; the other arguments of the command template
+// are kept, except those holding the token, which the file provides instead.
+func defaultsFileArgs(args []string, path, token string) []string {
+	result := []string{args[0], "--defaults-extra-file=" + path}
+	cleartext := false
+	for _, arg := range args[1:] {
+		if token != "" && strings.Contains(arg, token) {
+			continue
+		}
+		cleartext = cleartext || arg == "--enable-cleartext-plugin"
+		result = append(result, arg)
+	}
+	// The auth token is sent as a cleartext password
+	if !cleartext {
+		result = append(result, "--enable-cleartext-plugin")
+	}
+	return result
+}
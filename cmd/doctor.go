@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/internal/utils"
+	"rds-iam-connect/pkg/connect"
+
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd runs environment diagnostics and suggests fixes for failed checks.
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the local environment and suggest fixes",
+	Long: `Verify that everything rds-iam-connect depends on is in place: database clients, the AWS CLI,
+the configuration file, the cache directory, AWS credentials and network access to RDS.
+Each check is reported as passed, failed or as a warning, with a suggested fix.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+// doctorCheck is a single diagnostic run by the doctor command.
+type doctorCheck struct {
+	name     string
+	optional bool                                      // A failed optional check is reported as a warning.
+	fix      string                                    // The suggested fix when the check fails.
+	run      func(ctx context.Context) (string, error) // Returns a detail message on success.
+}
+
+// runDoctor is the execution function for the doctor command.
+func runDoctor(_ *cobra.Command, _ []string) error {
+	ctx, stop := notifyShutdown(context.Background())
+	defer stop()
+	if asciiMode {
+		setMarkers(true)
+	}
+
+	// Later checks need the configuration and a connector, which earlier checks provide
+	var (
+		cfg  *config.Config
+		conn *connect.Connector
+	)
+
+	checks := []doctorCheck{
+		{
+			name: "mysql client",
+			fix:  "install the MySQL client, e.g. `brew install mysql-client` or `apt install mysql-client`",
+			run:  func(ctx context.Context) (string, error) { return binaryVersion(ctx, "mysql") },
+		},
+		{
+			name:     "psql client",
+			optional: true,
+			fix:      "install the PostgreSQL client to connect to PostgreSQL clusters, e.g. `brew install libpq`",
+			run:      func(ctx context.Context) (string, error) { return binaryVersion(ctx, "psql") },
+		},
+		{
+			name:     "AWS CLI",
+			optional: true,
+			fix:      "install the AWS CLI to log in with `aws sso login` when using IAM Identity Center",
+			run:      func(ctx context.Context) (string, error) { return binaryVersion(ctx, "aws") },
+		},
+		{
+			name: "configuration",
+			fix:  "check the file passed with --config, see config.example.yaml for the format",
+			run: func(_ context.Context) (string, error) {
+				var err error
+				if cfg, err = loadConfig(); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%d environments configured", len(cfg.EnvTag)), nil
+			},
+		},
+		{
+			name: "cache directory",
+			fix:  "make sure the cache directory is owned by you and writable, or disable caching",
+			run:  func(_ context.Context) (string, error) { return checkCacheDirWritable() },
+		},
+		{
+			name: "AWS credentials",
+			fix:  "log in with `aws sso login` or configure credentials with `aws configure`",
+			run: func(ctx context.Context) (string, error) {
+				if cfg == nil {
+					return "", fmt.Errorf("skipped: configuration could not be loaded")
+				}
+
+				var err error
+				if conn, err = connect.New(cfg, firstEnvironment(cfg)); err != nil {
+					return "", err
+				}
+
+				identity, err := conn.STSClient().GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+				if err != nil {
+					return "", fmt.Errorf("failed to get caller identity: %w", err)
+				}
+				return displayARN(*identity.Arn), nil
+			},
+		},
+		{
+			name: "network access to RDS",
+			fix:  "check security groups, network ACLs and your VPN connection",
+			run: func(ctx context.Context) (string, error) {
+				if cfg == nil || conn == nil {
+					return "", fmt.Errorf("skipped: configuration or AWS credentials unavailable")
+				}
+				return checkRDSEgress(ctx, cfg)
+			},
+		},
+	}
+
+	fmt.Println("Running rds-iam-connect diagnostics...")
+	failed := 0
+	for _, check := range checks {
+		detail, err := check.run(ctx)
+		switch {
+		case err == nil:
+			fmt.Printf("%s %s: %s\n", markOK, check.name, detail)
+		case check.optional:
+			fmt.Printf("%s %s: %v\n    Fix: %s\n", markWarn, check.name, err, check.fix)
+		default:
+			failed++
+			fmt.Printf("%s %s: %v\n    Fix: %s\n", markFail, check.name, err, check.fix)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d diagnostic checks failed", failed)
+	}
+
+	fmt.Println("\nAll required checks passed!")
+	return nil
+}
+
+// binaryVersion returns the version reported by the given binary, or an error if it is not installed.
+func binaryVersion(ctx context.Context, name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%s not found in PATH", name)
+	}
+
+	//nolint:gosec // The binary is resolved from a fixed name
+	output, err := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s --version: %w", name, err)
+	}
+
+	version, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	return version, nil
+}
+
+// checkCacheDirWritable verifies that a file can be created in the cache directory.
+func checkCacheDirWritable() (string, error) {
+	cacheDir, err := utils.GetCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.CreateTemp(cacheDir, ".doctor-*")
+	if err != nil {
+		return "", fmt.Errorf("cache directory %s is not writable: %w", cacheDir, err)
+	}
+	_ = file.Close()
+	_ = os.Remove(file.Name())
+
+	return cacheDir, nil
+}
+
+// checkRDSEgress probes the endpoint of the first cluster of each environment.
+func checkRDSEgress(ctx context.Context, cfg *config.Config) (string, error) {
+	probeTimeout, err := cfg.ProbeTimeout()
+	if err != nil {
+		return "", err
+	}
+
+	envs := make([]string, 0, len(cfg.EnvTag))
+	for env := range cfg.EnvTag {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	probed := 0
+	for _, env := range envs {
+		conn, err := connect.New(cfg, env)
+		if err != nil {
+			return "", err
+		}
+
+		clusters, err := conn.Discover(ctx)
+		if err != nil {
+			return "", fmt.Errorf("environment %s: %w", env, err)
+		}
+		if len(clusters) == 0 {
+			continue
+		}
+
+		cluster := clusters[0]
+		if err := probeEndpoint(ctx, cluster.Endpoint, cluster.ClientPort(), probeTimeout); err != nil {
+			return "", fmt.Errorf("environment %s: cannot reach %s:%d: %w", env, cluster.Endpoint, cluster.ClientPort(), err)
+		}
+		probed++
+	}
+
+	if probed == 0 {
+		return "no clusters found to probe", nil
+	}
+	return fmt.Sprintf("reached a cluster in %d environments", probed), nil
+}
+
+// firstEnvironment returns the alphabetically first configured environment.
+func firstEnvironment(cfg *config.Config) string {
+	first := ""
+	for env := range cfg.EnvTag {
+		if first == "" || env < first {
+			first = env
+		}
+	}
+	return first
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/pkg/connect"
+)
+
+// selectClusterInstance prompts for a member instance of the cluster and
+// returns a copy of the cluster that targets the chosen instance endpoint.
+func selectClusterInstance(ctx context.Context, conn *connect.Connector, cluster rds.Cluster) (rds.Cluster, error) {
+	instances, err := conn.Instances(ctx, cluster)
+	if err != nil {
+		return rds.Cluster{}, err
+	}
+
+	if len(instances) == 0 {
+		return rds.Cluster{}, fmt.Errorf("no instances with an available endpoint found in cluster %s", cluster.Identifier)
+	}
+	warnIAMAuthMismatch(cluster, instances)
+
+	instance, err := promptInstanceSelection(instances)
+	if err != nil {
+		return rds.Cluster{}, err
+	}
+
+	return instance.AsCluster(cluster), nil
+}
+
+// Endpoint choices offered when selecting between the writer and reader endpoints.
+const (
+	writerEndpointOption = "writer (cluster endpoint)"
+	readerEndpointOption = "reader"
+)
+
+// selectClusterEndpoint prompts for the writer or reader side of the cluster. When the reader side
+// is chosen, a sub-prompt offers the load-balanced reader endpoint and each reader instance, or the
+// read replicas of a standalone instance.
+// Returns a copy of the cluster that targets the chosen endpoint.
+func selectClusterEndpoint(ctx context.Context, conn *connect.Connector, cluster rds.Cluster) (rds.Cluster, error) {
+	var endpointType string
+	if err := askOne(newSelect("Choose an endpoint:", []string{writerEndpointOption, readerEndpointOption}), &endpointType); err != nil {
+		return rds.Cluster{}, fmt.Errorf("failed to select endpoint: %w", err)
+	}
+
+	if endpointType == writerEndpointOption {
+		return cluster, nil
+	}
+	if cluster.Standalone {
+		return selectReadReplica(ctx, conn, cluster)
+	}
+
+	instances, err := conn.Instances(ctx, cluster)
+	if err != nil {
+		return rds.Cluster{}, err
+	}
+
+	readers := rds.Readers(instances)
+	warnIAMAuthMismatch(cluster, readers)
+	if cluster.ReaderEndpoint != "" {
+		// Offer the load-balanced reader endpoint alongside the individual readers
+		readers = append([]rds.Instance{{
+			Identifier: "reader endpoint",
+			Endpoint:   cluster.ReaderEndpoint,
+			Port:       cluster.Port,
+		}}, readers...)
+	}
+
+	if len(readers) == 0 {
+		return rds.Cluster{}, fmt.Errorf("no reader endpoints found in cluster %s", cluster.Identifier)
+	}
+
+	reader, err := promptInstanceSelection(readers)
+	if err != nil {
+		return rds.Cluster{}, err
+	}
+
+	return reader.AsCluster(cluster), nil
+}
+
+// selectReadReplica prompts for a read replica of the standalone instance, e.g. for read-only work.
+// The returned target carries the replica's endpoint and region, so the auth token is signed for it.
+func selectReadReplica(ctx context.Context, conn *connect.Connector, instance rds.Cluster) (rds.Cluster, error) {
+	replicas, err := conn.ReadReplicas(ctx, instance)
+	if err != nil {
+		return rds.Cluster{}, err
+	}
+	if len(replicas) == 0 {
+		return rds.Cluster{}, fmt.Errorf("no read replicas with an available endpoint found for instance %s", instance.Identifier)
+	}
+
+	options := make([]string, 0, len(replicas))
+	replicaMap := make(map[string]rds.Cluster, len(replicas))
+	for _, replica := range replicas {
+		display := fmt.Sprintf("%s %s (%s:%d)", replica.Region, replica.Identifier, displayEndpoint(replica.Endpoint), replica.Port)
+		options = append(options, display)
+		replicaMap[display] = replica
+	}
+
+	var selected string
+	if err := askOne(newSelect("Choose a read replica:", options), &selected); err != nil {
+		return rds.Cluster{}, fmt.Errorf("failed to select read replica: %w", err)
+	}
+
+	replica := replicaMap[selected]
+	cmdLogger.Debugf("Selected read replica %s in %s", replica.Identifier, replica.Region)
+	return replica, nil
+}
+
+// promptInstanceSelection presents an interactive prompt for selecting a cluster member instance.
+// Returns the selected instance or an error if the selection fails.
+func promptInstanceSelection(instances []rds.Instance) (rds.Instance, error) {
+	instanceNames := make([]string, 0, len(instances))
+	instanceMap := make(map[string]rds.Instance, len(instances))
+
+	for _, instance := range instances {
+		display := fmt.Sprintf("%s [%s] (%s:%d)", instance.Identifier, instance.Role(), displayEndpoint(instance.Endpoint), instance.Port)
+		instanceNames = append(instanceNames, display)
+		instanceMap[display] = instance
+	}
+
+	var selectedInstance string
+	if err := askOne(newSelect("Choose a cluster instance:", instanceNames), &selectedInstance); err != nil {
+		return rds.Instance{}, fmt.Errorf("failed to select instance: %w", err)
+	}
+
+	return instanceMap[selectedInstance], nil
+}
+
+// warnIAMAuthMismatch prints a warning for each member instance that reports IAM authentication
+// as disabled although the cluster has it enabled, which causes intermittent authentication failures.
+func warnIAMAuthMismatch(cluster rds.Cluster, instances []rds.Instance) {
+	for _, instance := range rds.IAMAuthDisabled(instances) {
+		warnf("cluster %s has IAM authentication enabled but its instance %s reports it disabled",
+			cluster.Identifier, instance.Identifier)
+	}
+}
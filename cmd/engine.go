@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"rds-iam-connect/internal/rds"
+)
+
+// Engine families selectable with --engine. Each family is served by its own client.
+const (
+	engineMySQL    = "mysql"
+	enginePostgres = "postgres"
+)
+
+// engineFamily returns the engine family of the cluster. Engines other than PostgreSQL, e.g. MariaDB,
+// use the mysql client.
+func engineFamily(cluster rds.Cluster) string {
+	if cluster.IsPostgres() {
+		return enginePostgres
+	}
+	return engineMySQL
+}
+
+// validateEngineFilter checks the --engine engine family.
+func validateEngineFilter(engine string) error {
+	switch engine {
+	case "", engineMySQL, enginePostgres:
+		return nil
+	default:
+		return fmt.Errorf("invalid --engine %q: must be %q or %q", engine, engineMySQL, enginePostgres)
+	}
+}
+
+// filterClustersByEngine returns the clusters of the engine family, or all clusters if engine is empty.
+func filterClustersByEngine(clusters []rds.Cluster, engine string) []rds.Cluster {
+	if engine == "" {
+		return clusters
+	}
+
+	filtered := make([]rds.Cluster, 0, len(clusters))
+	for _, cluster := range clusters {
+		if engineFamily(cluster) == engine {
+			filtered = append(filtered, cluster)
+		}
+	}
+	return filtered
+}
+
+// mixedEngines reports whether the clusters run engines of more than one family.
+func mixedEngines(clusters []rds.Cluster) bool {
+	for _, cluster := range clusters {
+		if engineFamily(cluster) != engineFamily(clusters[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupByEngine returns the clusters with those of the same engine family next to each other,
+// MySQL first, keeping their order within each family.
+func groupByEngine(clusters []rds.Cluster) []rds.Cluster {
+	grouped := append([]rds.Cluster(nil), clusters...)
+	sort.SliceStable(grouped, func(i, j int) bool {
+		return engineFamily(grouped[i]) == engineMySQL && engineFamily(grouped[j]) == enginePostgres
+	})
+	return grouped
+}
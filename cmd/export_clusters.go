@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"rds-iam-connect/internal/rds"
+)
+
+// exportFileMode is the permission mode of cluster export files.
+const exportFileMode os.FileMode = 0600
+
+// clusterExport is a discovered cluster as written by --export-clusters.
+type clusterExport struct {
+	Environment    string `json:"environment"`
+	Identifier     string `json:"identifier"`
+	Endpoint       string `json:"endpoint"`
+	ReaderEndpoint string `json:"readerEndpoint,omitempty"`
+	Port           int32  `json:"port"`
+	Engine         string `json:"engine,omitempty"`
+	Region         string `json:"region"`
+	Arn            string `json:"arn"`
+}
+
+// clusterExportHeader is the header row of CSV cluster exports.
+var clusterExportHeader = []string{"environment", "identifier", "endpoint", "reader_endpoint", "port", "engine", "region", "arn"}
+
+// newClusterExport converts a cluster of the environment for export.
+func newClusterExport(env string, cluster rds.Cluster) clusterExport {
+	return clusterExport{
+		Environment:    env,
+		Identifier:     cluster.Identifier,
+		Endpoint:       cluster.Endpoint,
+		ReaderEndpoint: cluster.ReaderEndpoint,
+		Port:           cluster.Port,
+		Engine:         cluster.Engine,
+		Region:         cluster.Region,
+		Arn:            cluster.Arn,
+	}
+}
+
+// exportClusters writes the discovered clusters of the environment to path. The format is
+// CSV if the file name ends in ".csv" and JSON otherwise.
+func exportClusters(path, env string, clusters []rds.Cluster) error {
+	rows := make([]clusterExport, 0, len(clusters))
+	for _, cluster := range clusters {
+		rows = append(rows, newClusterExport(env, cluster))
+	}
+
+	file, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, exportFileMode)
+	if err != nil {
+		return fmt.Errorf("failed to create cluster export file: %w", err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = writeClustersCSV(file, rows)
+	} else {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(rows)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write cluster export file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write cluster export file: %w", err)
+	}
+
+	cmdLogger.Debugf("Exported %d clusters to %s", len(rows), path)
+	return nil
+}
+
+// writeClustersCSV writes the clusters as CSV with a header row.
+func writeClustersCSV(file *os.File, rows []clusterExport) error {
+	w := csv.NewWriter(file)
+	if err := w.Write(clusterExportHeader); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := w.Write([]string{
+			row.Environment, row.Identifier, row.Endpoint, row.ReaderEndpoint,
+			strconv.Itoa(int(row.Port)), row.Engine, row.Region, row.Arn,
+		}); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"rds-iam-connect/internal/rds"
+
+	"github.com/spf13/cobra"
+)
+
+// Output formats supported by the export-dsn command.
+const (
+	dsnOutputDSN     = "dsn"
+	dsnOutputEnv     = "env"
+	dsnOutputContext = "context"
+)
+
+var (
+	dsnOutput   string
+	contextName string
+)
+
+// exportDSNCmd prints a driver connection string for the selected cluster and user.
+var exportDSNCmd = &cobra.Command{
+	Use:   "export-dsn",
+	Short: "Print a driver connection string with a fresh IAM auth token",
+	Long: `Select an environment, cluster and user, generate an IAM authentication token and print
+a ready-to-use DSN: a go-sql-driver/mysql DSN for MySQL clusters or a postgres:// URL for PostgreSQL clusters.
+The token is valid for 15 minutes.
+
+With --output context, the connection details are stored as a named context in connections.yaml in the
+configuration directory instead, so that other tooling can reference the connection by name. See the contexts command.`,
+	Args: cobra.NoArgs,
+	RunE: runExportDSN,
+}
+
+// runExportDSN is the execution function for the export-dsn command.
+func runExportDSN(_ *cobra.Command, _ []string) error {
+	if dsnOutput != dsnOutputDSN && dsnOutput != dsnOutputEnv && dsnOutput != dsnOutputContext {
+		return fmt.Errorf("invalid output format %q: must be %q, %q or %q", dsnOutput, dsnOutputDSN, dsnOutputEnv, dsnOutputContext)
+	}
+	if contextName != "" && dsnOutput != dsnOutputContext {
+		return fmt.Errorf("--context-name requires --output %s", dsnOutputContext)
+	}
+
+	ctx, stop := notifyShutdown(context.Background())
+	defer stop()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	conn, err := selectEnvironment(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	cluster, user, err := selectClusterAndUser(ctx, cfg, conn)
+	if err != nil {
+		return err
+	}
+
+	if err := checkIAMPermissions(ctx, cfg, conn, cluster, user); err != nil {
+		return err
+	}
+
+	// The context holds no token, which would expire long before the context is used
+	if dsnOutput == dsnOutputContext {
+		name, err := saveContext(contextName, conn.Environment(), cluster, user)
+		if err != nil {
+			return err
+		}
+		fmt.Println(name)
+		return nil
+	}
+
+	token, err := conn.Token(ctx, cluster, user)
+	if err != nil {
+		return err
+	}
+
+	dsn := buildDSN(cluster, user, token)
+	if dsnOutput == dsnOutputEnv {
+		fmt.Printf("export DATABASE_URL=%s\n", shellQuote(dsn))
+		fmt.Printf("export DB_HOST=%s\n", shellQuote(cluster.Endpoint))
+		fmt.Printf("export DB_PORT=%d\n", cluster.ClientPort())
+		fmt.Printf("export DB_USER=%s\n", shellQuote(user))
+		fmt.Printf("export DB_PASSWORD=%s\n", shellQuote(token))
+		return nil
+	}
+
+	fmt.Println(dsn)
+	return nil
+}
+
+// buildDSN returns a connection string for the cluster using the token as the password.
+// PostgreSQL clusters get a postgres:// URL with the credentials URL-encoded. MySQL clusters get a
+// go-sql-driver/mysql DSN, which takes the password verbatim up to the last '@', so the token is not encoded.
+func buildDSN(cluster rds.Cluster, user, token string) string {
+	addr := net.JoinHostPort(cluster.Endpoint, strconv.Itoa(int(cluster.ClientPort())))
+
+	if cluster.IsPostgres() {
+		return fmt.Sprintf("postgres://%s:%s@%s/?sslmode=require", escapeUserInfo(user), escapeUserInfo(token), addr)
+	}
+
+	return fmt.Sprintf("%s:%s@tcp(%s)/?tls=true&allowCleartextPasswords=true", user, token, addr)
+}
+
+// escapeUserInfo percent-encodes every character of s other than unreserved ones.
+// Unlike url.UserPassword, it also encodes sub-delimiters such as '&' and '=', which
+// appear in auth tokens and confuse some URL parsers.
+func escapeUserInfo(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// shellQuote quotes s for safe use in a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func init() {
+	exportDSNCmd.Flags().StringVarP(&dsnOutput, "output", "o", dsnOutputDSN, "output format: dsn, env (shell export lines) or context (store a named connection)")
+	exportDSNCmd.Flags().StringVar(&contextName, "context-name", "", "name of the context stored with --output context (default derived from the environment, cluster and user)")
+	rootCmd.AddCommand(exportDSNCmd)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+
+	"rds-iam-connect/config"
+)
+
+// Values of the command-line flags of the root command.
+var (
+	configPath     string
+	debugMode      bool
+	checkOnly      bool
+	selectInstance bool
+	selectReader   bool
+	selectGlobal   bool
+	loopMode       bool
+	prefixes       []string
+	maskAccount    bool
+	useIAMIdentity bool
+	execSQL        string
+	useDataAPI     bool
+	quietMode      bool
+	asciiMode      bool
+	exportPath     string
+	exportOnly     bool
+	maxConnections int
+	readOnly       bool
+	sslMode        string
+	tlsMinVersion  string
+	clientCharset  string
+	defaultsFile   bool
+	clusterMatch   string
+	ssmTarget      string
+	printTokenMode bool
+	profileName    string
+	skipIAMCheck   bool
+	forceIAMCheck  bool
+	healthAddr     string
+	tokenOutput    string
+	suppliedToken  string
+	searchMode     bool
+	noRanking      bool
+	engineFilter   string
+	dumpRawPath    string
+	refreshCache   bool
+	tokenFile      string
+
+	// selectFirst picks the first role, environment, cluster and user instead of prompting.
+	selectFirst bool
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "config.yaml", "path to config file")
+	rootCmd.PersistentFlags().BoolVar(&debugMode, "debug", false, "enable debug logging, overriding the config file setting")
+	rootCmd.PersistentFlags().BoolVarP(&quietMode, "quiet", "q", false, "suppress progress output")
+	rootCmd.PersistentFlags().BoolVar(&asciiMode, "ascii", false, "use ASCII markers such as [OK] and [FAIL] instead of Unicode symbols")
+	rootCmd.PersistentFlags().BoolVar(&maskAccount, "mask-account", false, "mask AWS account IDs in ARNs shown in output")
+	rootCmd.PersistentFlags().BoolVar(&refreshCache, "refresh-cache", false, "discover clusters from AWS even if the cache is valid, and rewrite the cache")
+	rootCmd.Flags().BoolVarP(&checkOnly, "check", "c", false, "verify the RDS IAM Connect tool configuration and environment")
+	rootCmd.Flags().BoolVar(&selectInstance, "instance", false, "connect to a specific cluster member instance instead of the cluster endpoint")
+	rootCmd.Flags().BoolVar(&selectReader, "reader", false, "choose between the writer and reader endpoints, including specific reader instances")
+	rootCmd.Flags().BoolVar(&selectGlobal, "global", false, "choose a region and endpoint of the cluster's Aurora global database, e.g. a secondary region's reader")
+	rootCmd.Flags().StringVar(&execSQL, "exec", "", "execute the SQL statement and exit instead of opening an interactive session")
+	rootCmd.Flags().BoolVar(&useDataAPI, "data-api", false, "execute the --exec statement through the RDS Data API instead of the mysql client")
+	rootCmd.Flags().StringVar(&exportPath, "export-clusters", "", "write the discovered clusters to a file (CSV if it ends in .csv, JSON otherwise)")
+	rootCmd.Flags().BoolVar(&exportOnly, "export-only", false, "exit after writing the --export-clusters file instead of connecting")
+	rootCmd.Flags().IntVar(&maxConnections, "max-connections", 0, "warn when the user has connected to the cluster this many times within the audit window (requires audit logging)")
+	rootCmd.Flags().StringVar(&sslMode, "ssl-mode", "", "TLS mode: required, verify-ca or verify-identity (verify-full); overrides sslMode in the config")
+	rootCmd.Flags().StringVar(&clientCharset, "charset", "", "character set of the client connection, e.g. utf8mb4 or latin1; overrides connect.charset in the config")
+	rootCmd.Flags().StringVar(&tlsMinVersion, "tls-min-version", "", "minimum TLS version of the database connection: TLSv1.2 or TLSv1.3; overrides ssl.minVersion in the config")
+	rootCmd.Flags().StringVar(&healthAddr, "http", "", "with --check, serve check results as JSON at /healthz on this address, e.g. :8080")
+	rootCmd.Flags().BoolVar(&skipIAMCheck, "skip-iam-check", false, "skip the IAM permission check for this run, overriding checkIAMPermissions")
+	rootCmd.Flags().BoolVar(&forceIAMCheck, "force-iam-check", false, "run the IAM permission check for this run, overriding checkIAMPermissions")
+	rootCmd.Flags().StringVar(&profileName, "profile-name", "", "connect with the environment, cluster and user of a configured profile, without prompts")
+	rootCmd.Flags().BoolVar(&printTokenMode, "print-token", false, "print an auth token for the selected cluster and user instead of connecting")
+	rootCmd.Flags().StringVar(&tokenOutput, "output", tokenOutputText, "output format of --print-token: text or json")
+	rootCmd.Flags().StringVar(&suppliedToken, "token", "", "connect with this pre-generated auth token instead of generating one (visible in the process list, prefer --token-file)")
+	rootCmd.Flags().StringVar(&tokenFile, "token-file", "", "connect with the pre-generated auth token read from this file instead of generating one")
+	rootCmd.Flags().StringVar(&ssmTarget, "ssm-target", "", "connect through SSM Session Manager port forwarding via this instance ID")
+	rootCmd.Flags().BoolVar(&selectFirst, "select-first", false, "connect to the first discovered cluster as the first allowed user without prompting, e.g. for smoke tests")
+	rootCmd.Flags().BoolVar(&noRanking, "no-ranking", false, "list clusters in discovery order instead of often and recently used clusters first")
+	rootCmd.Flags().BoolVar(&searchMode, "search", false, "search clusters by identifier, endpoint, engine or tag values, with an option to refresh them from AWS")
+	rootCmd.Flags().StringVar(&clusterMatch, "cluster-match", "", "select the single cluster whose identifier contains this substring")
+	rootCmd.Flags().BoolVar(&defaultsFile, "defaults-file", false, "pass credentials to mysql in a temporary --defaults-extra-file instead of the command line")
+	rootCmd.Flags().BoolVar(&readOnly, "read-only", false, "make the session read-only to prevent accidental writes")
+	rootCmd.Flags().BoolVar(&useIAMIdentity, "use-iam-identity", false, "connect as the DB user named after the current IAM role instead of prompting for a user")
+	rootCmd.Flags().BoolVar(&loopMode, "loop", false, "after the mysql client exits, prompt for a user and reconnect to the same cluster")
+	rootCmd.Flags().StringSliceVar(&prefixes, "prefix", nil, "only show clusters whose identifier starts with one of these comma-separated prefixes")
+	rootCmd.Flags().StringVar(&engineFilter, "engine", "", "only show clusters of this engine family: mysql or postgres")
+	rootCmd.Flags().StringVar(&dumpRawPath, "dump-raw", "", "write the raw DescribeDBClusters responses as JSON to this file, or stderr without a file")
+	rootCmd.Flags().Lookup("dump-raw").NoOptDefVal = "-"
+	_ = rootCmd.Flags().MarkHidden("dump-raw")
+	rootCmd.MarkFlagsMutuallyExclusive("instance", "reader", "global")
+	rootCmd.MarkFlagsMutuallyExclusive("data-api", "loop")
+	rootCmd.MarkFlagsMutuallyExclusive("print-token", "loop", "data-api")
+	rootCmd.MarkFlagsMutuallyExclusive("profile-name", "cluster-match", "select-first")
+	rootCmd.MarkFlagsMutuallyExclusive("skip-iam-check", "force-iam-check")
+	rootCmd.MarkFlagsMutuallyExclusive("token", "token-file")
+	rootCmd.MarkFlagsMutuallyExclusive("token", "loop", "print-token", "data-api")
+	rootCmd.MarkFlagsMutuallyExclusive("token-file", "loop", "print-token", "data-api")
+}
+
+// applyFlags overrides the settings of the configuration with the flags that were given.
+// Values of the SSL, TLS and charset flags are validated like those of the config file.
+func applyFlags(cfg *config.Config) error {
+	if debugMode {
+		cfg.Debug = true
+	}
+	if useIAMIdentity {
+		cfg.UseIAMIdentity = true
+	}
+	if ssmTarget != "" {
+		cfg.SSM.Target = ssmTarget
+	}
+	if skipIAMCheck {
+		cfg.CheckIAMPermissions = false
+		cfg.WarnOnNoAccess = false
+	}
+	if forceIAMCheck {
+		cfg.CheckIAMPermissions = true
+	}
+	if searchMode {
+		cfg.UI.Search = true
+	}
+	if noRanking {
+		cfg.UI.NoRanking = true
+	}
+	if asciiMode {
+		cfg.UI.ASCII = &asciiMode
+	}
+	if maskAccount {
+		cfg.Redaction.AccountIDs = true
+	}
+	if sslMode != "" {
+		if err := config.ValidateSSLMode(sslMode); err != nil {
+			return fmt.Errorf("invalid --ssl-mode: %w", err)
+		}
+		cfg.SSLMode = sslMode
+	}
+	if tlsMinVersion != "" {
+		if err := config.ValidateTLSMinVersion(tlsMinVersion); err != nil {
+			return fmt.Errorf("invalid --tls-min-version: %w", err)
+		}
+		cfg.SSL.MinVersion = tlsMinVersion
+	}
+	if clientCharset != "" {
+		if err := config.ValidateCharset(clientCharset); err != nil {
+			return fmt.Errorf("invalid --charset: %w", err)
+		}
+		cfg.Connect.Charset = clientCharset
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/pkg/connect"
+)
+
+// selectGlobalEndpoint prompts for a region and endpoint of the Aurora global database the cluster
+// belongs to, e.g. the reader of a secondary region for DR testing. The returned cluster carries the
+// region of the chosen member, so the auth token is signed for that region.
+func selectGlobalEndpoint(ctx context.Context, conn *connect.Connector, cluster rds.Cluster) (rds.Cluster, error) {
+	members, err := conn.GlobalMembers(ctx, cluster)
+	if err != nil {
+		return rds.Cluster{}, err
+	}
+	if len(members) == 0 {
+		return rds.Cluster{}, fmt.Errorf("no members of the global database of cluster %s have an available endpoint", cluster.Identifier)
+	}
+
+	options := make([]string, 0, 2*len(members))
+	endpoints := make(map[string]rds.Cluster, 2*len(members))
+	for _, member := range members {
+		display := fmt.Sprintf("%s %s: cluster endpoint (%s:%d)", member.Region, member.GlobalRole(), member.Endpoint, member.Port)
+		options = append(options, display)
+		endpoints[display] = member
+
+		if member.ReaderEndpoint != "" {
+			reader := member
+			reader.Endpoint = member.ReaderEndpoint
+			display := fmt.Sprintf("%s %s: reader endpoint (%s:%d)", member.Region, member.GlobalRole(), reader.Endpoint, reader.Port)
+			options = append(options, display)
+			endpoints[display] = reader
+		}
+	}
+
+	var selected string
+	prompt := newSelect(fmt.Sprintf("Choose a region of global database %s:", members[0].GlobalCluster), options)
+	if err := askOne(prompt, &selected); err != nil {
+		return rds.Cluster{}, fmt.Errorf("failed to select global database endpoint: %w", err)
+	}
+
+	chosen := endpoints[selected]
+	cmdLogger.Debugf("Selected %s cluster %s in %s", chosen.GlobalRole(), chosen.Identifier, chosen.Region)
+	return chosen, nil
+}
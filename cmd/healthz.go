@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/pkg/connect"
+)
+
+const (
+	// healthCacheTTL is how long check results are reused before the checks run again.
+	healthCacheTTL = 30 * time.Second
+	// healthCheckTimeout bounds a run of the checks, below the 10 second timeout probes commonly use,
+	// so that a hanging AWS call fails the checks instead of the probe.
+	healthCheckTimeout = 8 * time.Second
+	// healthReadHeaderTimeout bounds how long the server waits for request headers.
+	healthReadHeaderTimeout = 10 * time.Second
+	// healthShutdownTimeout bounds how long in-flight requests may take after an interrupt.
+	healthShutdownTimeout = 5 * time.Second
+)
+
+// healthCheckResult is the outcome of a single check phase.
+type healthCheckResult struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// healthReport is the JSON body served at /healthz.
+type healthReport struct {
+	Status    string              `json:"status"`
+	CheckedAt time.Time           `json:"checkedAt"`
+	Checks    []healthCheckResult `json:"checks"`
+}
+
+// healthServer runs the --check phases on demand and caches the results for healthCacheTTL.
+type healthServer struct {
+	ctx   context.Context                         // Checks are not tied to a request, as their results are shared.
+	check func(ctx context.Context) *healthReport // Runs the checks.
+
+	mu      sync.Mutex
+	report  *healthReport
+	running chan struct{} // Closed when the running checks finish; nil if none are running.
+}
+
+// runHealthServer serves the check results at /healthz on addr until ctx is cancelled.
+func runHealthServer(ctx context.Context, cfg *config.Config, conn *connect.Connector, addr string) error {
+	health := &healthServer{
+		ctx: ctx,
+		check: func(ctx context.Context) *healthReport {
+			return runHealthChecks(ctx, cfg, conn)
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", health.handle)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: healthReadHeaderTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), healthShutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving check results at http://%s/healthz\n", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve health endpoint: %w", err)
+	}
+	return nil
+}
+
+// handle serves the cached check results, running the checks if they are missing or stale.
+// It responds with 200 if all checks passed and 503 otherwise.
+func (h *healthServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, err := h.current(r.Context())
+	if err != nil {
+		http.Error(w, "checks have not finished yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// current returns the latest report, starting a run of the checks if it is missing or stale. Only one
+// run is in flight at a time and the stale report is served meanwhile, so that only requests arriving
+// before the first report wait for the checks, until ctx is done.
+func (h *healthServer) current(ctx context.Context) (*healthReport, error) {
+	h.mu.Lock()
+	if h.running == nil && (h.report == nil || time.Since(h.report.CheckedAt) > healthCacheTTL) {
+		h.running = make(chan struct{})
+		go h.refresh(h.running)
+	}
+	report, running := h.report, h.running
+	h.mu.Unlock()
+	if report != nil {
+		return report, nil
+	}
+
+	select {
+	case <-running:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.report, nil
+}
+
+// refresh runs the checks within healthCheckTimeout, stores their report and closes done.
+func (h *healthServer) refresh(done chan struct{}) {
+	ctx, cancel := context.WithTimeout(h.ctx, healthCheckTimeout)
+	defer cancel()
+	report := h.check(ctx)
+
+	h.mu.Lock()
+	h.report, h.running = report, nil
+	h.mu.Unlock()
+	close(done)
+}
+
+// runHealthChecks runs the same phases as --check and collects their results instead of
+// stopping at the first failure. The details the checks report are not printed.
+func runHealthChecks(ctx context.Context, cfg *config.Config, conn *connect.Connector) *healthReport {
+	report := &healthReport{Status: "ok", CheckedAt: time.Now()}
+
+	record := func(name string, check func() (checkDetails, error)) {
+		start := time.Now()
+		_, err := check()
+		result := healthCheckResult{Name: name, OK: err == nil, Duration: formatElapsed(time.Since(start))}
+		if err != nil {
+			result.Error = err.Error()
+			report.Status = "failing"
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	record("aws credentials", func() (checkDetails, error) { return checkAWSCredentials(ctx, conn) })
+	record("configuration", func() (checkDetails, error) { return checkConfiguration(cfg) })
+
+	envs := make([]string, 0, len(cfg.EnvTag))
+	for env := range cfg.EnvTag {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	for _, env := range envs {
+		record("rds connectivity: "+env, func() (checkDetails, error) {
+			envConn, err := connect.New(cfg, env)
+			if err != nil {
+				return nil, err
+			}
+			return checkRDSConnectivity(ctx, cfg, envConn)
+		})
+	}
+
+	record("cache", func() (checkDetails, error) { return checkCache(cfg) })
+
+	return report
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthServerCurrent(t *testing.T) {
+	var runs atomic.Int32
+	release := make(chan struct{})
+	h := &healthServer{
+		ctx: context.Background(),
+		check: func(ctx context.Context) *healthReport {
+			runs.Add(1)
+			<-release
+			_, hasDeadline := ctx.Deadline()
+			assert.True(t, hasDeadline, "checks must run with a timeout")
+			return &healthReport{Status: "ok", CheckedAt: time.Now()}
+		},
+	}
+
+	// Without a report, requests wait for the first run until they are cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := h.current(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	report, err := h.current(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ok", report.Status)
+	assert.Equal(t, int32(1), runs.Load())
+
+	// A stale report is served while a single run refreshes it
+	stale := &healthReport{Status: "failing", CheckedAt: time.Now().Add(-2 * healthCacheTTL)}
+	h.mu.Lock()
+	h.report = stale
+	h.mu.Unlock()
+	release = make(chan struct{})
+	for i := 0; i < 3; i++ {
+		report, err := h.current(context.Background())
+		require.NoError(t, err)
+		assert.Same(t, stale, report)
+	}
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		report, err := h.current(context.Background())
+		return err == nil && report.Status == "ok"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(2), runs.Load())
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"rds-iam-connect/internal/audit"
+
+	"github.com/spf13/cobra"
+)
+
+// Output formats supported by the history command.
+const (
+	historyOutputTable = "table"
+	historyOutputJSON  = "json"
+)
+
+var (
+	historyLimit  int
+	historyOutput string
+)
+
+// historyCmd prints the recent connections recorded in the audit log.
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Print recent connections from the audit log",
+	Long: `Print the most recent connections recorded in the audit log, newest first, with the time, environment,
+cluster, DB user and result. Connections that are still running, or whose session was killed, show the
+result "started". Requires audit.enabled in the configuration. With --output json, the audit
+entries are printed as a JSON array instead.`,
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+}
+
+// runHistory is the execution function for the history command.
+func runHistory(_ *cobra.Command, _ []string) error {
+	if historyOutput != historyOutputTable && historyOutput != historyOutputJSON {
+		return fmt.Errorf("invalid output format %q: must be %q or %q", historyOutput, historyOutputTable, historyOutputJSON)
+	}
+	if historyLimit < 0 {
+		return fmt.Errorf("--limit must not be negative")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if !cfg.Audit.Enabled {
+		fmt.Fprintln(os.Stderr, "Audit logging is disabled, so no connections are recorded. "+
+			"Set audit.enabled: true in the configuration to keep a history of connections.")
+		return nil
+	}
+
+	path, err := cfg.AuditPath()
+	if err != nil {
+		return fmt.Errorf("failed to get audit log path: %w", err)
+	}
+	entries, err := audit.New(path).Since(time.Time{})
+	if err != nil {
+		return err
+	}
+	entries = recentEntries(audit.Connections(entries), historyLimit)
+
+	if historyOutput == historyOutputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintf(os.Stderr, "No connections recorded in %s yet.\n", path)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tENVIRONMENT\tCLUSTER\tUSER\tRESULT")
+	for _, entry := range entries {
+		result := entry.Result
+		if result == "" {
+			// Entries written before results were recorded
+			result = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.Time.Local().Format("2006-01-02 15:04:05"),
+			entry.Environment, entry.Cluster, entry.User, result)
+	}
+	return w.Flush()
+}
+
+// recentEntries returns the last limit entries of the audit log, newest first. A limit of 0 returns all entries.
+func recentEntries(entries []audit.Entry, limit int) []audit.Entry {
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	recent := make([]audit.Entry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		recent = append(recent, entries[i])
+	}
+	return recent
+}
+
+func init() {
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "maximum number of connections to print, 0 for all")
+	historyCmd.Flags().StringVarP(&historyOutput, "output", "o", historyOutputTable, "output format: table or json")
+	rootCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"text/tabwriter"
+
+	"rds-iam-connect/internal/rds"
+
+	"github.com/spf13/cobra"
+)
+
+// Output formats supported by the list-clusters command.
+const (
+	listOutputTable     = "table"
+	listOutputTerraform = "terraform"
+)
+
+var (
+	listVerbose bool
+	listOutput  string
+)
+
+// listClustersCmd prints the clusters discovered in an environment.
+var listClustersCmd = &cobra.Command{
+	Use:   "list-clusters",
+	Short: "List the RDS clusters of an environment",
+	Long: `Select an environment and list its clusters that match the configured tags and have IAM authentication enabled.
+With --verbose, clusters that match but are not connectable yet, e.g. because they are still being created,
+are listed as well. With --output terraform, Terraform import blocks are printed for the clusters instead.`,
+	Args: cobra.NoArgs,
+	RunE: runListClusters,
+}
+
+// runListClusters is the execution function for the list-clusters command.
+func runListClusters(_ *cobra.Command, _ []string) error {
+	if listOutput != listOutputTable && listOutput != listOutputTerraform {
+		return fmt.Errorf("invalid output format %q: must be %q or %q", listOutput, listOutputTable, listOutputTerraform)
+	}
+
+	ctx, stop := notifyShutdown(context.Background())
+	defer stop()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	conn, err := selectEnvironment(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	clusters, err := discoverClusters(ctx, cfg, conn)
+	if err != nil {
+		return err
+	}
+
+	if listOutput == listOutputTerraform {
+		return writeTerraformImports(os.Stdout, clusters)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if clusterTemplate != nil {
+		// Clusters are shown in the configured format instead of the default columns
+		fmt.Fprintln(w, "CLUSTER\tSTATUS")
+		for _, cluster := range clusters {
+			fmt.Fprintf(w, "%s\tconnectable\n", clusterName(cluster))
+		}
+		if listVerbose {
+			for _, cluster := range conn.UnavailableClusters() {
+				fmt.Fprintf(w, "%s\tnot connectable (endpoint not yet available, status: %s)\n", clusterName(cluster), cluster.Status)
+			}
+		}
+		return w.Flush()
+	}
+
+	fmt.Fprintln(w, "IDENTIFIER\tENDPOINT\tPORT\tENGINE\tSTATUS")
+	for _, cluster := range clusters {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\tconnectable\n", cluster.Identifier, displayEndpoint(cluster.Endpoint), cluster.ClientPort(), cluster.Engine)
+	}
+	if listVerbose {
+		for _, cluster := range conn.UnavailableClusters() {
+			fmt.Fprintf(w, "%s\t-\t-\t%s\tnot connectable (endpoint not yet available, status: %s)\n",
+				cluster.Identifier, cluster.Engine, cluster.Status)
+		}
+	}
+	return w.Flush()
+}
+
+// terraformNameInvalid matches the characters that are not allowed in Terraform resource names.
+var terraformNameInvalid = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// writeTerraformImports writes a Terraform import block for each cluster, importing clusters as
+// aws_rds_cluster and standalone instances as aws_db_instance resources named after their identifier.
+// Identifiers that map to the same name, e.g. clusters of the same identifier in different accounts or
+// regions, or "orders-db" and "orders_db", get the suffixes _2, _3 and so on in the order of clusters.
+func writeTerraformImports(w io.Writer, clusters []rds.Cluster) error {
+	used := make(map[string]bool, len(clusters))
+	for i, cluster := range clusters {
+		resourceType := "aws_rds_cluster"
+		if cluster.Standalone {
+			resourceType = "aws_db_instance"
+		}
+		address := resourceType + "." + terraformName(cluster.Identifier)
+		for n := 2; used[address]; n++ {
+			address = fmt.Sprintf("%s.%s_%d", resourceType, terraformName(cluster.Identifier), n)
+		}
+		used[address] = true
+
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "import {\n  to = %s\n  id = %q\n}\n", address, cluster.Identifier)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// terraformName converts an identifier into a valid Terraform resource name, e.g. "orders-db" to "orders_db".
+// Names must not start with a digit, so such names get a leading underscore.
+func terraformName(identifier string) string {
+	name := terraformNameInvalid.ReplaceAllString(identifier, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+func init() {
+	listClustersCmd.Flags().BoolVarP(&listVerbose, "verbose", "v", false, "also list matching clusters that are not connectable yet")
+	listClustersCmd.Flags().StringVarP(&listOutput, "output", "o", listOutputTable, "output format: table or terraform")
+	rootCmd.AddCommand(listClustersCmd)
+}
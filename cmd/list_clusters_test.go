@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"rds-iam-connect/internal/rds"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerraformName(t *testing.T) {
+	assert.Equal(t, "orders_db", terraformName("orders-db"))
+	assert.Equal(t, "_1st_db", terraformName("1st-db"))
+	assert.Equal(t, "_", terraformName(""))
+}
+
+func TestWriteTerraformImportsDeduplicatesNames(t *testing.T) {
+	clusters := []rds.Cluster{
+		{Identifier: "orders-db", Account: "111111111111"},
+		{Identifier: "orders-db", Account: "222222222222"},
+		{Identifier: "orders_db"},
+		{Identifier: "orders_db_2"},
+		{Identifier: "orders-db", Standalone: true},
+	}
+
+	var out strings.Builder
+	require.NoError(t, writeTerraformImports(&out, clusters))
+
+	var addresses []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if to, ok := strings.CutPrefix(line, "  to = "); ok {
+			addresses = append(addresses, to)
+		}
+	}
+	assert.Equal(t, []string{
+		"aws_rds_cluster.orders_db",
+		"aws_rds_cluster.orders_db_2",
+		"aws_rds_cluster.orders_db_3",
+		"aws_rds_cluster.orders_db_2_2",
+		"aws_db_instance.orders_db",
+	}, addresses)
+}
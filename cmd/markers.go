@@ -0,0 +1,21 @@
+package cmd
+
+import "rds-iam-connect/config"
+
+// Status markers of the --check and doctor output. setMarkers switches them to ASCII.
+var markOK, markFail, markWarn string
+
+// setMarkers selects the ASCII markers [OK], [FAIL] and [WARN] instead of the Unicode symbols, for
+// terminals that cannot render them. The survey prompts already use ASCII icons by default.
+func setMarkers(ascii bool) {
+	if ascii {
+		markOK, markFail, markWarn = "[OK]", "[FAIL]", "[WARN]"
+	} else {
+		markOK, markFail, markWarn = "✓", "✗", "!"
+	}
+}
+
+func init() {
+	// Until the configuration is loaded, e.g. in the first doctor checks, the platform default applies
+	setMarkers(config.UISettings{}.UseASCII())
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/internal/rds"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/mattn/go-isatty"
+)
+
+// promptPassword prompts for the password of the DB user without echoing it.
+// The password is never logged.
+func promptPassword(user string) (string, error) {
+	var password string
+	prompt := &survey.Password{Message: fmt.Sprintf("Password for %s:", user)}
+	if err := askOne(prompt, &password, survey.WithValidator(survey.Required)); err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	if password == "" || strings.ContainsAny(password, "\x00\n\r") {
+		return "", fmt.Errorf("invalid password: must not be empty or contain line breaks")
+	}
+	return password, nil
+}
+
+// fallBackToPassword reports whether to connect again with the DB user's password after connecting
+// failed with err: AllowPasswordFallback is set, the server rejected the auth token and the password
+// can be prompted for.
+func fallBackToPassword(cfg *config.Config, err error) bool {
+	return cfg.AllowPasswordFallback && isAuthFailure(err) && isatty.IsTerminal(os.Stdin.Fd())
+}
+
+// connectWithPassword prompts for the password of the DB user and connects with it, for users of the
+// cluster that do not use IAM authentication.
+func connectWithPassword(ctx context.Context, cluster rds.Cluster, user string, env, args []string) error {
+	fmt.Fprintf(os.Stderr, "IAM authentication failed for user %s, falling back to password authentication.\n", user)
+	password, err := promptPassword(user)
+	if err != nil {
+		return err
+	}
+	return connectToRDS(ctx, cluster, user, password, execSQL == "", env, args...)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/internal/aws"
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/pkg/connect"
+)
+
+// checkIAMPermissions verifies IAM permissions if enabled in config.
+// When the check is disabled but WarnOnNoAccess is set, the simulation still runs and
+// a missing permission is reported as a warning instead of an error.
+func checkIAMPermissions(ctx context.Context, cfg *config.Config, conn *connect.Connector, cluster rds.Cluster, user string) error {
+	if !cfg.CheckIAMPermissions {
+		if cfg.WarnOnNoAccess {
+			warnOnNoAccess(ctx, conn, cluster, user)
+		}
+		return nil
+	}
+
+	iamRole, err := conn.Identity(ctx)
+	if err != nil {
+		return err
+	}
+
+	resourceARN, err := conn.ResourceARN(ctx, cluster, user)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Checking IAM access for role %s to resource %s\n", displayARN(iamRole), displayARN(resourceARN))
+
+	if err := conn.CheckAccess(ctx, cluster, user); err != nil {
+		printActionDecisions(err)
+		return fmt.Errorf("access denied: your IAM role '%s' does not have permission to connect to RDS instance as user '%s': %w",
+			displayARN(iamRole), user, err)
+	}
+
+	return nil
+}
+
+// printActionDecisions prints the decision of each simulated action if the IAM permission check
+// was denied, so that the allowed actions can be told from the denied ones.
+func printActionDecisions(err error) {
+	var denied *aws.AccessDeniedError
+	if !errors.As(err, &denied) {
+		return
+	}
+	for _, decision := range denied.Decisions {
+		fmt.Fprintf(os.Stderr, "  %s on %s: %s\n", decision.Action, displayARN(decision.Resource), decision.Decision)
+	}
+}
+
+// warnOnNoAccess runs the IAM permission simulation and prints a warning if it fails.
+// The connection attempt proceeds regardless.
+func warnOnNoAccess(ctx context.Context, conn *connect.Connector, cluster rds.Cluster, user string) {
+	if err := conn.CheckAccess(ctx, cluster, user); err != nil {
+		warnf("connecting may not be allowed for user '%s' on cluster %s: %s",
+			user, cluster.Identifier, displayARN(err.Error()))
+		printActionDecisions(err)
+	}
+}
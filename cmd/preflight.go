@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"rds-iam-connect/internal/certs"
+	"rds-iam-connect/internal/rds"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// preflightTimeout bounds the login of the token preflight enabled by Connect.Preflight.
+const preflightTimeout = 5 * time.Second
+
+// preflightTokenHint explains the usual causes of a token that is rejected right after it was generated.
+const preflightTokenHint = "the auth token was rejected right after it was generated: check that the clock of this machine " +
+	"is correct and that the token is signed for the cluster's region (%s); see also the hint of error 1045"
+
+// preflightLogger passes the messages of the MySQL driver to the debug log instead of stderr.
+type preflightLogger struct{}
+
+// Print implements mysql.Logger.
+func (preflightLogger) Print(v ...any) {
+	cmdLogger.Debugf("MySQL driver: %s", fmt.Sprint(v...))
+}
+
+// PreflightError is a MySQL error returned by the server when logging in with the auth token
+// in the preflight.
+type PreflightError struct {
+	Err  *mysql.MySQLError
+	Hint string // A human-friendly explanation of the likely cause, if known.
+}
+
+// Error implements the error interface.
+func (e *PreflightError) Error() string {
+	msg := fmt.Sprintf("auth token preflight failed: ERROR %d (%s): %s", e.Err.Number, e.Err.SQLState[:], e.Err.Message)
+	if e.Hint != "" {
+		msg += "\nHint: " + e.Hint
+	}
+	return msg
+}
+
+// Unwrap returns the MySQL error.
+func (e *PreflightError) Unwrap() error {
+	return e.Err
+}
+
+// AuthFailure reports whether the server rejected the token.
+func (e *PreflightError) AuthFailure() bool {
+	return e.Err.Number == mysqlAccessDenied
+}
+
+// preflightToken logs in to the MySQL cluster with the auth token over TLS and disconnects, so that a
+// token that does not work, e.g. because of clock skew or a wrong region, is reported with the server's
+// error instead of failing in the client. serverName is the endpoint the server certificate is verified
+// against, which is not the cluster endpoint when connecting through a local port. PostgreSQL clusters
+// are not checked.
+func preflightToken(ctx context.Context, cluster rds.Cluster, serverName, user, token string) error {
+	if cluster.IsPostgres() {
+		cmdLogger.Debugf("Skipping the token preflight, which only supports MySQL clusters")
+		return nil
+	}
+
+	pool, err := certs.Pool()
+	if err != nil {
+		return err
+	}
+
+	cfg := mysql.NewConfig()
+	cfg.User = user
+	cfg.Passwd = token
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(cluster.Endpoint, strconv.Itoa(int(cluster.Port)))
+	cfg.AllowCleartextPasswords = true
+	cfg.TLS = &tls.Config{RootCAs: pool, ServerName: serverName, MinVersion: tls.VersionTLS12}
+	cfg.Timeout = preflightTimeout
+	cfg.ReadTimeout = preflightTimeout
+	cfg.WriteTimeout = preflightTimeout
+	cfg.Logger = preflightLogger{}
+
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to configure token preflight: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := connector.Connect(ctx)
+	if err != nil {
+		return preflightError(err, cluster, cfg.Addr)
+	}
+	_ = conn.Close()
+
+	cmdLogger.Debugf("Auth token preflight succeeded in %s", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// preflightError maps an error of the preflight login to addr: errors returned by the server become a
+// PreflightError with a hint, other errors, e.g. a network or TLS failure, are wrapped.
+func preflightError(err error, cluster rds.Cluster, addr string) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		hint := mysqlErrorHints[int(mysqlErr.Number)]
+		if mysqlErr.Number == mysqlAccessDenied {
+			hint = fmt.Sprintf(preflightTokenHint, cluster.Region)
+		}
+		return &PreflightError{Err: mysqlErr, Hint: hint}
+	}
+	return fmt.Errorf("auth token preflight failed to connect to %s: %w", addr, err)
+}
+
+// preflightOutcome returns the error that stops the connection after the preflight, if any. A rejected
+// token is passed over with allowPasswordFallback, as the client then fails the same way and falls back
+// to the password prompt.
+func preflightOutcome(err error, allowPasswordFallback bool) error {
+	var preflightErr *PreflightError
+	if errors.As(err, &preflightErr) && preflightErr.AuthFailure() && allowPasswordFallback {
+		cmdLogger.Debugf("%v", err)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rds-iam-connect/internal/rds"
+)
+
+func TestPreflightError(t *testing.T) {
+	cluster := rds.Cluster{Identifier: "orders", Region: "eu-west-1"}
+	addr := "orders.cluster-abc.eu-west-1.rds.amazonaws.com:3306"
+
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    uint16
+		wantHint    string
+		wantAuth    bool
+		wantMessage string
+	}{
+		{
+			name:        "rejected token",
+			err:         &mysql.MySQLError{Number: 1045, SQLState: [5]byte{'2', '8', '0', '0', '0'}, Message: "Access denied for user 'app'"},
+			wantCode:    1045,
+			wantHint:    fmt.Sprintf(preflightTokenHint, "eu-west-1"),
+			wantAuth:    true,
+			wantMessage: "auth token preflight failed: ERROR 1045 (28000): Access denied for user 'app'\nHint: ",
+		},
+		{
+			name:        "mapped error",
+			err:         fmt.Errorf("handshake: %w", &mysql.MySQLError{Number: 2026, SQLState: [5]byte{'H', 'Y', '0', '0', '0'}, Message: "SSL connection error"}),
+			wantCode:    2026,
+			wantHint:    mysqlErrorHints[2026],
+			wantMessage: "auth token preflight failed: ERROR 2026 (HY000): SSL connection error",
+		},
+		{
+			name:        "unmapped error",
+			err:         &mysql.MySQLError{Number: 1049, SQLState: [5]byte{'4', '2', '0', '0', '0'}, Message: "Unknown database 'app'"},
+			wantCode:    1049,
+			wantMessage: "auth token preflight failed: ERROR 1049 (42000): Unknown database 'app'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := preflightError(tt.err, cluster, addr)
+
+			var preflightErr *PreflightError
+			require.ErrorAs(t, err, &preflightErr)
+			assert.Equal(t, tt.wantCode, preflightErr.Err.Number)
+			assert.Equal(t, tt.wantHint, preflightErr.Hint)
+			assert.Equal(t, tt.wantAuth, preflightErr.AuthFailure())
+			assert.Contains(t, err.Error(), tt.wantMessage)
+			if tt.wantHint == "" {
+				assert.NotContains(t, err.Error(), "Hint:")
+			}
+		})
+	}
+
+	t.Run("network error", func(t *testing.T) {
+		err := preflightError(context.DeadlineExceeded, cluster, addr)
+
+		var preflightErr *PreflightError
+		assert.False(t, errors.As(err, &preflightErr))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Contains(t, err.Error(), addr)
+	})
+}
+
+func TestPreflightOutcome(t *testing.T) {
+	rejected := &PreflightError{Err: &mysql.MySQLError{Number: 1045, Message: "Access denied"}}
+	unreachable := &PreflightError{Err: &mysql.MySQLError{Number: 2003, Message: "Can't connect"}}
+	networkErr := errors.New("dial tcp: i/o timeout")
+
+	tests := []struct {
+		name     string
+		err      error
+		fallback bool
+		want     error
+	}{
+		{name: "success", err: nil, fallback: false, want: nil},
+		{name: "rejected token without fallback", err: rejected, fallback: false, want: rejected},
+		{name: "rejected token with fallback", err: rejected, fallback: true, want: nil},
+		{name: "other server error with fallback", err: unreachable, fallback: true, want: unreachable},
+		{name: "network error with fallback", err: networkErr, fallback: true, want: networkErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, preflightOutcome(tt.err, tt.fallback))
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/pkg/connect"
+
+	"github.com/spf13/cobra"
+)
+
+// Actions granted by the generated IAM policy.
+var (
+	discoveryActions = []string{"rds:DescribeDBClusters"}
+	clusterActions   = []string{"rds:ListTagsForResource", "rds:DescribeDBInstances"}
+	connectActions   = []string{"rds-db:connect"}
+	simulateActions  = []string{"iam:SimulatePrincipalPolicy"}
+	groupActions     = []string{"resource-groups:ListGroupResources"}
+)
+
+// printPolicyCmd prints an IAM policy granting the permissions needed to use the tool.
+var printPolicyCmd = &cobra.Command{
+	Use:   "print-policy",
+	Short: "Print the IAM policy needed to use rds-iam-connect",
+	Long: `Discover the clusters of every configured environment and print a ready-to-attach IAM policy that grants
+cluster discovery and rds-db:connect, scoped to the matching clusters and the allowed IAM users.`,
+	Args: cobra.NoArgs,
+	RunE: runPrintPolicy,
+}
+
+// policyDocument is an IAM policy document.
+type policyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []policyStatement `json:"Statement"`
+}
+
+// policyStatement is a single statement of an IAM policy document.
+type policyStatement struct {
+	Sid      string   `json:"Sid"`
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+// runPrintPolicy is the execution function for the print-policy command.
+func runPrintPolicy(_ *cobra.Command, _ []string) error {
+	ctx, stop := notifyShutdown(context.Background())
+	defer stop()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	policy, err := buildPolicy(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(policy)
+}
+
+// buildPolicy discovers the clusters of all environments and builds a policy scoped to them.
+func buildPolicy(ctx context.Context, cfg *config.Config) (*policyDocument, error) {
+	if cfg.UseIAMIdentity {
+		return nil, fmt.Errorf("print-policy needs a list of allowed IAM users and does not support useIAMIdentity")
+	}
+
+	var (
+		discoveryResources = make(map[string]bool)
+		clusterResources   = make(map[string]bool)
+		connectResources   = make(map[string]bool)
+		simulateResources  = make(map[string]bool)
+		groupResources     = make(map[string]bool)
+	)
+
+	for env := range cfg.EnvTag {
+		conn, err := connect.New(cfg, env)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := conn.LoadAllowedUsers(ctx); err != nil {
+			return nil, err
+		}
+
+		clusters, err := conn.Discover(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("environment %s: %w", env, err)
+		}
+		if len(clusters) == 0 {
+			warnf("no clusters found in environment %s", env)
+		}
+
+		if group := cfg.EnvTag[env].ResourceGroup; group != "" {
+			groupResources[resourceGroupARN(group)] = true
+		}
+
+		for _, cluster := range clusters {
+			discoveryResources[clusterWildcardARN(cluster.Arn)] = true
+			clusterResources[cluster.Arn] = true
+			for _, user := range cfg.AllowedUsers(env) {
+				resourceARN, err := conn.ResourceARN(ctx, cluster, user)
+				if err != nil {
+					return nil, err
+				}
+				connectResources[resourceARN] = true
+			}
+		}
+
+		if cfg.CheckIAMPermissions || cfg.WarnOnNoAccess {
+			iamRole, err := conn.Identity(ctx)
+			if err != nil {
+				return nil, err
+			}
+			simulateResources[iamRole] = true
+		}
+	}
+
+	if len(connectResources) == 0 {
+		return nil, fmt.Errorf("no clusters or allowed IAM users found to build a policy for")
+	}
+
+	policy := &policyDocument{
+		Version: "2012-10-17",
+		Statement: []policyStatement{
+			{Sid: "DiscoverClusters", Effect: "Allow", Action: discoveryActions, Resource: sortedKeys(discoveryResources)},
+			{Sid: "InspectClusters", Effect: "Allow", Action: clusterActions, Resource: sortedKeys(clusterResources)},
+			{Sid: "ConnectAsAllowedUsers", Effect: "Allow", Action: connectActions, Resource: sortedKeys(connectResources)},
+		},
+	}
+	if len(simulateResources) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid: "CheckIAMPermissions", Effect: "Allow", Action: simulateActions, Resource: sortedKeys(simulateResources),
+		})
+	}
+
+	if len(groupResources) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{
+			Sid: "ListResourceGroups", Effect: "Allow", Action: groupActions, Resource: sortedKeys(groupResources),
+		})
+	}
+
+	return policy, nil
+}
+
+// resourceGroupARN returns the ARN of a resource group given by name or ARN. Groups given by name
+// match in any region and account, e.g. "arn:aws:resource-groups:*:*:group/analytics".
+func resourceGroupARN(group string) string {
+	if strings.HasPrefix(group, "arn:") {
+		return group
+	}
+	return "arn:aws:resource-groups:*:*:group/" + group
+}
+
+// clusterWildcardARN returns an ARN matching all clusters in the region and account of the given cluster ARN,
+// e.g. "arn:aws:rds:us-west-2:123456789012:cluster:*", or all instances for a standalone instance ARN.
+func clusterWildcardARN(clusterARN string) string {
+	if i := strings.LastIndex(clusterARN, ":"); i >= 0 {
+		return clusterARN[:i+1] + "*"
+	}
+	return clusterARN
+}
+
+// sortedKeys returns the keys of the set in sorted order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	rootCmd.AddCommand(printPolicyCmd)
+}
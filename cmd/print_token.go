@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/pkg/connect"
+)
+
+// Output formats supported by --print-token.
+const (
+	tokenOutputText = "text"
+	tokenOutputJSON = "json"
+)
+
+// tokenInfo is the JSON output of --print-token.
+type tokenInfo struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Endpoint  string    `json:"endpoint"`
+	Port      int32     `json:"port"`
+	User      string    `json:"user"`
+}
+
+// validateTokenOutput checks the --output format of --print-token.
+func validateTokenOutput() error {
+	if tokenOutput != tokenOutputText && tokenOutput != tokenOutputJSON {
+		return fmt.Errorf("invalid output format %q: must be %q or %q", tokenOutput, tokenOutputText, tokenOutputJSON)
+	}
+	return nil
+}
+
+// printToken generates a token for the cluster and user and writes it to out instead of connecting.
+// The text format prints the token alone, with its expiry on stderr, so that it can be captured directly.
+// The expiry is derived from the generation time, as the SDK does not report it.
+func printToken(ctx context.Context, conn *connect.Connector, cluster rds.Cluster, user string, out io.Writer) error {
+	issuedAt := time.Now()
+	token, err := conn.Token(ctx, cluster, user)
+	if err != nil {
+		return err
+	}
+	expiresAt := issuedAt.Add(rds.TokenLifetime).UTC().Truncate(time.Second)
+
+	if tokenOutput == tokenOutputJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(tokenInfo{
+			Token:     token,
+			ExpiresAt: expiresAt,
+			Endpoint:  cluster.Endpoint,
+			Port:      cluster.ClientPort(),
+			User:      user,
+		})
+	}
+
+	fmt.Fprintf(os.Stderr, "Token expires at %s\n", expiresAt.Format(time.RFC3339))
+	_, err = fmt.Fprintln(out, token)
+	return err
+}
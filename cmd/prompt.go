@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/internal/rds"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/terminal"
+)
+
+// promptUserSelections handles user interaction to select cluster and IAM user.
+// It presents interactive prompts for selecting a cluster and user from the provided lists.
+// Returns the selected cluster, user, and any error that occurred.
+func promptUserSelections(clusters []rds.Cluster, allowedUsers []string) (rds.Cluster, string, error) {
+	cluster, err := promptClusterSelection(clusters)
+	if err != nil {
+		return rds.Cluster{}, "", err
+	}
+
+	selectedUser, err := promptUserSelection(allowedUsers)
+	if err != nil {
+		return rds.Cluster{}, "", fmt.Errorf("failed to select user: %w", err)
+	}
+
+	return cluster, selectedUser, nil
+}
+
+// promptClusterSelection presents an interactive prompt for selecting an RDS cluster.
+// Returns the selected cluster or an error if the selection fails.
+func promptClusterSelection(clusters []rds.Cluster) (rds.Cluster, error) {
+	clusters = orderClusters(clusters)
+	clusterNames := clusterDisplayNames(clusters)
+	clusterMap := make(map[string]rds.Cluster, len(clusters))
+	for i, display := range clusterNames {
+		clusterMap[display] = clusters[i]
+	}
+
+	var selectedCluster string
+	if err := askOne(newSelect("Choose an RDS cluster:", clusterNames), &selectedCluster); err != nil {
+		return rds.Cluster{}, fmt.Errorf("failed to select cluster: %w", err)
+	}
+
+	return clusterMap[selectedCluster], nil
+}
+
+// promptUserSelection presents an interactive prompt for selecting an IAM user.
+// Returns the selected user or an error if the selection fails.
+func promptUserSelection(users []string) (string, error) {
+	var selectedUser string
+	if err := askOne(newSelect("Choose an IAM user:", users), &selectedUser); err != nil {
+		return "", err
+	}
+
+	return selectedUser, nil
+}
+
+// promptEnvironmentSelection presents an interactive prompt for selecting an environment.
+// It takes a map of environment tags and returns the selected environment name.
+// Returns an error if the selection fails.
+func promptEnvironmentSelection(envTags map[string]config.EnvConfig) (string, error) {
+	environments := make([]string, 0, len(envTags))
+	for env := range envTags {
+		environments = append(environments, env)
+	}
+
+	var selectedEnv string
+	if err := askOne(newSelect("Choose environment:", environments), &selectedEnv); err != nil {
+		return "", fmt.Errorf("failed to select environment: %w", err)
+	}
+
+	return selectedEnv, nil
+}
+
+// Choices offered when the cached clusters are getting old.
+const (
+	useCacheOption     = "Use cached data"
+	refreshCacheOption = "Refresh from AWS"
+)
+
+// promptUseStaleCache asks whether to use cached clusters of the given age or refresh them.
+// Returns true to use the cache. If the prompt fails, the cache is used; if it is interrupted,
+// errInterrupted is returned.
+func promptUseStaleCache(age time.Duration) (bool, error) {
+	var choice string
+	if err := askOne(newSelect(
+		fmt.Sprintf("Cluster data was cached %s ago. Use it or refresh?", age.Round(time.Minute)),
+		[]string{useCacheOption, refreshCacheOption},
+	), &choice); err != nil {
+		if errors.Is(err, errInterrupted) {
+			return false, err
+		}
+		return true, nil
+	}
+	return choice == useCacheOption, nil
+}
+
+// interruptExitCode is the conventional exit code of a program interrupted with Ctrl-C (128 + SIGINT).
+const interruptExitCode = 130
+
+// errInterrupted is returned when a prompt is aborted with Ctrl-C. Execute exits with interruptExitCode
+// without reporting it, since aborting a selection is not a failure.
+var errInterrupted = fmt.Errorf("interrupted: %w", terminal.InterruptErr)
+
+// askOne runs a survey prompt on stderr, so that stdout only carries the output of commands such as
+// export-dsn and --print-token. Pressing Ctrl-C at the prompt returns errInterrupted, which callers pass
+// on so that deferred cleanup still runs.
+func askOne(prompt survey.Prompt, response any, opts ...survey.AskOpt) error {
+	opts = append([]survey.AskOpt{survey.WithStdio(os.Stdin, os.Stderr, os.Stderr)}, opts...)
+	err := survey.AskOne(prompt, response, opts...)
+	if errors.Is(err, terminal.InterruptErr) {
+		fmt.Fprintln(os.Stderr)
+		return errInterrupted
+	}
+	return err
+}
+
+// newSelect creates a selection prompt that honors the configured UI settings.
+// Typing filters the options; with vim mode enabled, j/k move the cursor after pressing Esc.
+func newSelect(message string, options []string) *survey.Select {
+	return &survey.Select{
+		Message:  message,
+		Options:  options,
+		PageSize: uiSettings.SelectPageSize(),
+		VimMode:  uiSettings.VimMode,
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"path/filepath"
+	"sort"
+
+	"rds-iam-connect/internal/ranking"
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/internal/utils"
+)
+
+// rankingFileName is the name of the file in the cache directory that holds the cluster scores.
+const rankingFileName = "cluster-ranking.json"
+
+// rankedClusters is the number of often or recently chosen clusters listed first in the cluster prompt.
+const rankedClusters = 5
+
+// minRankScore is the score below which a cluster is no longer listed first, reached about three weeks
+// after a single choice.
+const minRankScore = 0.1
+
+// loadRanking loads the cluster scores from the cache directory. Ranking is best effort, so if they
+// cannot be loaded, the clusters are not ranked and nil is returned.
+func loadRanking() *ranking.Store {
+	cacheDir, err := utils.GetCacheDir()
+	if err != nil {
+		cmdLogger.Debugf("Not ranking clusters: %v", err)
+		return nil
+	}
+
+	store, err := ranking.Load(filepath.Join(cacheDir, rankingFileName))
+	if err != nil {
+		cmdLogger.Debugf("Not ranking clusters: %v", err)
+		return nil
+	}
+	return store
+}
+
+// rankingKey returns the key of the cluster's score. The ARN tells apart clusters of the same name in
+// other accounts or regions.
+func rankingKey(cluster rds.Cluster) string {
+	if cluster.Arn != "" {
+		return cluster.Arn
+	}
+	return cluster.Identifier
+}
+
+// orderClusters returns the clusters in the order of the cluster prompt: up to rankedClusters clusters
+// that were chosen often or recently first, whatever their engine, followed by the other clusters grouped
+// by engine family.
+func orderClusters(clusters []rds.Cluster) []rds.Cluster {
+	top, rest := rankClusters(clusters)
+	return append(top, groupByEngine(rest)...)
+}
+
+// rankClusters splits the clusters into up to rankedClusters clusters that were chosen often or recently,
+// highest score first, and the other clusters in alphabetical order. Without ranking, as with --no-ranking,
+// no cluster is ranked and the others keep the order they were discovered in.
+func rankClusters(clusters []rds.Cluster) (top, rest []rds.Cluster) {
+	if clusterRanking == nil {
+		return nil, clusters
+	}
+
+	sorted := append([]rds.Cluster(nil), clusters...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Identifier < sorted[j].Identifier })
+
+	scores := make(map[string]float64, len(sorted))
+	top = make([]rds.Cluster, 0, rankedClusters)
+	for _, cluster := range sorted {
+		if score := clusterRanking.Score(rankingKey(cluster)); score >= minRankScore {
+			scores[rankingKey(cluster)] = score
+			top = append(top, cluster)
+		}
+	}
+	sort.SliceStable(top, func(i, j int) bool { return scores[rankingKey(top[i])] > scores[rankingKey(top[j])] })
+	top = top[:min(len(top), rankedClusters)]
+
+	rest = make([]rds.Cluster, 0, len(sorted)-len(top))
+	for _, cluster := range sorted {
+		if !containsCluster(top, cluster) {
+			rest = append(rest, cluster)
+		}
+	}
+	return top, rest
+}
+
+// containsCluster reports whether the cluster is one of the clusters.
+func containsCluster(clusters []rds.Cluster, cluster rds.Cluster) bool {
+	for _, c := range clusters {
+		if rankingKey(c) == rankingKey(cluster) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordClusterChoice raises the score of the chosen cluster. Failures are only logged.
+func recordClusterChoice(cluster rds.Cluster) {
+	if err := clusterRanking.Record(rankingKey(cluster)); err != nil {
+		cmdLogger.Debugf("Failed to record cluster choice: %v", err)
+	}
+}
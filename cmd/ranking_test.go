@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rds-iam-connect/internal/ranking"
+	"rds-iam-connect/internal/rds"
+)
+
+func TestOrderClustersKeepsRankedPostgresFirst(t *testing.T) {
+	store, err := ranking.Load(filepath.Join(t.TempDir(), rankingFileName))
+	require.NoError(t, err)
+	require.NoError(t, store.Record("reports"))
+
+	previous := clusterRanking
+	clusterRanking = store
+	t.Cleanup(func() { clusterRanking = previous })
+
+	clusters := []rds.Cluster{
+		{Identifier: "orders", Engine: "aurora-mysql"},
+		{Identifier: "reports", Engine: "aurora-postgresql"},
+		{Identifier: "billing", Engine: "aurora-postgresql"},
+		{Identifier: "accounts", Engine: "aurora-mysql"},
+	}
+
+	var order []string
+	for _, cluster := range orderClusters(clusters) {
+		order = append(order, cluster.Identifier)
+	}
+	assert.Equal(t, []string{"reports", "accounts", "orders", "billing"}, order)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/internal/utils"
+)
+
+// The display functions below are the single place where output is masked according to the
+// Redaction settings of the config, which --mask-account extends with account IDs.
+
+// displayARN returns s with the ARNs it contains masked as configured: whole ARNs with
+// redaction.arns, or only their account IDs with redaction.accountIDs.
+func displayARN(s string) string {
+	if redaction.ARNs {
+		return utils.MaskARNs(s)
+	}
+	if redaction.AccountIDs {
+		return utils.MaskAccountID(s)
+	}
+	return s
+}
+
+// displayAccountID returns the account ID, masked when redaction.accountIDs is set.
+func displayAccountID(accountID string) string {
+	if redaction.AccountIDs && accountID != "" {
+		return utils.MaskedAccountID()
+	}
+	return accountID
+}
+
+// displayEndpoint returns the endpoint hostname, masked when redaction.endpoints is set.
+func displayEndpoint(endpoint string) string {
+	if redaction.Endpoints {
+		return utils.MaskEndpoint(endpoint)
+	}
+	return endpoint
+}
+
+// displayCluster returns a copy of the cluster with its endpoints, ARN and account masked as configured,
+// for rendering user-defined templates. It must not be used to connect.
+func displayCluster(cluster rds.Cluster) rds.Cluster {
+	cluster.Endpoint = displayEndpoint(cluster.Endpoint)
+	cluster.ReaderEndpoint = displayEndpoint(cluster.ReaderEndpoint)
+	cluster.Arn = displayARN(cluster.Arn)
+	cluster.Account = displayAccountID(cluster.Account)
+	return cluster
+}
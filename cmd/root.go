@@ -7,28 +7,58 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
-	"os/signal"
 	"path/filepath"
 	"strings"
+	"text/template"
+	"time"
 
 	"rds-iam-connect/config"
 	"rds-iam-connect/internal/aws"
+	"rds-iam-connect/internal/certs"
+	"rds-iam-connect/internal/cli"
+	"rds-iam-connect/internal/logger"
+	"rds-iam-connect/internal/metrics"
+	"rds-iam-connect/internal/ranking"
 	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/pkg/connect"
 
-	"log"
-
-	"github.com/AlecAivazis/survey/v2"
-	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 )
 
 var (
-	configPath string
-	rdsService *rds.DatabaseService
-	checkOnly  bool
+	cmdLogger  = logger.New(false)
+	uiSettings config.UISettings
+	redaction  config.RedactionSettings
+	// clusterRanking holds the cluster scores that order the cluster prompt, nil without ranking.
+	clusterRanking *ranking.Store
+
+	// clusterTemplate is the parsed UI.ClusterTemplate, or nil for the default cluster format.
+	clusterTemplate *template.Template
+	// promptTemplate is the parsed UI.PromptTemplate used for the mysql client prompt.
+	promptTemplate *template.Template
+	// clientCommands are the parsed Client.Commands of each engine, including the defaults.
+	clientCommands map[string][]*template.Template
+	// clientDatabase is the database passed to the client command templates.
+	clientDatabase string
+	// initCommands are the Connect.InitCommands run by the mysql client after connecting.
+	initCommands []string
+	// connectTimeout is the parsed Connect.Timeout, or 0 for the client default.
+	connectTimeout time.Duration
+	// activeProfile is the connection profile selected with --profile-name, if any.
+	activeProfile *config.ConnectionProfile
+	// metricsRecorder collects the metrics sent at the end of the run, or is nil without Metrics config.
+	metricsRecorder *metrics.Recorder
+	// sysLog receives warnings, errors and connections with Logging.Syslog, or is nil.
+	sysLog *logger.Syslog
 )
 
+// metricsFlushTimeout bounds sending the metrics at the end of the run.
+const metricsFlushTimeout = 5 * time.Second
+
+// quitOption is the selection that ends the reconnect loop.
+const quitOption = "[Quit]"
+
 // rootCmd represents the base command when called without any subcommands.
 // It provides the main functionality for connecting to RDS clusters using IAM authentication.
 var rootCmd = &cobra.Command{
@@ -42,22 +72,47 @@ It supports interactive selection of environments, clusters, and users, with opt
 // run is the main execution function for the root command.
 // It handles configuration loading, environment selection, AWS authentication,
 // cluster discovery, and establishing the RDS connection.
-func run(_ *cobra.Command, _ []string) error {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Handle interrupt signal
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt)
-	go func() {
-		<-signalChan
-		cancel()
-	}()
+func run(cmd *cobra.Command, _ []string) error {
+	// Handle Ctrl-C and SIGTERM
+	ctx, stop := notifyShutdown(context.Background())
+	defer stop()
 
 	// Load configuration
-	cfg, err := config.LoadConfig(configPath)
+	cfg, err := loadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
+	}
+	defer flushMetrics()
+
+	if printTokenMode {
+		if err := validateTokenOutput(); err != nil {
+			return err
+		}
+	}
+
+	if err := loadSuppliedToken(cmd.Flags().Changed("token")); err != nil {
+		return err
+	}
+
+	if profileName != "" {
+		profile, ok := cfg.Profiles[strings.ToLower(profileName)]
+		if !ok {
+			return fmt.Errorf("unknown profile: %s", profileName)
+		}
+		activeProfile = &profile
+		cmdLogger.Debugf("Using profile %s (environment: %s, cluster: %s)", profileName, profile.Env, profile.Cluster)
+	}
+
+	if err := validateEngineFilter(engineFilter); err != nil {
+		return err
+	}
+
+	if healthAddr != "" && !checkOnly {
+		return fmt.Errorf("--http requires --check")
+	}
+
+	if maxConnections > 0 && !cfg.Audit.Enabled {
+		return fmt.Errorf("--max-connections requires audit logging: set audit.enabled in the config")
 	}
 
 	// If check flag is set, run checks for all environments
@@ -73,426 +128,747 @@ func run(_ *cobra.Command, _ []string) error {
 			return fmt.Errorf("no environments configured")
 		}
 
-		awsCfg, err := aws.CheckAWSCredentials(cfg.EnvTag[firstEnv].Region)
+		conn, err := connect.New(cfg, firstEnv)
 		if err != nil {
-			return fmt.Errorf("failed to initialize AWS credentials: %w", err)
+			return err
 		}
+		conn.SetMetrics(metricsRecorder)
 
-		return runCheck(ctx, cfg, awsCfg)
+		if err := conn.LoadAllowedUsers(ctx); err != nil {
+			return err
+		}
+
+		if healthAddr != "" {
+			return runHealthServer(ctx, cfg, conn, healthAddr)
+		}
+		return runCheck(ctx, cfg, conn)
 	}
 
 	// Normal operation: prompt for environment selection
-	env, err := promptEnvironmentSelection(cfg.EnvTag)
+	conn, err := selectEnvironment(ctx, cfg)
 	if err != nil {
-		return fmt.Errorf("failed to select environment: %w", err)
+		return err
 	}
 
-	region := cfg.EnvTag[env].Region
-	awsCfg, err := aws.CheckAWSCredentials(region)
-	if err != nil {
-		return fmt.Errorf("failed to initialize AWS credentials: %w", err)
+	if dumpRawPath != "" {
+		closeDump, err := dumpRawResponses(conn)
+		if err != nil {
+			return err
+		}
+		defer closeDump()
+	}
+
+	// Only write the discovered clusters when running standalone
+	if exportOnly {
+		if exportPath == "" {
+			return fmt.Errorf("--export-only requires a file to write with --export-clusters")
+		}
+		_, err := discoverClusters(ctx, cfg, conn)
+		return err
+	}
+
+	// The Data API authenticates with a Secrets Manager secret, so no DB user is selected
+	if useDataAPI {
+		if execSQL == "" {
+			return fmt.Errorf("--data-api requires a statement to run with --exec")
+		}
+
+		clusters, err := discoverFilteredClusters(ctx, cfg, conn)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(clusters)
+		if err != nil {
+			return err
+		}
+		if err := warnEnvironment(cfg, conn.Environment()); err != nil {
+			return err
+		}
+		return runDataAPI(ctx, conn, cluster)
 	}
 
 	// Get clusters and handle user selection
-	cluster, user, err := selectClusterAndUser(ctx, cfg, awsCfg, env)
+	cluster, user, err := selectClusterAndUser(ctx, cfg, conn)
+	if err != nil {
+		return err
+	}
+	recordClusterChoice(cluster)
+
+	// Optionally narrow the connection down to a specific endpoint
+	switch {
+	case selectInstance:
+		cluster, err = selectClusterInstance(ctx, conn, cluster)
+	case selectReader:
+		cluster, err = selectClusterEndpoint(ctx, conn, cluster)
+	case selectGlobal:
+		cluster, err = selectGlobalEndpoint(ctx, conn, cluster)
+	}
 	if err != nil {
 		return err
 	}
 
-	// Check IAM permissions if enabled
-	if err := checkIAMPermissions(ctx, cfg, awsCfg, cluster, user); err != nil {
+	// Check IAM permissions if enabled, for the endpoint that is connected to
+	if err := checkIAMPermissions(ctx, cfg, conn, cluster, user); err != nil {
+		return err
+	}
+
+	if printTokenMode {
+		return printToken(ctx, conn, cluster, user, os.Stdout)
+	}
+
+	if err := warnEnvironment(cfg, conn.Environment()); err != nil {
 		return err
 	}
 
 	// Generate token and connect to RDS
-	return connectToRDSWithToken(ctx, awsCfg, cluster, user)
+	if !loopMode {
+		return connectToRDSWithToken(ctx, cfg, conn, cluster, user)
+	}
+
+	return connectLoop(ctx, cfg, conn, cluster, user)
 }
 
-// selectClusterAndUser handles cluster discovery and user selection.
-func selectClusterAndUser(ctx context.Context, cfg *config.Config, awsCfg *aws.Config, env string) (rds.Cluster, string, error) {
-	// Get current IAM role (not used in this function, but kept for future use)
-	if _, err := awsCfg.GetCurrentIAMRole(ctx); err != nil {
-		fmt.Printf("Warning: Could not get IAM role: %v\n", err)
+// selectEnvironment prompts for an environment and creates a connector for it,
+// with the allowed users loaded from all configured sources.
+func selectEnvironment(ctx context.Context, cfg *config.Config) (*connect.Connector, error) {
+	if err := selectRole(cfg, selectFirst); err != nil {
+		return nil, err
+	}
+
+	var env string
+	switch {
+	case activeProfile != nil:
+		env = activeProfile.Env
+	case selectFirst:
+		env = firstEnvironment(cfg)
+	default:
+		var err error
+		if env, err = promptEnvironmentSelection(cfg.EnvTag); err != nil {
+			return nil, fmt.Errorf("failed to select environment: %w", err)
+		}
 	}
 
-	rdsService = rds.NewService(*awsCfg.Config, cfg.Caching.Enabled, cfg.Caching.Duration, cfg.Debug)
-	clusters, err := rdsService.GetClusters(ctx, cfg.RdsTags.TagName, cfg.RdsTags.TagValue, "ReleaseState", cfg.EnvTag[env].ReleaseState, env)
+	cmdLogger.Debugf("Selected environment %s (regions: %s)", env, strings.Join(cfg.EnvTag[env].AllRegions(), ", "))
+	conn, err := connect.New(cfg, env)
 	if err != nil {
-		return rds.Cluster{}, "", fmt.Errorf("failed to get RDS clusters: %w", err)
+		return nil, err
 	}
+	conn.SetMetrics(metricsRecorder)
+	conn.SetRefresh(refreshCache)
 
-	if len(clusters) == 0 {
-		return rds.Cluster{}, "", fmt.Errorf("no RDS clusters found with specified tags and IAM authentication enabled")
+	if err := conn.LoadAllowedUsers(ctx); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// connectLoop repeatedly connects to the cluster, re-prompting for the user after each
+// session ends until the user chooses to quit. A fresh token is generated for every connection.
+func connectLoop(ctx context.Context, cfg *config.Config, conn *connect.Connector, cluster rds.Cluster, user string) error {
+	for {
+		if err := connectToRDSWithToken(ctx, cfg, conn, cluster, user); err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		users := cfg.AllowedUsers(conn.Environment())
+		if cfg.UseIAMIdentity {
+			users = []string{user}
+		}
+
+		next, err := promptUserSelection(append(append([]string{}, users...), quitOption))
+		if err != nil {
+			return fmt.Errorf("failed to select user: %w", err)
+		}
+		if next == quitOption {
+			return nil
+		}
+		user = next
+
+		if err := checkIAMPermissions(ctx, cfg, conn, cluster, user); err != nil {
+			return err
+		}
 	}
+}
 
-	cluster, user, err := promptUserSelections(clusters, cfg.AllowedIAMUsers)
+// loadConfig loads the configuration, applies the command-line overrides with applyFlags, and
+// initializes the command logger.
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
-		return rds.Cluster{}, "", fmt.Errorf("failed to select cluster or user: %w", err)
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	return cluster, user, nil
+	if err := applyFlags(cfg); err != nil {
+		return nil, err
+	}
+	// The preflight verifies the server certificate with the embedded bundle
+	if cfg.Connect.Preflight {
+		if _, err := certs.Pool(); err != nil {
+			return nil, fmt.Errorf("connect.preflight needs the RDS CA bundle: %w", err)
+		}
+	}
+
+	cmdLogger = logger.New(cfg.Debug)
+	uiSettings = cfg.UI
+	setMarkers(uiSettings.UseASCII())
+	if !cfg.UI.NoRanking {
+		clusterRanking = loadRanking()
+	}
+	redaction = cfg.Redaction
+	metricsRecorder = metrics.New(cfg.Metrics.StatsdAddress, cfg.Metrics.PushgatewayURL)
+
+	if cfg.Logging.Syslog {
+		if sysLog, err = logger.NewSyslog(cfg.Logging.Tag, cfg.Logging.Facility); err != nil {
+			return nil, fmt.Errorf("invalid logging settings: %w", err)
+		}
+	}
+	logger.SetSyslog(sysLog)
+	// The template is validated when the configuration is loaded
+	clusterTemplate, _ = cfg.UI.ParseClusterTemplate()
+	promptTemplate, _ = cfg.UI.ParsePromptTemplate()
+	clientCommands, _ = cfg.Client.ParseCommands()
+	clientDatabase = cfg.Client.Database
+	initCommands = cfg.Connect.InitCommands
+	// The flag overrides the config, which then applies to all connections
+	tlsMinVersion = cfg.SSL.MinVersion
+	clientCharset = cfg.Connect.Charset
+	connectTimeout, _ = cfg.ConnectTimeout()
+	cmdLogger.Debugf("Loaded configuration from %s", configPath)
+	return cfg, nil
 }
 
-// checkIAMPermissions verifies IAM permissions if enabled in config.
-func checkIAMPermissions(ctx context.Context, cfg *config.Config, awsCfg *aws.Config, cluster rds.Cluster, user string) error {
-	if !cfg.CheckIAMPermissions {
-		return nil
+// selectClusterAndUser handles cluster discovery and user selection.
+func selectClusterAndUser(ctx context.Context, cfg *config.Config, conn *connect.Connector) (rds.Cluster, string, error) {
+	// Get current IAM role, which names the DB user when connecting as the IAM identity
+	iamRole, err := conn.Identity(ctx)
+	if err != nil {
+		if cfg.UseIAMIdentity {
+			return rds.Cluster{}, "", err
+		}
+		warnf("Could not get IAM role: %s", displayARN(err.Error()))
 	}
 
-	iamRole, err := awsCfg.GetCurrentIAMRole(ctx)
+	clusters, err := discoverFilteredClusters(ctx, cfg, conn)
 	if err != nil {
-		return fmt.Errorf("failed to get IAM role: %w", err)
+		return rds.Cluster{}, "", err
 	}
 
-	if err := awsCfg.CheckIAMUserAccess(ctx, iamRole, rdsService.GetRDSInstanceIdentifier(cluster), user); err != nil {
-		return fmt.Errorf("access denied: your IAM role '%s' does not have permission to connect to RDS instance as user '%s': %w",
-			iamRole, user, err)
+	if activeProfile != nil {
+		cluster, err := resolveCluster(clusters)
+		if err != nil {
+			return rds.Cluster{}, "", err
+		}
+
+		user := activeProfile.User
+		if user == "" {
+			user, err = iamIdentityUser(iamRole)
+			if err != nil {
+				return rds.Cluster{}, "", err
+			}
+		} else if !cfg.IsAllowedUser(conn.Environment(), user) {
+			return rds.Cluster{}, "", fmt.Errorf("profile user %s is not an allowed IAM user", user)
+		}
+		return cluster, user, nil
 	}
 
-	return nil
-}
+	if cfg.UseIAMIdentity {
+		user, err := iamIdentityUser(iamRole)
+		if err != nil {
+			return rds.Cluster{}, "", err
+		}
 
-// connectToRDSWithToken generates an auth token and connects to RDS.
-func connectToRDSWithToken(_ context.Context, awsCfg *aws.Config, cluster rds.Cluster, user string) error {
-	token, err := rds.GenerateAuthToken(*awsCfg.Config, cluster, user, log.Default())
-	if err != nil {
-		return fmt.Errorf("failed to generate IAM auth token: %w", err)
+		cluster, err := resolveCluster(clusters)
+		if err != nil {
+			return rds.Cluster{}, "", err
+		}
+		return cluster, user, nil
 	}
 
-	return connectToRDS(cluster, user, token)
-}
+	if selectFirst {
+		users := cfg.AllowedUsers(conn.Environment())
+		if len(users) == 0 {
+			return rds.Cluster{}, "", fmt.Errorf("no allowed IAM users configured for environment %s", conn.Environment())
+		}
+
+		cluster, err := resolveCluster(clusters)
+		if err != nil {
+			return rds.Cluster{}, "", err
+		}
+		cmdLogger.Debugf("Selected first user %s", users[0])
+		return cluster, users[0], nil
+	}
 
-// promptUserSelections handles user interaction to select cluster and IAM user.
-// It presents interactive prompts for selecting a cluster and user from the provided lists.
-// Returns the selected cluster, user, and any error that occurred.
-func promptUserSelections(clusters []rds.Cluster, allowedUsers []string) (rds.Cluster, string, error) {
-	clusterNames := make([]string, 0, len(clusters))
-	clusterMap := make(map[string]rds.Cluster, len(clusters))
+	if clusterMatch != "" {
+		cluster, err := resolveCluster(clusters)
+		if err != nil {
+			return rds.Cluster{}, "", err
+		}
 
-	for _, cluster := range clusters {
-		display := fmt.Sprintf("%s (%s:%d)", cluster.Identifier, cluster.Endpoint, cluster.Port)
-		clusterNames = append(clusterNames, display)
-		clusterMap[display] = cluster
+		user, err := promptUserSelection(cfg.AllowedUsers(conn.Environment()))
+		if err != nil {
+			return rds.Cluster{}, "", fmt.Errorf("failed to select user: %w", err)
+		}
+		return cluster, user, nil
 	}
 
-	var selectedCluster string
-	if err := survey.AskOne(&survey.Select{
-		Message:  "Choose an RDS cluster:",
-		Options:  clusterNames,
-		PageSize: 10,
-	}, &selectedCluster); err != nil {
-		return rds.Cluster{}, "", fmt.Errorf("failed to select cluster: %w", err)
+	if cfg.UI.Search {
+		cluster, err := searchClusters(ctx, cfg, conn, clusters)
+		if err != nil {
+			return rds.Cluster{}, "", err
+		}
+
+		user, err := promptUserSelection(cfg.AllowedUsers(conn.Environment()))
+		if err != nil {
+			return rds.Cluster{}, "", fmt.Errorf("failed to select user: %w", err)
+		}
+		return cluster, user, nil
 	}
 
-	var selectedUser string
-	if err := survey.AskOne(&survey.Select{
-		Message:  "Choose an IAM user:",
-		Options:  allowedUsers,
-		PageSize: 10,
-	}, &selectedUser); err != nil {
-		return rds.Cluster{}, "", fmt.Errorf("failed to select user: %w", err)
+	cluster, user, err := promptUserSelections(clusters, cfg.AllowedUsers(conn.Environment()))
+	if err != nil {
+		return rds.Cluster{}, "", fmt.Errorf("failed to select cluster or user: %w", err)
 	}
 
-	return clusterMap[selectedCluster], selectedUser, nil
+	return cluster, user, nil
 }
 
-// connectToRDS establishes a connection to the RDS instance using the mysql client.
-// It configures and executes the mysql command with the provided connection details.
-// Returns an error if the connection fails or if the mysql client exits with an error.
-func connectToRDS(cluster rds.Cluster, user, token string) error {
-	// Validate inputs to prevent command injection
-	if !isValidHostname(cluster.Endpoint) {
-		return fmt.Errorf("invalid endpoint: %s", cluster.Endpoint)
-	}
-	if !isValidUsername(user) {
-		return fmt.Errorf("invalid username: %s", user)
-	}
-	if !isValidPort(cluster.Port) {
-		return fmt.Errorf("invalid port: %d", cluster.Port)
-	}
-
-	// Use exec.Command with separate arguments to prevent command injection
-	cmd := exec.Command("mysql")
-	cmd.Args = append(cmd.Args,
-		"-h", cluster.Endpoint,
-		"-P", fmt.Sprintf("%d", cluster.Port),
-		"-u", user,
-		"-p"+token,
-		"--enable-cleartext-plugin",
-	)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
-			return nil // Normal exit from MySQL client
-		}
-		return fmt.Errorf("failed to connect to RDS: %w", err)
+// resolveCluster selects the cluster of the active profile or the one matching --cluster-match,
+// or prompts for one if neither is set.
+func resolveCluster(clusters []rds.Cluster) (rds.Cluster, error) {
+	if activeProfile != nil {
+		for _, cluster := range clusters {
+			if cluster.Identifier == activeProfile.Cluster {
+				return cluster, nil
+			}
+		}
+		return rds.Cluster{}, fmt.Errorf("cluster %s of the profile was not found", activeProfile.Cluster)
 	}
-	return nil
-}
 
-// isValidHostname checks if a string is a valid hostname.
-func isValidHostname(hostname string) bool {
-	if len(hostname) > 253 {
-		return false
+	if selectFirst {
+		cmdLogger.Debugf("Selected first cluster %s", clusters[0].Identifier)
+		return clusters[0], nil
 	}
-	// Basic validation - can be enhanced based on requirements
-	return strings.Contains(hostname, ".") && !strings.ContainsAny(hostname, " \t\n\r")
-}
 
-// isValidUsername checks if a string is a valid MySQL username.
-func isValidUsername(username string) bool {
-	if len(username) > 32 {
-		return false
+	if clusterMatch == "" {
+		return promptClusterSelection(clusters)
 	}
-	// Basic validation - can be enhanced based on requirements
-	return !strings.ContainsAny(username, " \t\n\r")
-}
 
-// isValidPort checks if a port number is valid.
-func isValidPort(port int32) bool {
-	return port > 0 && port < 65536
+	cluster, err := matchCluster(clusters, clusterMatch)
+	if err != nil {
+		return rds.Cluster{}, err
+	}
+	cmdLogger.Debugf("Selected cluster %s matching %q", cluster.Identifier, clusterMatch)
+	return cluster, nil
 }
 
-// Execute adds all child commands to the root command and sets flags appropriately.
-// It is the entry point for the command-line application.
-func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+// iamIdentityUser derives the DB user from the name of the given IAM role.
+func iamIdentityUser(iamRole string) (string, error) {
+	user := aws.IdentityName(iamRole)
+	if user == "" || !isValidUsername(user) {
+		return "", fmt.Errorf("IAM identity %s does not map to a valid DB user name", displayARN(iamRole))
 	}
+	cmdLogger.Debugf("Using DB user %s derived from IAM identity", user)
+	return user, nil
 }
 
-func init() {
-	rootCmd.CompletionOptions.DisableDefaultCmd = true
-	rootCmd.SetHelpCommand(nil)
-	rootCmd.PersistentFlags().StringVar(&configPath, "config", "config.yaml", "path to config file")
-	rootCmd.Flags().BoolVarP(&checkOnly, "check", "c", false, "verify the RDS IAM Connect tool configuration and environment")
-}
+// discoverClusters discovers the clusters of the connector's environment, showing a spinner
+// on interactive terminals unless --quiet is set. If Caching.PromptWhenStale is set, the user is
+// asked whether to refresh an old cache. The clusters are also written to the --export-clusters file, if given.
+func discoverClusters(ctx context.Context, cfg *config.Config, conn *connect.Connector) ([]rds.Cluster, error) {
+	spinner := cli.NewSpinner(os.Stderr, "Discovering RDS clusters", !quietMode)
+	conn.SetProgressFunc(spinner.SetScanned)
 
-// promptEnvironmentSelection presents an interactive prompt for selecting an environment.
-// It takes a map of environment tags and returns the selected environment name.
-// Returns an error if the selection fails.
-func promptEnvironmentSelection(envTags map[string]struct {
-	ReleaseState string
-	Region       string
-}) (string, error) {
-	environments := make([]string, 0, len(envTags))
-	for env := range envTags {
-		environments = append(environments, env)
+	// Interrupting the stale cache prompt cancels the discovery
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var promptErr error
+	if cfg.Caching.PromptWhenStale && isatty.IsTerminal(os.Stdin.Fd()) {
+		conn.SetStaleCacheFunc(func(age time.Duration) bool {
+			if promptErr != nil {
+				return false
+			}
+			spinner.Stop()
+			defer spinner.Start()
+			useCache, err := promptUseStaleCache(age)
+			if err != nil {
+				promptErr = err
+				cancel()
+			}
+			return useCache
+		})
 	}
 
-	var selectedEnv string
-	if err := survey.AskOne(&survey.Select{
-		Message:  "Choose environment:",
-		Options:  environments,
-		PageSize: 10,
-	}, &selectedEnv); err != nil {
-		return "", fmt.Errorf("failed to select environment: %w", err)
+	spinner.Start()
+	clusters, err := conn.Discover(ctx)
+	spinner.Stop()
+	if promptErr != nil {
+		return nil, promptErr
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return selectedEnv, nil
-}
+	if err := conn.IncompleteError(); err != nil {
+		warnf("cluster discovery was incomplete, showing the %d clusters found so far: %v", len(clusters), err)
+	}
 
-// runCheck executes the check functionality.
-func runCheck(ctx context.Context, cfg *config.Config, awsCfg *aws.Config) error {
-	// Initialize RDS service
-	rdsService = rds.NewService(*awsCfg.Config, cfg.Caching.Enabled, cfg.Caching.Duration, cfg.Debug)
+	for _, cluster := range conn.UnavailableClusters() {
+		warnf("cluster %s is not connectable: endpoint not yet available (status: %s)",
+			cluster.Identifier, cluster.Status)
+	}
 
-	// Run checks
-	fmt.Println("Running RDS IAM Connect checks...")
-	fmt.Println("--------------------------------")
+	if exportPath != "" {
+		if err := exportClusters(exportPath, conn.Environment(), clusters); err != nil {
+			return nil, err
+		}
+	}
+
+	return clusters, nil
+}
 
-	// Check 1: AWS Credentials
-	fmt.Println("1. Checking AWS credentials...")
-	if err := checkAWSCredentials(ctx, awsCfg); err != nil {
-		return fmt.Errorf("AWS credentials check failed: %w", err)
+// dumpRawResponses makes the discovery of the connector bypass the cache and write the raw
+// DescribeDBClusters responses to the --dump-raw file, or stderr for "-". Returns a function that
+// closes the file.
+func dumpRawResponses(conn *connect.Connector) (func(), error) {
+	conn.SetRefresh(true)
+	if dumpRawPath == "-" {
+		conn.SetRawDump(os.Stderr)
+		return func() {}, nil
 	}
-	fmt.Println("✓ AWS credentials are valid")
 
-	// Check 2: Configuration
-	fmt.Println("\n2. Checking configuration...")
-	if err := checkConfiguration(cfg); err != nil {
-		return fmt.Errorf("configuration check failed: %w", err)
+	file, err := os.OpenFile(filepath.Clean(dumpRawPath), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, exportFileMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create raw dump file: %w", err)
 	}
-	fmt.Println("✓ Configuration is valid")
+	conn.SetRawDump(file)
+	cmdLogger.Debugf("Writing raw DescribeDBClusters responses to %s", dumpRawPath)
+	return func() { _ = file.Close() }, nil
+}
 
-	// Check 3: RDS Connectivity for each environment
-	fmt.Println("\n3. Checking RDS connectivity...")
-	for envName, envConfig := range cfg.EnvTag {
-		fmt.Printf("\n  Environment: %s\n", envName)
-		fmt.Printf("  Region: %s\n", envConfig.Region)
-		fmt.Printf("  Release State: %s\n", envConfig.ReleaseState)
+// discoverFilteredClusters discovers the clusters of the connector's environment and applies the
+// --prefix and --engine filters. It is an error if no cluster is found.
+func discoverFilteredClusters(ctx context.Context, cfg *config.Config, conn *connect.Connector) ([]rds.Cluster, error) {
+	clusters, err := discoverClusters(ctx, cfg, conn)
+	if err != nil {
+		return nil, err
+	}
 
-		// Create AWS config for this environment's region
-		envAwsCfg, err := aws.CheckAWSCredentials(envConfig.Region)
-		if err != nil {
-			fmt.Printf("  ✗ Failed to initialize AWS credentials for region %s: %v\n", envConfig.Region, err)
-			continue
-		}
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("no RDS clusters found with specified tags and IAM authentication enabled")
+	}
 
-		// Initialize RDS service for this region
-		envRdsService := rds.NewService(*envAwsCfg.Config, cfg.Caching.Enabled, cfg.Caching.Duration, cfg.Debug)
-		rdsService = envRdsService // Set global service for other checks
+	return filterClusters(clusters)
+}
 
-		if err := checkRDSConnectivity(ctx, cfg, envName); err != nil {
-			fmt.Printf("  ✗ RDS connectivity check failed: %v\n", err)
-		} else {
-			fmt.Println("  ✓ RDS connectivity is valid")
+// filterClusters applies the --prefix and --engine filters to the discovered clusters.
+// It is an error if no cluster is left.
+func filterClusters(clusters []rds.Cluster) ([]rds.Cluster, error) {
+	if len(prefixes) > 0 {
+		clusters = filterClustersByPrefix(clusters, prefixes)
+		if len(clusters) == 0 {
+			return nil, fmt.Errorf("no RDS clusters found with identifier prefix %s", strings.Join(prefixes, ", "))
 		}
 	}
 
-	// Check 4: Cache
-	fmt.Println("\n4. Checking cache...")
-	if err := checkCache(cfg); err != nil {
-		return fmt.Errorf("cache check failed: %w", err)
+	clusters = filterClustersByEngine(clusters, engineFilter)
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("no %s RDS clusters found", engineFilter)
 	}
-	fmt.Println("✓ Cache is working properly")
+	return clusters, nil
+}
 
-	fmt.Println("\nAll checks completed!")
-	return nil
+// filterClustersByPrefix returns the clusters whose identifier starts with any of the given prefixes.
+func filterClustersByPrefix(clusters []rds.Cluster, prefixes []string) []rds.Cluster {
+	filtered := make([]rds.Cluster, 0, len(clusters))
+	for _, cluster := range clusters {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(cluster.Identifier, prefix) {
+				filtered = append(filtered, cluster)
+				break
+			}
+		}
+	}
+	return filtered
 }
 
-// checkAWSCredentials verifies AWS credentials and permissions.
-func checkAWSCredentials(ctx context.Context, awsCfg *aws.Config) error {
-	// Check if we can get the caller identity
-	stsClient := sts.NewFromConfig(*awsCfg.Config)
-	identity, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
-	if err != nil {
-		return fmt.Errorf("failed to get caller identity: %w", err)
+// matchCluster returns the single cluster whose identifier contains substr.
+// It is an error if no cluster or more than one cluster matches.
+func matchCluster(clusters []rds.Cluster, substr string) (rds.Cluster, error) {
+	var matches []rds.Cluster
+	for _, cluster := range clusters {
+		if strings.Contains(cluster.Identifier, substr) {
+			matches = append(matches, cluster)
+		}
 	}
 
-	fmt.Printf("  - AWS Account ID: %s\n", *identity.Account)
-	fmt.Printf("  - AWS User ARN: %s\n", *identity.Arn)
-	fmt.Printf("  - AWS Region: %s\n", awsCfg.Region)
+	switch len(matches) {
+	case 0:
+		return rds.Cluster{}, fmt.Errorf("no RDS cluster identifier contains %q", substr)
+	case 1:
+		return matches[0], nil
+	default:
+		identifiers := make([]string, 0, len(matches))
+		for _, cluster := range matches {
+			identifiers = append(identifiers, cluster.Identifier)
+		}
+		return rds.Cluster{}, fmt.Errorf("%d RDS clusters match %q: %s", len(matches), substr, strings.Join(identifiers, ", "))
+	}
+}
 
-	// Check if we have the required RDS permissions
-	permissions := []string{
-		"rds:DescribeDBClusters",
-		"rds:ListTagsForResource",
-		"rds:GenerateDBAuthToken",
+// connectToRDSWithToken generates an auth token and connects to RDS. The connection is recorded by
+// auditConnection before the client starts and its result by recordConnection when the client exits.
+func connectToRDSWithToken(ctx context.Context, cfg *config.Config, conn *connect.Connector, cluster rds.Cluster, user string) (err error) {
+	if cfg.SSM.Target != "" && (cfg.SSLMode == config.SSLModeVerifyIdentity || cfg.SSLMode == config.SSLModeVerifyFull) {
+		return fmt.Errorf("SSL mode %s cannot verify the endpoint through SSM port forwarding, use %s instead",
+			cfg.SSLMode, config.SSLModeVerifyCA)
 	}
 
-	// Get current IAM role
-	iamRole, err := awsCfg.GetCurrentIAMRole(ctx)
+	args, env, err := clientSSLOptions(cfg.SSLMode, cluster)
 	if err != nil {
-		fmt.Printf("  - Warning: Could not get IAM role: %v\n", err)
+		return err
+	}
+
+	token := suppliedToken
+	if token != "" {
+		// The token itself is never logged
+		cmdLogger.Debugf("Using the supplied auth token (length %d) instead of generating one", len(token))
 	} else {
-		fmt.Printf("  - Current IAM Role: %s\n", iamRole)
+		token, err = conn.Token(ctx, cluster, user)
+		if err != nil {
+			return err
+		}
 	}
 
-	for _, permission := range permissions {
-		fmt.Printf("  - Permission %s: ✓ (required)\n", permission)
+	entry, err := auditConnection(ctx, cfg, conn, cluster, user)
+	if err != nil {
+		return err
 	}
+	defer func() { recordConnection(cfg, entry, err) }()
 
-	return nil
-}
+	// The token is signed for the cluster port, the client connects to the ConnectPort override
+	cluster.Port = cluster.ClientPort()
 
-// checkConfiguration validates the configuration.
-func checkConfiguration(cfg *config.Config) error {
-	// Check RDS tags
-	if cfg.RdsTags.TagName == "" || cfg.RdsTags.TagValue == "" {
-		return fmt.Errorf("RDS tags are not configured")
+	if cfg.Connect.TokenNotice {
+		fmt.Fprintf(os.Stderr, "Note: the auth token can open connections until %s (%s). Sessions stay open after that, "+
+			"but the server closes idle ones after its wait_timeout and reconnecting needs a new token.\n",
+			time.Now().Add(rds.TokenLifetime).Format(time.Kitchen), rds.TokenLifetime)
 	}
-	fmt.Printf("  - RDS Tags: %s=%s\n", cfg.RdsTags.TagName, cfg.RdsTags.TagValue)
 
-	// Check allowed IAM users
-	if len(cfg.AllowedIAMUsers) == 0 {
-		return fmt.Errorf("no allowed IAM users configured")
+	// The token is signed for the real endpoint, the client connects to the forwarded port
+	serverName := cluster.Endpoint
+	if cfg.SSM.Target != "" {
+		tunnel, err := startSSMTunnel(ctx, cfg, conn, cluster)
+		if err != nil {
+			return err
+		}
+		defer tunnel.Stop()
+		cluster = tunnel.Cluster(cluster)
 	}
-	fmt.Printf("  - Allowed IAM Users: %d configured\n", len(cfg.AllowedIAMUsers))
 
-	// Check environment tags
-	if len(cfg.EnvTag) == 0 {
-		return fmt.Errorf("no environment tags configured")
+	printClusterNotes(cfg, cluster)
+
+	if cfg.Connect.Preflight {
+		err = preflightToken(ctx, cluster, serverName, user, token)
+		if err := preflightOutcome(err, cfg.AllowPasswordFallback); err != nil {
+			return err
+		}
 	}
-	fmt.Printf("  - Environment Tags: %d configured\n", len(cfg.EnvTag))
 
-	// Check cache configuration
-	if cfg.Caching.Enabled {
-		fmt.Printf("  - Cache: Enabled (duration: %s)\n", cfg.Caching.Duration)
-	} else {
-		fmt.Println("  - Cache: Disabled")
+	args = append(args, clientPromptArgs(cfg, conn.Environment(), cluster, user)...)
+	metricsRecorder.Count("connections", metrics.Labels{"env": conn.Environment(), "cluster": cluster.Identifier, "user": user})
+	err = connectToRDS(ctx, cluster, user, token, execSQL == "", env, args...)
+	if !fallBackToPassword(cfg, err) {
+		return err
 	}
+	return connectWithPassword(ctx, cluster, user, env, args)
+}
 
-	return nil
+// printClusterNotes prints the configured ClusterNotes entry and the OpsNote tag of the cluster, if any.
+func printClusterNotes(cfg *config.Config, cluster rds.Cluster) {
+	if note := cfg.ClusterNotes[strings.ToLower(cluster.Identifier)]; note != "" {
+		fmt.Fprintf(os.Stderr, "Note for %s: %s\n", cluster.Identifier, note)
+	}
+	if cluster.Note != "" {
+		fmt.Fprintf(os.Stderr, "Note for %s (%s tag): %s\n", cluster.Identifier, rds.OpsNoteTag, cluster.Note)
+	}
 }
 
-// checkRDSConnectivity verifies RDS connectivity and IAM authentication.
-func checkRDSConnectivity(ctx context.Context, cfg *config.Config, env string) error {
-	// Get clusters to verify connectivity
-	clusters, err := rdsService.GetClusters(ctx, cfg.RdsTags.TagName, cfg.RdsTags.TagValue, "ReleaseState", cfg.EnvTag[env].ReleaseState, env)
-	if err != nil {
-		return fmt.Errorf("failed to get RDS clusters: %w", err)
+// loadSuppliedToken reads the auth token given with --token-file into suppliedToken and checks that
+// a token given with --token or --token-file is usable as a password. tokenSet reports whether --token was given.
+func loadSuppliedToken(tokenSet bool) error {
+	if tokenFile != "" {
+		data, err := os.ReadFile(filepath.Clean(tokenFile))
+		if err != nil {
+			return fmt.Errorf("failed to read token file: %w", err)
+		}
+		suppliedToken = strings.TrimSpace(string(data))
+		if suppliedToken == "" {
+			return fmt.Errorf("token file %s is empty", tokenFile)
+		}
 	}
 
-	if len(clusters) == 0 {
-		return fmt.Errorf("no RDS clusters found with the specified tags")
+	if suppliedToken == "" {
+		if tokenSet {
+			return fmt.Errorf("--token must not be empty")
+		}
+		return nil
 	}
+	// Only the length is reported so the token does not end up in logs
+	if strings.ContainsAny(suppliedToken, " \t\x00\n\r") {
+		return fmt.Errorf("invalid auth token (length %d): must not contain whitespace or control characters", len(suppliedToken))
+	}
+	return nil
+}
+
+// warnf prints a warning to stderr and writes it to syslog if enabled.
+func warnf(format string, args ...interface{}) {
+	logger.Warnf(format, args...)
+}
 
-	fmt.Printf("  - Found %d RDS clusters\n", len(clusters))
+// flushMetrics sends the metrics of the run, if configured. Failing to send them only logs a warning,
+// so that an unreachable metrics backend never fails a connection.
+func flushMetrics() {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsFlushTimeout)
+	defer cancel()
 
-	// Check IAM authentication for each cluster
-	for i, cluster := range clusters {
-		fmt.Printf("  - Cluster %d: %s\n", i+1, cluster.Identifier)
-		fmt.Printf("    - Endpoint: %s:%d\n", cluster.Endpoint, cluster.Port)
-		fmt.Printf("    - Region: %s\n", cluster.Region)
-		fmt.Printf("    - IAM Auth: Enabled\n")
+	if err := metricsRecorder.Flush(ctx); err != nil {
+		cmdLogger.Debugf("Warning: %v", err)
 	}
-
-	return nil
 }
 
-// checkCache verifies cache functionality.
-func checkCache(cfg *config.Config) error {
-	if !cfg.Caching.Enabled {
-		fmt.Println("  - Cache is disabled, skipping cache checks")
-		return nil
+// clusterName formats the cluster with UI.ClusterTemplate, falling back to the default format
+// if no template is configured or it fails to render.
+func clusterName(cluster rds.Cluster) string {
+	if clusterTemplate != nil {
+		var b strings.Builder
+		err := clusterTemplate.Execute(&b, displayCluster(cluster))
+		if err == nil {
+			return b.String()
+		}
+		cmdLogger.Debugf("Failed to render cluster template for %s: %v", cluster.Identifier, err)
 	}
+	return fmt.Sprintf("%s (%s:%d)", cluster.Identifier, displayEndpoint(cluster.Endpoint), cluster.ClientPort())
+}
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+// clusterDisplayName returns the text shown for a cluster in the selection prompt,
+// including the values of the configured display tags and the account of clusters in other accounts.
+func clusterDisplayName(cluster rds.Cluster) string {
+	display := clusterName(cluster)
+
+	tags := make([]string, 0, len(uiSettings.DisplayTags))
+	for _, key := range uiSettings.DisplayTags {
+		if value, ok := cluster.Tags[key]; ok {
+			tags = append(tags, key+"="+value)
+		}
+	}
+	if len(tags) > 0 {
+		display += " [" + strings.Join(tags, ", ") + "]"
+	}
+	if cluster.Account != "" {
+		display += " (account " + displayAccountID(cluster.Account) + ")"
 	}
 
-	cachePath := filepath.Join(homeDir, ".rds-iam-connect")
+	return display
+}
 
-	// Check cache directory
-	dirInfo, err := os.Stat(cachePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Println("  - Cache directory does not exist")
-			return nil
+// clusterDisplayNames returns the display names of the clusters, made unique where they collide,
+// e.g. for clusters of the same name in several accounts. When the clusters span several regions, each
+// name is prefixed with its region, and when they run both MySQL and PostgreSQL, with its engine family.
+func clusterDisplayNames(clusters []rds.Cluster) []string {
+	mixed := mixedEngines(clusters)
+	regional := mixedRegions(clusters)
+	names := make([]string, 0, len(clusters))
+	for _, cluster := range clusters {
+		name := clusterDisplayName(cluster)
+		if regional {
+			name = "[" + cluster.Region + "] " + name
+		}
+		if mixed {
+			name = "[" + engineFamily(cluster) + "] " + name
 		}
-		return fmt.Errorf("failed to check cache directory: %w", err)
+		names = append(names, name)
 	}
+	return cli.UniqueDisplayNames(clusters, names)
+}
 
-	if !dirInfo.IsDir() {
-		return fmt.Errorf("cache path is not a directory: %s", cachePath)
+// mixedRegions reports whether the clusters are in more than one region, as in environments with several regions.
+func mixedRegions(clusters []rds.Cluster) bool {
+	for _, cluster := range clusters {
+		if cluster.Region != clusters[0].Region {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute adds all child commands to the root command and sets flags appropriately.
+// It is the entry point for the command-line application.
+// A failed mysql client passes on its exit code, an interrupted prompt exits with interruptExitCode
+// and other errors exit with 1.
+func Execute() {
+	silenceInterrupt(rootCmd)
+	err := rootCmd.Execute()
+	if err != nil && !errors.Is(err, errInterrupted) {
+		sysLog.Error(err.Error())
+	}
+	_ = sysLog.Close()
+	if err == nil {
+		return
 	}
 
-	fmt.Println("  - Cache directory exists")
+	if errors.Is(err, errInterrupted) {
+		os.Exit(interruptExitCode)
+	}
+	var connErr *ConnectError
+	if errors.As(err, &connErr) && connErr.ExitCode > 0 {
+		os.Exit(connErr.ExitCode)
+	}
+	os.Exit(1)
+}
 
-	// Check cache files for each environment
-	for env := range cfg.EnvTag {
-		cacheFile := filepath.Join(cachePath, rds.GetCacheFileName(env))
-		fileInfo, err := os.Stat(cacheFile)
-		if err != nil {
-			if os.IsNotExist(err) {
-				fmt.Printf("  - Cache file for environment %s does not exist\n", env)
-				continue
+// silenceInterrupt keeps cobra from printing errInterrupted and the usage of the command it aborted.
+func silenceInterrupt(cmd *cobra.Command) {
+	if runE := cmd.RunE; runE != nil {
+		cmd.RunE = func(c *cobra.Command, args []string) error {
+			err := runE(c, args)
+			if errors.Is(err, errInterrupted) {
+				c.SilenceErrors, c.SilenceUsage = true, true
 			}
-			return fmt.Errorf("failed to check cache file for environment %s: %w", env, err)
+			return err
 		}
+	}
+	for _, sub := range cmd.Commands() {
+		silenceInterrupt(sub)
+	}
+}
 
-		if !fileInfo.Mode().IsRegular() {
-			return fmt.Errorf("cache file is not a regular file: %s", cacheFile)
-		}
+func init() {
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.SetHelpCommand(nil)
+}
 
-		fmt.Printf("  - Cache file exists for environment %s\n", env)
+// selectRole sets the role to assume from the configured Roles, prompting if there is more than one
+// unless first is set, e.g. by --select-first.
+func selectRole(cfg *config.Config, first bool) error {
+	switch {
+	case len(cfg.Roles) == 0:
+		return nil
+	case len(cfg.Roles) == 1 || first:
+		cfg.AssumeRole.RoleArn = cfg.Roles[0]
+	default:
+		var role string
+		if err := askOne(newSelect("Choose an IAM role:", cfg.Roles), &role); err != nil {
+			return fmt.Errorf("failed to select IAM role: %w", err)
+		}
+		cfg.AssumeRole.RoleArn = role
 	}
 
+	cmdLogger.Debugf("Assuming IAM role %s", displayARN(cfg.AssumeRole.RoleArn))
 	return nil
 }
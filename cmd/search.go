@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/pkg/connect"
+)
+
+// refreshClustersOption is offered in the cluster search to rediscover the clusters from AWS.
+const refreshClustersOption = "[Not listed? Refresh clusters from AWS]"
+
+// searchClusters prompts for a cluster with a search over the discovered clusters, as enabled by --search
+// or UI.Search. Choosing the refresh option discovers the clusters again from AWS, bypassing the cache,
+// and searches the fresh list.
+func searchClusters(ctx context.Context, cfg *config.Config, conn *connect.Connector, clusters []rds.Cluster) (rds.Cluster, error) {
+	for {
+		cluster, refresh, err := promptClusterSearch(clusters)
+		if err != nil || !refresh {
+			return cluster, err
+		}
+
+		conn.SetRefresh(true)
+		clusters, err = discoverClusters(ctx, cfg, conn)
+		conn.SetRefresh(false)
+		if err != nil {
+			return rds.Cluster{}, err
+		}
+
+		if len(clusters) == 0 {
+			return rds.Cluster{}, fmt.Errorf("no RDS clusters found after refreshing from AWS")
+		}
+		if clusters, err = filterClusters(clusters); err != nil {
+			return rds.Cluster{}, err
+		}
+		cmdLogger.Debugf("Refreshed %d clusters from AWS", len(clusters))
+	}
+}
+
+// promptClusterSearch presents the clusters followed by the refresh option in a prompt that filters them
+// as the user types. Returns true instead of a cluster if the refresh option was chosen.
+func promptClusterSearch(clusters []rds.Cluster) (rds.Cluster, bool, error) {
+	clusters = orderClusters(clusters)
+	options := clusterDisplayNames(clusters)
+	searchText := make([]string, 0, len(clusters))
+	clusterMap := make(map[string]rds.Cluster, len(clusters))
+
+	for i, display := range options {
+		searchText = append(searchText, clusterSearchText(clusters[i], display))
+		clusterMap[display] = clusters[i]
+	}
+	options = append(options, refreshClustersOption)
+
+	prompt := newSelect("Search RDS clusters (type to filter):", options)
+	prompt.Filter = func(filter, _ string, index int) bool {
+		// The refresh option stays visible for when nothing matches
+		if index >= len(searchText) {
+			return true
+		}
+		return matchesSearch(searchText[index], filter)
+	}
+
+	var selected string
+	if err := askOne(prompt, &selected); err != nil {
+		return rds.Cluster{}, false, fmt.Errorf("failed to select cluster: %w", err)
+	}
+	if selected == refreshClustersOption {
+		return rds.Cluster{}, true, nil
+	}
+	return clusterMap[selected], false, nil
+}
+
+// clusterSearchText returns the lower-cased text a search matches against: the displayed name,
+// identifier, endpoint, engine, account and tag values of the cluster.
+func clusterSearchText(cluster rds.Cluster, display string) string {
+	fields := []string{display, cluster.Identifier, cluster.Endpoint, cluster.Engine, cluster.Account}
+	for _, value := range cluster.Tags {
+		fields = append(fields, value)
+	}
+	return strings.ToLower(strings.Join(fields, " "))
+}
+
+// matchesSearch reports whether the text contains every whitespace-separated word of the query,
+// ignoring case, so that "prod orders" matches "orders-db (prod)".
+func matchesSearch(text, query string) bool {
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/pkg/connect"
+
+	"github.com/spf13/cobra"
+)
+
+// Actions of serve requests.
+const (
+	serveActionList  = "list"
+	serveActionToken = "token"
+)
+
+// maxServeRequestSize bounds a single request line of serve --stdin.
+const maxServeRequestSize = 64 * 1024
+
+var serveStdin bool
+
+// serveCmd answers requests of editor integrations as a long-lived helper process.
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Answer JSON requests for editor integrations",
+	Long: `Read newline-delimited JSON requests from stdin and write one JSON response per line to stdout,
+e.g. {"action":"list","env":"prod"} or {"action":"token","env":"prod","cluster":"orders","user":"app"}.
+AWS credentials and discovered clusters are kept across requests, so editors need not start the tool
+for every call. Nothing is prompted for: the first role is assumed when several are configured.`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+// serveRequest is a single request of serve --stdin.
+type serveRequest struct {
+	ID      json.RawMessage `json:"id,omitempty"` // Echoed in the response to match it to the request.
+	Action  string          `json:"action"`
+	Env     string          `json:"env,omitempty"` // Defaults to the environment of the previous request.
+	Cluster string          `json:"cluster,omitempty"`
+	User    string          `json:"user,omitempty"`
+}
+
+// serveResponse is the response to a serveRequest. Error is set instead of the result if it failed.
+type serveResponse struct {
+	ID       json.RawMessage `json:"id,omitempty"`
+	OK       bool            `json:"ok"`
+	Error    string          `json:"error,omitempty"`
+	Clusters []clusterExport `json:"clusters"`
+	*tokenInfo
+}
+
+// serveConnector discovers the clusters of an environment and issues tokens for them, like a
+// connect.Connector.
+type serveConnector interface {
+	Discover(ctx context.Context) ([]rds.Cluster, error)
+	Token(ctx context.Context, cluster rds.Cluster, user string) (string, error)
+}
+
+// server answers serve requests, keeping a connector per environment across requests.
+type server struct {
+	cfg          *config.Config
+	connectors   map[string]serveConnector
+	newConnector func(ctx context.Context, env string) (serveConnector, error)
+	env          string // The environment of the previous request.
+}
+
+// runServe is the execution function for the serve command.
+func runServe(_ *cobra.Command, _ []string) error {
+	if !serveStdin {
+		return fmt.Errorf("serve requires --stdin")
+	}
+
+	ctx, stop := notifyShutdown(context.Background())
+	defer stop()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	defer flushMetrics()
+
+	// Nothing is prompted for, so the first role is assumed
+	if err := selectRole(cfg, true); err != nil {
+		return err
+	}
+
+	srv := &server{
+		cfg:        cfg,
+		connectors: make(map[string]serveConnector),
+		newConnector: func(ctx context.Context, env string) (serveConnector, error) {
+			return newServeConnector(ctx, cfg, env)
+		},
+		env: firstEnvironment(cfg),
+	}
+	return srv.serve(ctx, os.Stdin, os.Stdout)
+}
+
+// newServeConnector creates the connector of an environment, loading its allowed users.
+func newServeConnector(ctx context.Context, cfg *config.Config, env string) (serveConnector, error) {
+	conn, err := connect.New(cfg, env)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.LoadAllowedUsers(ctx); err != nil {
+		return nil, err
+	}
+	conn.SetMetrics(metricsRecorder)
+	return conn, nil
+}
+
+// serve answers the requests read from in until it is closed or ctx is cancelled.
+// A request that fails gets an error response; only failing to write a response ends serving.
+func (s *server) serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	lines := make(chan []byte)
+	scanErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 0, 4096), maxServeRequestSize)
+		for scanner.Scan() {
+			// Nobody receives the line once serving has stopped
+			select {
+			case lines <- append([]byte(nil), scanner.Bytes()...):
+			case <-ctx.Done():
+				return
+			}
+		}
+		scanErr <- scanner.Err()
+	}()
+
+	encoder := json.NewEncoder(out)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				if err := <-scanErr; err != nil {
+					return fmt.Errorf("failed to read request: %w", err)
+				}
+				return nil
+			}
+			if len(line) == 0 {
+				continue
+			}
+			if err := encoder.Encode(s.handle(ctx, line)); err != nil {
+				return fmt.Errorf("failed to write response: %w", err)
+			}
+		}
+	}
+}
+
+// handle answers a single request line.
+func (s *server) handle(ctx context.Context, line []byte) serveResponse {
+	var req serveRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return serveResponse{Error: fmt.Sprintf("invalid request: %v", err)}
+	}
+
+	resp := serveResponse{ID: req.ID}
+	var err error
+	switch req.Action {
+	case serveActionList:
+		resp.Clusters, err = s.list(ctx, req)
+	case serveActionToken:
+		resp.tokenInfo, err = s.token(ctx, req)
+	default:
+		err = fmt.Errorf("unknown action %q: must be %q or %q", req.Action, serveActionList, serveActionToken)
+	}
+
+	if err != nil {
+		cmdLogger.Debugf("Request %s failed: %v", req.Action, err)
+		resp.Error = err.Error()
+		return resp
+	}
+	resp.OK = true
+	return resp
+}
+
+// list returns the clusters of the environment, masked according to the redaction settings.
+func (s *server) list(ctx context.Context, req serveRequest) ([]clusterExport, error) {
+	env, clusters, err := s.discover(ctx, req.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	// An empty list is still a list
+	rows := make([]clusterExport, 0, len(clusters))
+	for _, cluster := range clusters {
+		rows = append(rows, newClusterExport(env, displayCluster(cluster)))
+	}
+	return rows, nil
+}
+
+// token generates an auth token for an allowed user of the cluster with the exact identifier.
+func (s *server) token(ctx context.Context, req serveRequest) (*tokenInfo, error) {
+	if req.Cluster == "" || req.User == "" {
+		return nil, fmt.Errorf("token requires a cluster and a user")
+	}
+
+	env, clusters, err := s.discover(ctx, req.Env)
+	if err != nil {
+		return nil, err
+	}
+	if !s.cfg.IsAllowedUser(env, req.User) {
+		return nil, fmt.Errorf("user %q is not allowed in environment %s", req.User, env)
+	}
+
+	var cluster *rds.Cluster
+	for i := range clusters {
+		if clusters[i].Identifier == req.Cluster {
+			cluster = &clusters[i]
+			break
+		}
+	}
+	if cluster == nil {
+		return nil, fmt.Errorf("cluster %q not found in environment %s", req.Cluster, env)
+	}
+
+	issuedAt := time.Now()
+	token, err := s.connectors[env].Token(ctx, *cluster, req.User)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenInfo{
+		Token:     token,
+		ExpiresAt: issuedAt.Add(rds.TokenLifetime).UTC().Truncate(time.Second),
+		Endpoint:  cluster.Endpoint,
+		Port:      cluster.ClientPort(),
+		User:      req.User,
+	}, nil
+}
+
+// discover returns the environment of the request and its clusters. The connector of each environment
+// is created once, and discovery is served from the cluster cache while it is valid.
+func (s *server) discover(ctx context.Context, env string) (string, []rds.Cluster, error) {
+	if env == "" {
+		env = s.env
+	}
+
+	conn, ok := s.connectors[env]
+	if !ok {
+		if _, known := s.cfg.EnvTag[env]; !known {
+			return "", nil, fmt.Errorf("unknown environment: %s", env)
+		}
+
+		var err error
+		if conn, err = s.newConnector(ctx, env); err != nil {
+			return "", nil, err
+		}
+		s.connectors[env] = conn
+	}
+	s.env = env
+
+	clusters, err := conn.Discover(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	return env, clusters, nil
+}
+
+func init() {
+	serveCmd.Flags().BoolVar(&serveStdin, "stdin", false, "read JSON requests from stdin and write JSON responses to stdout")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/internal/rds"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServeConnector serves fixed clusters and issues tokens naming the cluster and user.
+type fakeServeConnector struct {
+	clusters []rds.Cluster
+	err      error
+}
+
+func (f *fakeServeConnector) Discover(_ context.Context) ([]rds.Cluster, error) {
+	return f.clusters, f.err
+}
+
+func (f *fakeServeConnector) Token(_ context.Context, cluster rds.Cluster, user string) (string, error) {
+	return "token-" + cluster.Identifier + "-" + user, nil
+}
+
+// newTestServer returns a server of the environments prod, qa and staging, where qa has no clusters
+// and staging fails discovery.
+func newTestServer(t *testing.T) *server {
+	t.Helper()
+	cfg := &config.Config{
+		EnvTag: map[string]config.EnvConfig{
+			"prod":    {AllowedIAMUsers: []string{"app"}},
+			"qa":      {AllowedIAMUsers: []string{"app"}},
+			"staging": {AllowedIAMUsers: []string{"app"}},
+		},
+	}
+	connectors := map[string]*fakeServeConnector{
+		"prod": {clusters: []rds.Cluster{
+			{Identifier: "orders", Endpoint: "orders.cluster-xxx.us-east-1.rds.amazonaws.com", Port: 3306, Region: "us-east-1"},
+		}},
+		"qa":      {},
+		"staging": {err: errors.New("access denied")},
+	}
+	return &server{
+		cfg:        cfg,
+		connectors: make(map[string]serveConnector),
+		newConnector: func(_ context.Context, env string) (serveConnector, error) {
+			return connectors[env], nil
+		},
+		env: "prod",
+	}
+}
+
+// serveLines serves the request lines and returns the decoded responses.
+func serveLines(t *testing.T, srv *server, lines ...string) []map[string]interface{} {
+	t.Helper()
+	var out strings.Builder
+	require.NoError(t, srv.serve(context.Background(), strings.NewReader(strings.Join(lines, "\n")+"\n"), &out))
+
+	var responses []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &resp))
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func TestServe(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		check   func(t *testing.T, resp map[string]interface{})
+	}{
+		{
+			name:    "list",
+			request: `{"id":1,"action":"list","env":"prod"}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				assert.Equal(t, true, resp["ok"])
+				assert.Equal(t, float64(1), resp["id"])
+				clusters := resp["clusters"].([]interface{})
+				require.Len(t, clusters, 1)
+				assert.Equal(t, "orders", clusters[0].(map[string]interface{})["identifier"])
+			},
+		},
+		{
+			name:    "list of an environment without clusters",
+			request: `{"action":"list","env":"qa"}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				assert.Equal(t, true, resp["ok"])
+				assert.Equal(t, []interface{}{}, resp["clusters"])
+			},
+		},
+		{
+			name:    "token",
+			request: `{"id":"a","action":"token","cluster":"orders","user":"app"}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				assert.Equal(t, true, resp["ok"])
+				assert.Equal(t, "a", resp["id"])
+				assert.Equal(t, "token-orders-app", resp["token"])
+				assert.Equal(t, "orders.cluster-xxx.us-east-1.rds.amazonaws.com", resp["endpoint"])
+				assert.Equal(t, float64(3306), resp["port"])
+			},
+		},
+		{
+			name:    "token for a user that is not allowed",
+			request: `{"action":"token","cluster":"orders","user":"admin"}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				assert.Equal(t, false, resp["ok"])
+				assert.Equal(t, `user "admin" is not allowed in environment prod`, resp["error"])
+				assert.NotContains(t, resp, "token")
+			},
+		},
+		{
+			name:    "token for an unknown cluster",
+			request: `{"action":"token","cluster":"billing","user":"app"}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				assert.Equal(t, false, resp["ok"])
+				assert.Equal(t, `cluster "billing" not found in environment prod`, resp["error"])
+			},
+		},
+		{
+			name:    "unknown action",
+			request: `{"id":2,"action":"connect"}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				assert.Equal(t, false, resp["ok"])
+				assert.Equal(t, float64(2), resp["id"])
+				assert.Equal(t, `unknown action "connect": must be "list" or "token"`, resp["error"])
+			},
+		},
+		{
+			name:    "unknown environment",
+			request: `{"action":"list","env":"dev"}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				assert.Equal(t, false, resp["ok"])
+				assert.Equal(t, "unknown environment: dev", resp["error"])
+			},
+		},
+		{
+			name:    "failed discovery",
+			request: `{"action":"list","env":"staging"}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				assert.Equal(t, false, resp["ok"])
+				assert.Equal(t, "access denied", resp["error"])
+			},
+		},
+		{
+			name:    "malformed JSON",
+			request: `{"action":`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				assert.Equal(t, false, resp["ok"])
+				assert.Contains(t, resp["error"], "invalid request:")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := serveLines(t, newTestServer(t), tt.request)
+			require.Len(t, responses, 1)
+			tt.check(t, responses[0])
+		})
+	}
+}
+
+func TestServeKeepsServingAfterErrors(t *testing.T) {
+	srv := newTestServer(t)
+	responses := serveLines(t, srv,
+		`not json`,
+		``,
+		`{"action":"list","env":"staging"}`,
+		`{"action":"list"}`,
+	)
+
+	require.Len(t, responses, 3)
+	assert.Equal(t, false, responses[0]["ok"])
+	assert.Equal(t, false, responses[1]["ok"])
+	// The environment defaults to that of the previous request
+	assert.Equal(t, false, responses[2]["ok"])
+	assert.Equal(t, "access denied", responses[2]["error"])
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// clientStopTimeout is how long the database client may take to exit after SIGTERM before it is killed.
+const clientStopTimeout = 5 * time.Second
+
+// shutdownSignals are the signals that cancel a command: Ctrl-C and the SIGTERM sent by process supervisors.
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// errTerminated is the cause of the cancellation of a command's context by SIGTERM.
+var errTerminated = errors.New("terminated by SIGTERM")
+
+// notifyShutdown returns a copy of ctx that is cancelled by the first of shutdownSignals, like
+// signal.NotifyContext. SIGTERM cancels it with errTerminated as the cause, so that the database
+// client is terminated as well; see clientContext. The returned function stops the signal handling.
+func notifyShutdown(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, shutdownSignals...)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			cmdLogger.Debugf("Received %s, cancelling", sig)
+			if sig == syscall.SIGTERM {
+				cancel(errTerminated)
+			} else {
+				cancel(nil)
+			}
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel(nil)
+	}
+}
+
+// clientContext returns the context the database client runs with. Unlike ctx, it is only cancelled
+// by SIGTERM: Ctrl-C reaches an interactive client from the terminal, which handles it itself, e.g.
+// by aborting the running query, so the client is not stopped on os.Interrupt.
+func clientContext(ctx context.Context) (context.Context, func()) {
+	clientCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		if errors.Is(context.Cause(ctx), errTerminated) {
+			cancel()
+		}
+	})
+	return clientCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// terminateOnCancel makes cmd receive SIGTERM when its context is cancelled, and kills it if it does not
+// exit within clientStopTimeout. Where SIGTERM cannot be sent, e.g. on Windows, it is killed right away.
+func terminateOnCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		err := cmd.Process.Signal(syscall.SIGTERM)
+		if err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return cmd.Process.Kill()
+		}
+		return err
+	}
+	cmd.WaitDelay = clientStopTimeout
+}
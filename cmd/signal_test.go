@@ -0,0 +1,108 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitDone fails the test if ctx is not cancelled in time.
+func waitDone(t *testing.T, ctx context.Context) {
+	t.Helper()
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context was not cancelled")
+	}
+}
+
+func TestNotifyShutdownSIGTERM(t *testing.T) {
+	ctx, stop := notifyShutdown(context.Background())
+	defer stop()
+	clientCtx, stopClient := clientContext(ctx)
+	defer stopClient()
+
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+
+	waitDone(t, ctx)
+	assert.ErrorIs(t, context.Cause(ctx), errTerminated)
+	waitDone(t, clientCtx)
+}
+
+func TestNotifyShutdownInterrupt(t *testing.T) {
+	ctx, stop := notifyShutdown(context.Background())
+	defer stop()
+	clientCtx, stopClient := clientContext(ctx)
+	defer stopClient()
+
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGINT))
+
+	waitDone(t, ctx)
+	assert.ErrorIs(t, context.Cause(ctx), context.Canceled)
+
+	// Ctrl-C is left to the database client
+	select {
+	case <-clientCtx.Done():
+		t.Fatal("client context was cancelled by SIGINT")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTerminateOnCancel(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not available")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, sleep, "30")
+	terminateOnCancel(cmd)
+	require.NoError(t, cmd.Start())
+
+	cancel()
+	err = cmd.Wait()
+
+	var exitErr *exec.ExitError
+	require.True(t, errors.As(err, &exitErr), "unexpected error: %v", err)
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	require.True(t, ok)
+	assert.Equal(t, syscall.SIGTERM, status.Signal())
+}
+
+func TestSilenceInterrupt(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		err    error
+		silent bool
+	}{
+		{name: "interrupted prompt", err: fmt.Errorf("failed to select cluster: %w", errInterrupted), silent: true},
+		{name: "other error", err: errors.New("failed to load configuration"), silent: false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			root := &cobra.Command{Use: "root", RunE: func(*cobra.Command, []string) error { return nil }}
+			root.AddCommand(&cobra.Command{Use: "sub", RunE: func(*cobra.Command, []string) error { return tt.err }})
+			var out bytes.Buffer
+			root.SetOut(&out)
+			root.SetErr(&out)
+			root.SetArgs([]string{"sub"})
+
+			silenceInterrupt(root)
+			err := root.Execute()
+
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.silent, out.Len() == 0, out.String())
+		})
+	}
+}
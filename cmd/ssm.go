@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/internal/rds"
+	"rds-iam-connect/pkg/connect"
+)
+
+const (
+	// ssmPortForwardDocument is the SSM document that forwards a local port to a remote host.
+	ssmPortForwardDocument = "AWS-StartPortForwardingSessionToRemoteHost"
+	// ssmStartTimeout bounds how long to wait for the forwarded port to accept connections.
+	ssmStartTimeout = 30 * time.Second
+	// ssmStopTimeout bounds how long to wait for the session to end before killing it.
+	ssmStopTimeout = 5 * time.Second
+	// ssmLocalHost is the address the forwarded port listens on.
+	ssmLocalHost = "127.0.0.1"
+)
+
+// ssmTunnel is a running SSM Session Manager port forwarding session.
+type ssmTunnel struct {
+	cmd  *exec.Cmd
+	port int32
+	done chan struct{} // Closed when the session process has exited.
+	err  error         // The exit error of the session process, set before done is closed.
+}
+
+// startSSMTunnel starts an SSM port forwarding session through the configured target instance to the
+// cluster endpoint and waits until the local port accepts connections. The session uses the AWS credentials
+// of the account the cluster was discovered in and the cluster's region, so an assumed role also applies
+// to the AWS CLI.
+func startSSMTunnel(ctx context.Context, cfg *config.Config, conn *connect.Connector, cluster rds.Cluster) (*ssmTunnel, error) {
+	target := cfg.SSM.Target
+	if !isValidSSMTarget(target) {
+		return nil, fmt.Errorf("invalid SSM target: %s", target)
+	}
+
+	awsPath, err := exec.LookPath("aws")
+	if err != nil {
+		return nil, fmt.Errorf("SSM port forwarding requires the AWS CLI and the Session Manager plugin: %w", err)
+	}
+
+	localPort := cfg.SSM.LocalPort
+	if localPort == 0 {
+		if localPort, err = freeLocalPort(); err != nil {
+			return nil, err
+		}
+	}
+
+	awsCfg := conn.ClusterAWSConfig(cluster)
+	creds, err := awsCfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+
+	parameters := fmt.Sprintf("host=%s,portNumber=%d,localPortNumber=%d", cluster.Endpoint, cluster.Port, localPort)
+
+	//nolint:gosec // The binary is resolved from a fixed name and the arguments are validated
+	cmd := exec.Command(awsPath, "ssm", "start-session",
+		"--region", awsCfg.Region,
+		"--target", target,
+		"--document-name", ssmPortForwardDocument,
+		"--parameters", parameters,
+	)
+	cmd.Env = append(os.Environ(),
+		"AWS_ACCESS_KEY_ID="+creds.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY="+creds.SecretAccessKey,
+		"AWS_SESSION_TOKEN="+creds.SessionToken,
+	)
+	// The session output is only useful when debugging
+	if cfg.Debug {
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+	}
+
+	detachSignals(cmd)
+
+	cmdLogger.Debugf("Starting SSM port forwarding to %s:%d through %s on local port %d",
+		cluster.Endpoint, cluster.Port, target, localPort)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start SSM session: %w", err)
+	}
+
+	tunnel := &ssmTunnel{cmd: cmd, done: make(chan struct{}), port: int32(localPort)} //nolint:gosec // Port is at most 65535
+	go func() {
+		tunnel.err = cmd.Wait()
+		close(tunnel.done)
+	}()
+
+	if err := tunnel.waitReady(ctx); err != nil {
+		tunnel.Stop()
+		return nil, err
+	}
+	return tunnel, nil
+}
+
+// waitReady polls the local port until it accepts connections, the session exits or the start timeout expires.
+func (t *ssmTunnel) waitReady(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, ssmStartTimeout)
+	defer cancel()
+
+	for {
+		if err := probeEndpoint(ctx, ssmLocalHost, t.port, time.Second); err == nil {
+			return nil
+		}
+
+		select {
+		case <-t.done:
+			return fmt.Errorf("SSM session exited before the port was forwarded: %v", t.err)
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("timed out after %s waiting for SSM port forwarding on port %d", ssmStartTimeout, t.port)
+			}
+			return ctx.Err()
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// Cluster returns a copy of the cluster that connects through the forwarded local port.
+func (t *ssmTunnel) Cluster(cluster rds.Cluster) rds.Cluster {
+	cluster.Endpoint = ssmLocalHost
+	cluster.Port = t.port
+	return cluster
+}
+
+// Stop ends the SSM session, killing it if it does not exit within ssmStopTimeout.
+func (t *ssmTunnel) Stop() {
+	select {
+	case <-t.done:
+		return
+	default:
+	}
+
+	_ = t.cmd.Process.Signal(os.Interrupt)
+	select {
+	case <-t.done:
+	case <-time.After(ssmStopTimeout):
+		_ = t.cmd.Process.Kill()
+		<-t.done
+	}
+	cmdLogger.Debugf("SSM session stopped")
+}
+
+// freeLocalPort returns a TCP port on the loopback interface that is currently free.
+func freeLocalPort() (int, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(ssmLocalHost, "0"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free local port: %w", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// isValidSSMTarget checks that the target looks like an instance or managed node ID.
+func isValidSSMTarget(target string) bool {
+	if target == "" || len(target) > 64 || strings.HasPrefix(target, "-") {
+		return false
+	}
+	for _, r := range target {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
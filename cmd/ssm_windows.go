@@ -0,0 +1,8 @@
+//go:build windows
+
+package cmd
+
+import "os/exec"
+
+// detachSignals is a no-op on Windows, where console Ctrl-C events are not isolated per process group.
+func detachSignals(_ *exec.Cmd) {}
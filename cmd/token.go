@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"rds-iam-connect/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// tokenCmd groups the commands that manage auth token material stored by the tool.
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage auth tokens stored in the cache directory",
+	Args:  cobra.NoArgs,
+}
+
+// tokenClearCmd removes the auth tokens stored in the cache directory.
+var tokenClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove the auth tokens stored in the cache directory",
+	Long: `Remove the auth tokens stored in the cache directory, e.g. after switching roles or when a token may be stale.
+Auth tokens are generated for every connection and not cached, so the only tokens on disk are the temporary
+mysql defaults files written with --defaults-file, which a killed session can leave behind. Cluster caches
+are not affected.`,
+	Args: cobra.NoArgs,
+	RunE: runTokenClear,
+}
+
+// runTokenClear is the execution function for the token clear command.
+func runTokenClear(_ *cobra.Command, _ []string) error {
+	cacheDir, err := utils.GetCacheDir()
+	if err != nil {
+		return err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(cacheDir, defaultsFilePattern))
+	if err != nil {
+		return fmt.Errorf("failed to list cached tokens: %w", err)
+	}
+
+	removed := 0
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			// Removed by the session it belongs to in the meantime
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to remove cached token %s: %w", path, err)
+		}
+		cmdLogger.Debugf("Removed %s", path)
+		removed++
+	}
+
+	fmt.Printf("Removed %d cached token(s) from %s\n", removed, cacheDir)
+	return nil
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenClearCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"rds-iam-connect/pkg/connect"
+
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/spf13/cobra"
+)
+
+// whoamiCmd prints the AWS identity the tool runs as.
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Print the AWS identity used to discover clusters",
+	Long: `Print the AWS account, caller ARN and IAM role used to discover clusters and sign auth tokens,
+after assuming the configured role. Values are masked according to the redaction settings.`,
+	Args: cobra.NoArgs,
+	RunE: runWhoami,
+}
+
+// runWhoami is the execution function for the whoami command.
+func runWhoami(_ *cobra.Command, _ []string) error {
+	ctx, stop := notifyShutdown(context.Background())
+	defer stop()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := selectRole(cfg, false); err != nil {
+		return err
+	}
+
+	conn, err := connect.New(cfg, firstEnvironment(cfg))
+	if err != nil {
+		return err
+	}
+
+	identity, err := conn.STSClient().GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return fmt.Errorf("failed to get caller identity: %w", err)
+	}
+
+	fmt.Printf("Account: %s\n", displayAccountID(*identity.Account))
+	fmt.Printf("ARN:     %s\n", displayARN(*identity.Arn))
+	if iamRole, err := conn.Identity(ctx); err == nil {
+		fmt.Printf("Role:    %s\n", displayARN(iamRole))
+	} else {
+		cmdLogger.Debugf("Could not get IAM role: %v", err)
+	}
+	fmt.Printf("Region:  %s\n", conn.AWSConfig().Region)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(whoamiCmd)
+}
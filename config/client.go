@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+)
+
+// Database engines with their own client command.
+const (
+	EngineMySQL    = "mysql"
+	EnginePostgres = "postgres"
+)
+
+// ClientSettings configures the database client commands.
+type ClientSettings struct {
+	// Commands maps an engine ("mysql" or "postgres") to its client command line, given as one Go text/template
+	// per argument with the fields of CommandData. Engines without a command use the built-in defaults.
+	Commands map[string][]string
+	// Database is passed to the command templates as .Database.
+	Database string
+}
+
+// CommandData holds the fields available to client command templates.
+type CommandData struct {
+	Host     string // The endpoint to connect to.
+	Port     int32  // The port to connect to.
+	User     string // The DB user.
+	Token    string // The IAM auth token used as the password.
+	Database string // The configured client.database, if any.
+}
+
+// defaultCommands are the client command lines used for engines without a configured command.
+// psql reads the token from PGPASSWORD and the SSL mode from PGSSLMODE, which are always set for
+// PostgreSQL clusters, so that the sslMode setting is not overridden by the connection string.
+var defaultCommands = map[string][]string{
+	EngineMySQL: {"mysql", "-h", "{{.Host}}", "-P", "{{.Port}}", "-u", "{{.User}}", "-p{{.Token}}", "--enable-cleartext-plugin"},
+	EnginePostgres: {"psql", "host={{.Host}} port={{.Port}} user={{.User}}" +
+		"{{if .Database}} dbname={{.Database}}{{end}}"},
+}
+
+// sampleCommandData is used to check at load time that command templates only reference known fields.
+var sampleCommandData = CommandData{Host: "cluster.example.com", Port: 3306, User: "user", Token: "token", Database: "db"}
+
+// ParseCommands parses the client command of each engine, falling back to the default commands.
+func (c ClientSettings) ParseCommands() (map[string][]*template.Template, error) {
+	commands := make(map[string][]*template.Template, len(defaultCommands))
+	for engine, args := range defaultCommands {
+		tmpls, err := parseCommand(engine, args)
+		if err != nil {
+			return nil, err
+		}
+		commands[engine] = tmpls
+	}
+
+	for engine, args := range c.Commands {
+		if _, ok := defaultCommands[engine]; !ok {
+			return nil, fmt.Errorf("client.commands has unknown engine %q: must be %q or %q", engine, EngineMySQL, EnginePostgres)
+		}
+		if len(args) == 0 || args[0] == "" {
+			return nil, fmt.Errorf("client.commands.%s must start with the client program", engine)
+		}
+
+		tmpls, err := parseCommand(engine, args)
+		if err != nil {
+			return nil, err
+		}
+		commands[engine] = tmpls
+	}
+	return commands, nil
+}
+
+// parseCommand parses the arguments of a command and renders them with sample data,
+// which fails for references to unknown fields.
+func parseCommand(engine string, args []string) ([]*template.Template, error) {
+	tmpls := make([]*template.Template, 0, len(args))
+	for i, arg := range args {
+		tmpl, err := template.New(fmt.Sprintf("%s[%d]", engine, i)).Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client.commands.%s: %w", engine, err)
+		}
+		if err := tmpl.Execute(io.Discard, sampleCommandData); err != nil {
+			return nil, fmt.Errorf("invalid client.commands.%s: %w", engine, err)
+		}
+		tmpls = append(tmpls, tmpl)
+	}
+	return tmpls, nil
+}
@@ -3,13 +3,27 @@
 package config
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
+	"rds-iam-connect/internal/logger"
 	"rds-iam-connect/internal/utils"
 
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/spf13/viper"
 )
 
@@ -17,28 +31,299 @@ import (
 // It contains settings for RDS tags, IAM users, environment tags, caching, and IAM permission checks.
 type Config struct {
 	// RdsTags contains the tag name and value used to identify RDS clusters.
-	RdsTags struct {
-		TagName  string // The name of the tag used to identify RDS clusters.
-		TagValue string // The value of the tag used to identify RDS clusters.
-	}
+	RdsTags TagFilter
+	// ExcludeTags lists tags that exclude clusters from discovery even if they match RdsTags,
+	// e.g. a Decommissioned=true tag. An empty TagValue matches any value.
+	ExcludeTags []TagFilter
 	// AllowedIAMUsers lists the IAM users permitted to connect to RDS clusters.
 	AllowedIAMUsers []string
-	// EnvTag maps environment names to their release state and region.
-	EnvTag map[string]struct {
-		ReleaseState string // The release state of the environment (e.g., "prod", "staging").
-		Region       string // The AWS region where the environment is located.
+	// AllowedIAMUsersFile is a path to a file holding additional users as a JSON list or one per line.
+	AllowedIAMUsersFile string
+	// AllowedIAMUsersParam is the name of an SSM parameter holding additional users as a JSON list or one per line.
+	AllowedIAMUsersParam string
+	// EnvTag maps environment names to their settings.
+	EnvTag map[string]EnvConfig
+	// DefaultRegion is the AWS region used for environments that do not specify one.
+	DefaultRegion string
+	// STSEndpoint selects the STS endpoint: "regional" (the default) or "global" for the legacy
+	// global endpoint.
+	STSEndpoint string
+	// AssumeRole configures an optional IAM role to assume for all AWS calls.
+	AssumeRole struct {
+		RoleArn     string            // The ARN of the role to assume. Empty disables role assumption.
+		SessionName string            // The role session name (defaults to "rds-iam-connect").
+		SessionTags map[string]string // Session tags passed to STS. Keys are lower-cased by the config loader.
 	}
+	// Roles lists IAM roles to choose from before discovery. The chosen role replaces AssumeRole.RoleArn and is
+	// assumed with the AssumeRole session name and tags. With more than one role, the user is prompted.
+	Roles []string
 	// Caching controls the caching behavior for RDS cluster data.
 	Caching struct {
 		Enabled  bool   // Whether caching is enabled.
 		Duration string // The duration for which cached data is valid.
+		Jitter   string // The maximum random adjustment applied to Duration (e.g., "30m").
+		FileMode string // Octal permission mode of cache files (e.g., "0600").
+		DirMode  string // Octal permission mode of the cache directory (e.g., "0700").
+		// PromptWhenStale asks whether to refresh a cache older than half its duration.
+		PromptWhenStale bool
+		// MaxFiles is the number of environments whose cache files are kept; the files of the least recently
+		// written ones beyond it are deleted when a cache file is written. 0 keeps all files.
+		MaxFiles int
+		// CacheEmpty caches discoveries that found no clusters for a short time, instead of not caching them.
+		CacheEmpty bool
+	}
+	// Discovery controls how clusters are discovered.
+	Discovery struct {
+		// BestEffort keeps the clusters found so far when fetching a later page fails, with a warning.
+		BestEffort bool
+	}
+	// UseIAMIdentity connects as the DB user named after the current IAM role instead of prompting for a user.
+	UseIAMIdentity bool
+	// AllowPasswordFallback prompts for the DB user's password when the server rejects the IAM auth token,
+	// for clusters where some users do not use IAM authentication. Only applies to interactive terminals.
+	AllowPasswordFallback bool
+	// DataAPI configures connections through the RDS Data API (--data-api).
+	DataAPI struct {
+		SecretArn string `sensitive:"true"` // ARN of the Secrets Manager secret holding the database credentials.
+		Database  string // Database used for statements (optional).
+	}
+	// ExternalTokenCommand is a command and its arguments that prints an auth token, used instead of
+	// signing tokens with the AWS SDK. It receives RDS_ENDPOINT, RDS_PORT, RDS_USER and RDS_REGION.
+	// Its arguments may carry credentials, so it is redacted from config dumps.
+	ExternalTokenCommand []string `sensitive:"true"`
+	// TokenCredentials configures separate credentials for signing auth tokens, e.g. the instance profile
+	// while discovery uses an assumed role. Tokens are signed with the discovery credentials when unset.
+	TokenCredentials struct {
+		Profile         string // Shared config profile whose credentials sign tokens.
+		InstanceProfile bool   // Sign tokens with the EC2 instance profile credentials.
+		RoleArn         string // Optional role assumed on top of these credentials.
+		SessionName     string // The role session name (defaults to "rds-iam-connect").
 	}
 	// CheckIAMPermissions determines whether to verify IAM permissions before connecting.
 	CheckIAMPermissions bool
+	// WarnOnNoAccess runs the IAM permission check as a non-fatal warning when CheckIAMPermissions is false.
+	WarnOnNoAccess bool
+	// IAMCheckActions are the actions simulated by the IAM permission check (defaults to rds-db:connect).
+	IAMCheckActions []string
+	// Check controls the behavior of the --check mode.
+	Check struct {
+		ProbeTimeout string // Timeout for endpoint reachability probes (defaults to "3s").
+	}
+	// Audit configures the local log of connections.
+	Audit struct {
+		Enabled bool   // Whether connections are recorded.
+		Path    string // Path of the audit log (defaults to audit.log in the cache directory).
+		Window  string // Window in which repeated connections count towards --max-connections (defaults to "1m").
+	}
+	// Logging configures where warnings, errors and connections are logged in addition to the terminal.
+	Logging struct {
+		Syslog   bool   // Whether they are also written to the local syslog. Not supported on Windows.
+		Tag      string // The syslog tag (defaults to "rds-iam-connect").
+		Facility string // The syslog facility: user (default), daemon, auth or local0 to local7.
+	}
+	// Metrics configures sending connection counts and discovery timings at the end of each run.
+	// Nothing is sent when neither is set.
+	Metrics struct {
+		StatsdAddress  string // host:port of a statsd server, sent to over UDP with DogStatsD tags.
+		PushgatewayURL string `sensitive:"true"` // Base URL of a Prometheus pushgateway, which may carry basic auth credentials.
+	}
+	// UI controls the interactive prompts.
+	UI UISettings
+	// Redaction lists the kinds of values masked in output such as list-clusters, --check and whoami.
+	Redaction RedactionSettings
+	// Client configures the database client commands.
+	Client ClientSettings
+	// Connect configures the database client sessions.
+	Connect struct {
+		// InitCommands are SQL statements the mysql client runs after connecting, e.g. "SET SESSION wait_timeout = 28800".
+		InitCommands []string
+		Timeout      string // Timeout for establishing the connection, e.g. "10s". Empty leaves the client default.
+		TokenNotice  bool   // Whether to print how long the auth token can be used before connecting.
+		// Charset is the character set of the client connection, e.g. "utf8mb4" or "latin1", passed as
+		// --default-character-set to mysql and as the equivalent PGCLIENTENCODING to psql. Empty leaves the client default.
+		Charset string
+		// Preflight logs in to MySQL clusters with the auth token before starting the client, so that a token
+		// that does not work, e.g. because of clock skew or a wrong region, is reported with the server's error.
+		Preflight bool
+	}
+	// ClusterNotes maps cluster identifiers to operational notes, e.g. the owner or maintenance window,
+	// printed before connecting. Identifiers are lower-cased by the config loader, like RDS does.
+	ClusterNotes map[string]string
+	// Profiles maps profile names to an environment, cluster and user to connect to without prompts.
+	// Names are lower-cased by the config loader.
+	Profiles map[string]ConnectionProfile
+	// SSM configures connecting through AWS Systems Manager Session Manager port forwarding.
+	SSM struct {
+		Target    string // ID of the instance that forwards the connection. Empty connects directly.
+		LocalPort int    // Local port of the forwarded connection (defaults to a free port).
+	}
+	// SSLMode is the TLS mode of database connections: "required", "verify-ca" or "verify-identity"
+	// ("verify-full" is accepted as an alias). The verifying modes use the bundled RDS CA certificates.
+	// Empty leaves the client default.
+	SSLMode string
+	// SSL holds further TLS settings of database connections.
+	SSL struct {
+		// MinVersion is the minimum TLS version of database connections: "TLSv1.2" or "TLSv1.3".
+		// Empty leaves the client default.
+		MinVersion string
+	}
 	// Debug enables detailed logging when set to true.
 	Debug bool
+	// VerboseDebug adds per-cluster details to the debug log, such as each cluster skipped for being
+	// in another region. By default such skips are summarized in a single line.
+	VerboseDebug bool
+}
+
+// TagFilter is a tag name and value used to identify RDS clusters.
+type TagFilter struct {
+	TagName  string // The name of the tag used to identify RDS clusters.
+	TagValue string // The value of the tag used to identify RDS clusters.
+	// CaseInsensitive matches the keys and values of this tag and the ReleaseState tag regardless of case.
+	// AWS tags are case-sensitive, so it is off by default.
+	CaseInsensitive bool
+}
+
+// EnvConfig holds the settings of a single environment.
+type EnvConfig struct {
+	ReleaseState string    // The release state of the environment (e.g., "prod", "staging").
+	Region       string    // The AWS region where the environment is located.
+	RdsTags      TagFilter // Overrides the global RdsTags for this environment. Empty fields fall back to the global values.
+	// Regions lists further regions the environment spans. Clusters are discovered in Region and every one
+	// of Regions. If Region is unset, the first of Regions takes its place, e.g. for the AWS credentials.
+	Regions []string
+	// AllowedIAMUsers replaces the global AllowedIAMUsers for this environment when set.
+	AllowedIAMUsers []string
+	// ResourceGroup is the name or ARN of an AWS Resource Group whose clusters make up the environment.
+	// When set, clusters are discovered by group membership instead of RdsTags and ReleaseState.
+	ResourceGroup string
+	// Accounts lists AWS accounts whose clusters belong to this environment, each reached by assuming
+	// a role. When set, clusters are discovered in every account instead of with the default credentials.
+	Accounts []AccountConfig
+	// Banner is printed prominently before connecting to the environment, e.g. "YOU ARE CONNECTING TO PRODUCTION".
+	Banner string
+	// RequireConfirm requires the environment name to be typed before connecting to the environment.
+	RequireConfirm bool
+}
+
+// AllRegions returns the regions clusters of the environment are discovered in: Region followed by Regions,
+// without duplicates.
+func (e EnvConfig) AllRegions() []string {
+	regions := make([]string, 0, 1+len(e.Regions))
+	for _, region := range append([]string{e.Region}, e.Regions...) {
+		if region != "" && !slices.Contains(regions, region) {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+// AccountConfig is an AWS account reached by assuming a role.
+type AccountConfig struct {
+	RoleArn string // The ARN of the role to assume in the account.
+	Region  string // The region of the account's clusters (defaults to the environment's regions).
+}
+
+// ConnectionProfile is a named combination of environment, cluster and user.
+type ConnectionProfile struct {
+	Env     string // The environment of the cluster.
+	Cluster string // The exact identifier of the cluster.
+	User    string // The DB user. May be empty when UseIAMIdentity is set.
+}
+
+// RedactionSettings lists the kinds of values masked in output. Nothing is masked by default.
+type RedactionSettings struct {
+	AccountIDs bool // Masks AWS account IDs, including the account of ARNs (like --mask-account).
+	ARNs       bool // Masks ARNs apart from their partition and service.
+	Endpoints  bool // Masks cluster and instance endpoints apart from their first label.
+}
+
+// UISettings controls the interactive prompts.
+type UISettings struct {
+	PageSize    int      // Number of options shown at once in selection prompts (defaults to 10).
+	VimMode     bool     // Enables vim-style j/k navigation in selection prompts.
+	DisplayTags []string // Cluster tag keys whose values are shown in the cluster prompt.
+	// Search replaces the cluster prompt with a search that matches every typed word against the cluster's
+	// identifier, endpoint, engine, account and tag values, and offers to rediscover the clusters from AWS.
+	Search bool
+	// NoRanking keeps the cluster prompt in discovery order instead of listing the clusters chosen most
+	// often and recently first, followed by the others in alphabetical order (like --no-ranking).
+	NoRanking bool
+	// ClusterTemplate is a Go text/template for cluster names in prompts and listings, with the fields
+	// .Identifier, .Endpoint, .Port, .Region, .Engine and .Arn. Defaults to "{{.Identifier}} ({{.Endpoint}}:{{.Port}})".
+	ClusterTemplate string
+	// PromptTemplate is a Go text/template for the mysql and psql prompt, with the fields .Environment,
+	// .ReleaseState, .Cluster and .User. Defaults to "{{.Environment}}:{{.Cluster}}> ".
+	PromptTemplate string
+	// ASCII replaces the Unicode status markers of --check and doctor with [OK] and [FAIL], for terminals
+	// that do not render them. Defaults to true on Windows, whose consoles often lack the glyphs.
+	ASCII *bool
+}
+
+// UseASCII reports whether output uses ASCII markers, either as configured or by default on Windows.
+func (u UISettings) UseASCII() bool {
+	if u.ASCII != nil {
+		return *u.ASCII
+	}
+	return runtime.GOOS == "windows"
+}
+
+// ParseClusterTemplate parses ClusterTemplate. Returns nil if no template is configured.
+func (u UISettings) ParseClusterTemplate() (*template.Template, error) {
+	if u.ClusterTemplate == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("cluster").Option("missingkey=error").Parse(u.ClusterTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ui.clusterTemplate: %w", err)
+	}
+	return tmpl, nil
 }
 
+// defaultPromptTemplate is the client prompt used when no PromptTemplate is configured.
+const defaultPromptTemplate = "{{.Environment}}:{{.Cluster}}> "
+
+// ParsePromptTemplate parses PromptTemplate, or the default prompt template if none is configured.
+func (u UISettings) ParsePromptTemplate() (*template.Template, error) {
+	text := u.PromptTemplate
+	if text == "" {
+		text = defaultPromptTemplate
+	}
+
+	tmpl, err := template.New("prompt").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ui.promptTemplate: %w", err)
+	}
+	return tmpl, nil
+}
+
+// defaultPageSize is the number of options shown in selection prompts when none is configured.
+const defaultPageSize = 10
+
+// SelectPageSize returns the configured page size, or the default if unset.
+func (u UISettings) SelectPageSize() int {
+	if u.PageSize <= 0 {
+		return defaultPageSize
+	}
+	return u.PageSize
+}
+
+// defaultAuditWindow is the window for counting repeated connections used when none is configured.
+const defaultAuditWindow = time.Minute
+
+// defaultProbeTimeout is the endpoint reachability probe timeout used when none is configured.
+const defaultProbeTimeout = 3 * time.Second
+
+// Limits imposed by AWS STS on session tags.
+const (
+	maxSessionTags           = 50
+	maxSessionTagKeyLength   = 128
+	maxSessionTagValueLength = 256
+)
+
+// sessionTagPattern matches the characters AWS allows in session tag keys and values.
+var sessionTagPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
 // LoadConfig loads the application configuration from a YAML file.
 // If configPath is not provided, it uses the default path in the user's home directory.
 // If the config file doesn't exist, it copies the example config.
@@ -56,6 +341,8 @@ func loadConfigFromPath(configPath string) (*Config, error) {
 	viper.SetConfigFile(configPath)
 	viper.SetConfigType("yaml")
 
+	warnConfigPermissions(configPath)
+
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
@@ -65,17 +352,641 @@ func loadConfigFromPath(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to decode config into struct: %w", err)
 	}
 
+	if err := config.resolveRegions(); err != nil {
+		return nil, err
+	}
+
+	if err := config.loadAllowedIAMUsersFile(); err != nil {
+		return nil, err
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	return &config, nil
 }
 
-// loadDefaultConfig loads the default configuration from the user's home directory.
-func loadDefaultConfig() (*Config, error) {
+// warnConfigPermissions warns when the config file is accessible by its group or other users, since it
+// can contain role ARNs and internal hostnames. Windows does not have Unix permissions and is skipped.
+func warnConfigPermissions(configPath string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	info, err := os.Stat(configPath)
+	if err != nil {
+		// ReadInConfig reports missing or unreadable files
+		return
+	}
+
+	if mode := info.Mode().Perm(); mode&0o077 != 0 {
+		logger.Warnf("config file %s has permissions %04o and is accessible by other users; "+
+			"consider running chmod 600 %s", configPath, mode, configPath)
+	}
+}
+
+// loadAllowedIAMUsersFile merges the users listed in AllowedIAMUsersFile into AllowedIAMUsers.
+func (c *Config) loadAllowedIAMUsersFile() error {
+	if c.AllowedIAMUsersFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(filepath.Clean(c.AllowedIAMUsersFile))
+	if err != nil {
+		return fmt.Errorf("failed to read allowed IAM users file: %w", err)
+	}
+
+	users, err := ParseUserList(string(data))
+	if err != nil {
+		return fmt.Errorf("failed to parse allowed IAM users file %s: %w", c.AllowedIAMUsersFile, err)
+	}
+
+	c.MergeAllowedIAMUsers(users)
+	return nil
+}
+
+// MergeAllowedIAMUsers appends users to AllowedIAMUsers, skipping duplicates.
+func (c *Config) MergeAllowedIAMUsers(users []string) {
+	seen := make(map[string]bool, len(c.AllowedIAMUsers))
+	for _, user := range c.AllowedIAMUsers {
+		seen[user] = true
+	}
+
+	for _, user := range users {
+		if !seen[user] {
+			c.AllowedIAMUsers = append(c.AllowedIAMUsers, user)
+			seen[user] = true
+		}
+	}
+}
+
+// ParseUserList parses a list of users given either as a JSON array of strings
+// or as one user per line. Blank lines and lines starting with '#' are ignored.
+func ParseUserList(data string) ([]string, error) {
+	trimmed := strings.TrimSpace(data)
+	if strings.HasPrefix(trimmed, "[") {
+		var users []string
+		if err := json.Unmarshal([]byte(trimmed), &users); err != nil {
+			return nil, fmt.Errorf("invalid JSON user list: %w", err)
+		}
+		return users, nil
+	}
+
+	var users []string
+	for _, line := range strings.Split(trimmed, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		users = append(users, line)
+	}
+	return users, nil
+}
+
+// validate checks the configuration for invalid values.
+func (c *Config) validate() error {
+	if err := validateSessionTags(c.AssumeRole.SessionTags); err != nil {
+		return err
+	}
+
+	if err := c.validateCacheModes(); err != nil {
+		return err
+	}
+
+	if _, err := c.AuditWindow(); err != nil {
+		return err
+	}
+
+	switch c.STSEndpoint {
+	case "", "regional", "global":
+	default:
+		return fmt.Errorf("stsEndpoint must be \"regional\" or \"global\", got %q", c.STSEndpoint)
+	}
+
+	if len(c.ExternalTokenCommand) > 0 {
+		if _, err := exec.LookPath(c.ExternalTokenCommand[0]); err != nil {
+			return fmt.Errorf("externalTokenCommand %q is not executable: %w", c.ExternalTokenCommand[0], err)
+		}
+	}
+
+	if c.TokenCredentials.Profile != "" && c.TokenCredentials.InstanceProfile {
+		return fmt.Errorf("tokenCredentials.profile and tokenCredentials.instanceProfile are mutually exclusive")
+	}
+	if role := c.TokenCredentials.RoleArn; role != "" && (!strings.HasPrefix(role, "arn:") || !strings.Contains(role, ":role/")) {
+		return fmt.Errorf("tokenCredentials.roleArn %q is not an IAM role ARN", role)
+	}
+
+	for _, role := range c.Roles {
+		if !strings.HasPrefix(role, "arn:") || !strings.Contains(role, ":role/") {
+			return fmt.Errorf("roles entry %q is not an IAM role ARN", role)
+		}
+	}
+
+	for _, action := range c.IAMCheckActions {
+		if service, name, ok := strings.Cut(action, ":"); !ok || service == "" || name == "" {
+			return fmt.Errorf("iamCheckActions entry %q is not an IAM action such as \"rds-db:connect\"", action)
+		}
+	}
+
+	for i, tag := range c.ExcludeTags {
+		if tag.TagName == "" {
+			return fmt.Errorf("excludeTags[%d] must set a tagName", i)
+		}
+	}
+
+	if err := c.validateEnvironments(); err != nil {
+		return err
+	}
+
+	if err := c.validateEnvUsers(); err != nil {
+		return err
+	}
+
+	if err := c.validateAccounts(); err != nil {
+		return err
+	}
+
+	if err := c.validateProfiles(); err != nil {
+		return err
+	}
+
+	if err := ValidateSSLMode(c.SSLMode); err != nil {
+		return err
+	}
+	if err := ValidateTLSMinVersion(c.SSL.MinVersion); err != nil {
+		return err
+	}
+
+	if _, err := c.UI.ParseClusterTemplate(); err != nil {
+		return err
+	}
+	if _, err := c.UI.ParsePromptTemplate(); err != nil {
+		return err
+	}
+	if _, err := c.Client.ParseCommands(); err != nil {
+		return err
+	}
+
+	if err := c.validateInitCommands(); err != nil {
+		return err
+	}
+	if _, err := c.ConnectTimeout(); err != nil {
+		return err
+	}
+	if err := ValidateCharset(c.Connect.Charset); err != nil {
+		return err
+	}
+
+	if c.SSM.LocalPort < 0 || c.SSM.LocalPort > 65535 {
+		return fmt.Errorf("ssm.localPort must be between 0 and 65535, got %d", c.SSM.LocalPort)
+	}
+
+	if c.Caching.MaxFiles < 0 {
+		return fmt.Errorf("caching.maxFiles must not be negative, got %d", c.Caching.MaxFiles)
+	}
+
+	if err := c.validateMetrics(); err != nil {
+		return err
+	}
+
+	if c.UI.PageSize < 0 {
+		return fmt.Errorf("ui.pageSize must not be negative, got %d", c.UI.PageSize)
+	}
+
+	_, err := c.ProbeTimeout()
+	return err
+}
+
+// validateMetrics checks the statsd address and pushgateway URL, if set.
+func (c *Config) validateMetrics() error {
+	if addr := c.Metrics.StatsdAddress; addr != "" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("metrics.statsdAddress must be host:port, got %q", addr)
+		}
+	}
+	if raw := c.Metrics.PushgatewayURL; raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("metrics.pushgatewayURL must be an http or https URL, got %q", raw)
+		}
+	}
+	return nil
+}
+
+// validateProfiles checks that each profile names a configured environment, a cluster and an allowed user.
+// Users are only checked against the statically configured list when no SSM parameter can add more.
+func (c *Config) validateProfiles() error {
+	for name, profile := range c.Profiles {
+		if _, ok := c.EnvTag[profile.Env]; !ok {
+			return fmt.Errorf("profile %q references unknown environment %q", name, profile.Env)
+		}
+		if profile.Cluster == "" {
+			return fmt.Errorf("profile %q must set a cluster", name)
+		}
+		if profile.User == "" {
+			if !c.UseIAMIdentity {
+				return fmt.Errorf("profile %q must set a user unless useIAMIdentity is enabled", name)
+			}
+			continue
+		}
+		// Users from the SSM parameter are merged into the global list only
+		staticUsers := c.AllowedIAMUsersParam == "" || len(c.EnvTag[profile.Env].AllowedIAMUsers) > 0
+		if staticUsers && !c.IsAllowedUser(profile.Env, profile.User) {
+			return fmt.Errorf("profile %q references user %q, which is not in allowedIAMUsers", name, profile.User)
+		}
+	}
+	return nil
+}
+
+// HasTokenCredentials reports whether separate credentials are configured for signing auth tokens.
+func (c *Config) HasTokenCredentials() bool {
+	return c.TokenCredentials.Profile != "" || c.TokenCredentials.InstanceProfile || c.TokenCredentials.RoleArn != ""
+}
+
+// maxUserNameLength is the maximum length of a MySQL user name.
+const maxUserNameLength = 32
+
+// validateEnvUsers checks the user names of the per-environment AllowedIAMUsers lists.
+func (c *Config) validateEnvUsers() error {
+	for env, envCfg := range c.EnvTag {
+		for _, user := range envCfg.AllowedIAMUsers {
+			if user == "" || len(user) > maxUserNameLength || strings.ContainsAny(user, " \t\n\r") {
+				return fmt.Errorf("envTag.%s.allowedIAMUsers contains invalid user name %q", env, user)
+			}
+		}
+	}
+	return nil
+}
+
+// validateEnvironments checks that each environment has a region and a release state to match clusters by.
+// Without a release state the environment tag would silently match no clusters. Environments that take
+// their clusters from a resource group do not match tags and need no release state.
+func (c *Config) validateEnvironments() error {
+	for env, envCfg := range c.EnvTag {
+		if strings.TrimSpace(envCfg.Region) == "" {
+			return fmt.Errorf("envTag.%s must set a region", env)
+		}
+		for i, region := range envCfg.Regions {
+			if strings.TrimSpace(region) == "" {
+				return fmt.Errorf("envTag.%s.regions[%d] must not be empty", env, i)
+			}
+			if slices.Contains(envCfg.Regions[:i], region) {
+				return fmt.Errorf("envTag.%s.regions lists %s more than once", env, region)
+			}
+		}
+		if envCfg.ResourceGroup == "" && strings.TrimSpace(envCfg.ReleaseState) == "" {
+			return fmt.Errorf("envTag.%s must set a releaseState (or a resourceGroup)", env)
+		}
+	}
+	return nil
+}
+
+// validateAccounts checks that the accounts of each environment are given as role ARNs of distinct accounts.
+func (c *Config) validateAccounts() error {
+	for env, envCfg := range c.EnvTag {
+		seen := make(map[string]bool, len(envCfg.Accounts))
+		for i, account := range envCfg.Accounts {
+			parts := strings.Split(account.RoleArn, ":")
+			if len(parts) < 6 || parts[0] != "arn" || parts[4] == "" || !strings.HasPrefix(parts[5], "role/") {
+				return fmt.Errorf("envTag.%s.accounts[%d].roleArn %q is not an IAM role ARN", env, i, account.RoleArn)
+			}
+			if seen[parts[4]] {
+				return fmt.Errorf("envTag.%s.accounts lists account %s more than once", env, parts[4])
+			}
+			seen[parts[4]] = true
+		}
+	}
+	return nil
+}
+
+// AllowedUsers returns the users permitted to connect in the environment: its own AllowedIAMUsers
+// if it defines any, otherwise the global AllowedIAMUsers.
+func (c *Config) AllowedUsers(env string) []string {
+	if users := c.EnvTag[env].AllowedIAMUsers; len(users) > 0 {
+		return users
+	}
+	return c.AllowedIAMUsers
+}
+
+// IsAllowedUser reports whether user is permitted to connect in the environment.
+func (c *Config) IsAllowedUser(env, user string) bool {
+	for _, allowed := range c.AllowedUsers(env) {
+		if allowed == user {
+			return true
+		}
+	}
+	return false
+}
+
+// SSL modes supported for database connections.
+const (
+	SSLModeRequired       = "required"
+	SSLModeVerifyCA       = "verify-ca"
+	SSLModeVerifyIdentity = "verify-identity"
+	SSLModeVerifyFull     = "verify-full"
+)
+
+// ValidateSSLMode checks that mode is empty or one of the supported SSL modes.
+func ValidateSSLMode(mode string) error {
+	switch mode {
+	case "", SSLModeRequired, SSLModeVerifyCA, SSLModeVerifyIdentity, SSLModeVerifyFull:
+		return nil
+	default:
+		return fmt.Errorf("sslMode must be one of %q, %q, %q or %q, got %q",
+			SSLModeRequired, SSLModeVerifyCA, SSLModeVerifyIdentity, SSLModeVerifyFull, mode)
+	}
+}
+
+// TLS versions supported as the minimum version of database connections.
+const (
+	TLSVersion12 = "TLSv1.2"
+	TLSVersion13 = "TLSv1.3"
+)
+
+// ValidateTLSMinVersion checks that version is empty or one of the supported minimum TLS versions.
+// TLS 1.0 and 1.1 are deprecated and rejected.
+func ValidateTLSMinVersion(version string) error {
+	switch version {
+	case "", TLSVersion12, TLSVersion13:
+		return nil
+	case "TLSv1", "TLSv1.0", "TLSv1.1":
+		return fmt.Errorf("ssl.minVersion %s is deprecated and insecure, use %q or %q", version, TLSVersion12, TLSVersion13)
+	default:
+		return fmt.Errorf("ssl.minVersion must be %q or %q, got %q", TLSVersion12, TLSVersion13, version)
+	}
+}
+
+// TLSVersionsFrom returns the supported TLS versions starting at the given minimum version, in the
+// comma-separated form of the mysql --tls-version option, e.g. "TLSv1.2,TLSv1.3".
+func TLSVersionsFrom(minVersion string) string {
+	if minVersion == TLSVersion13 {
+		return TLSVersion13
+	}
+	return TLSVersion12 + "," + TLSVersion13
+}
+
+// clientCharsets maps the character sets accepted for Connect.Charset, named as in MySQL, to the
+// equivalent PostgreSQL client encoding.
+var clientCharsets = map[string]string{
+	"utf8mb4": "UTF8",
+	"utf8mb3": "UTF8",
+	"utf8":    "UTF8",
+	"latin1":  "LATIN1",
+	"latin2":  "LATIN2",
+	"latin5":  "LATIN5",
+	"latin7":  "LATIN7",
+	"ascii":   "SQL_ASCII",
+	"cp1250":  "WIN1250",
+	"cp1251":  "WIN1251",
+	"cp1256":  "WIN1256",
+	"cp1257":  "WIN1257",
+	"greek":   "ISO_8859_7",
+	"hebrew":  "ISO_8859_8",
+	"koi8r":   "KOI8R",
+	"koi8u":   "KOI8U",
+	"sjis":    "SJIS",
+	"ujis":    "EUC_JP",
+	"euckr":   "EUC_KR",
+	"gbk":     "GBK",
+	"gb18030": "GB18030",
+	"big5":    "BIG5",
+}
+
+// ValidateCharset checks that charset is empty or one of the known client character sets, ignoring case.
+func ValidateCharset(charset string) error {
+	if charset == "" {
+		return nil
+	}
+	if _, ok := clientCharsets[strings.ToLower(charset)]; !ok {
+		names := make([]string, 0, len(clientCharsets))
+		for name := range clientCharsets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("connect.charset must be one of %s, got %q", strings.Join(names, ", "), charset)
+	}
+	return nil
+}
+
+// PostgresEncoding returns the PostgreSQL client encoding of a valid character set, e.g. "UTF8" for "utf8mb4".
+func PostgresEncoding(charset string) string {
+	return clientCharsets[strings.ToLower(charset)]
+}
+
+// AuditWindow returns the configured window for counting repeated connections, or the default if unset.
+func (c *Config) AuditWindow() (time.Duration, error) {
+	if c.Audit.Window == "" {
+		return defaultAuditWindow, nil
+	}
+
+	window, err := time.ParseDuration(c.Audit.Window)
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("audit.window must be a positive duration such as \"1m\", got %q", c.Audit.Window)
+	}
+	return window, nil
+}
+
+// maxInitCommandLength is the maximum length of each of Connect.InitCommands.
+const maxInitCommandLength = 1024
+
+// validateInitCommands checks that each of Connect.InitCommands is a single SQL statement.
+// The statements are joined into one mysql --init-command, so separators are not allowed.
+func (c *Config) validateInitCommands() error {
+	for i, command := range c.Connect.InitCommands {
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("connect.initCommands[%d] must not be empty", i)
+		}
+		if len(command) > maxInitCommandLength {
+			return fmt.Errorf("connect.initCommands[%d] is longer than %d characters", i, maxInitCommandLength)
+		}
+		if strings.ContainsAny(command, ";\x00\n\r") {
+			return fmt.Errorf("connect.initCommands[%d] must be a single statement without \";\" or line breaks, got %q", i, command)
+		}
+	}
+	return nil
+}
+
+// ConnectTimeout returns the configured connection timeout, or 0 if the client default should be used.
+// Clients take the timeout in whole seconds.
+func (c *Config) ConnectTimeout() (time.Duration, error) {
+	if c.Connect.Timeout == "" {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(c.Connect.Timeout)
+	if err != nil || timeout < time.Second || timeout%time.Second != 0 {
+		return 0, fmt.Errorf("connect.timeout must be a whole number of seconds such as \"10s\", got %q", c.Connect.Timeout)
+	}
+	return timeout, nil
+}
+
+// AuditPath returns the path of the audit log, defaulting to audit.log in the cache directory.
+func (c *Config) AuditPath() (string, error) {
+	if c.Audit.Path != "" {
+		return c.Audit.Path, nil
+	}
+
 	cacheDir, err := utils.GetCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "audit.log"), nil
+}
+
+// ProbeTimeout returns the configured endpoint probe timeout, or the default if unset.
+func (c *Config) ProbeTimeout() (time.Duration, error) {
+	if c.Check.ProbeTimeout == "" {
+		return defaultProbeTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(c.Check.ProbeTimeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("check.probeTimeout must be a positive duration such as \"3s\", got %q", c.Check.ProbeTimeout)
+	}
+	return timeout, nil
+}
+
+// validateCacheModes checks the cache permission modes and warns about world-readable settings.
+func (c *Config) validateCacheModes() error {
+	fileMode, err := c.CacheFileMode()
+	if err != nil {
+		return err
+	}
+	dirMode, err := c.CacheDirMode()
+	if err != nil {
+		return err
+	}
+
+	if fileMode&0o004 != 0 || dirMode&0o004 != 0 {
+		logger.Warnf("cache permissions (file %04o, directory %04o) are world-readable; "+
+			"cache files can contain cluster ARNs and endpoints", fileMode, dirMode)
+	}
+
+	return nil
+}
+
+// CacheFileMode returns the configured cache file mode, or 0 if the default should be used.
+func (c *Config) CacheFileMode() (os.FileMode, error) {
+	return parseFileMode("caching.fileMode", c.Caching.FileMode)
+}
+
+// CacheDirMode returns the configured cache directory mode, or 0 if the default should be used.
+func (c *Config) CacheDirMode() (os.FileMode, error) {
+	return parseFileMode("caching.dirMode", c.Caching.DirMode)
+}
+
+// parseFileMode parses an octal permission string such as "0640".
+// An empty string yields 0, meaning the default mode applies.
+func parseFileMode(field, value string) (os.FileMode, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("%s must be an octal permission mode such as \"0600\", got %q", field, value)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// validateSessionTags checks session tags against the AWS STS limits.
+func validateSessionTags(tags map[string]string) error {
+	if len(tags) > maxSessionTags {
+		return fmt.Errorf("too many session tags: %d (maximum %d)", len(tags), maxSessionTags)
+	}
+
+	for key, value := range tags {
+		if key == "" || len(key) > maxSessionTagKeyLength {
+			return fmt.Errorf("session tag key %q must be between 1 and %d characters", key, maxSessionTagKeyLength)
+		}
+		if strings.HasPrefix(strings.ToLower(key), "aws:") {
+			return fmt.Errorf("session tag key %q uses the reserved aws: prefix", key)
+		}
+		if len(value) > maxSessionTagValueLength {
+			return fmt.Errorf("session tag %q value must be at most %d characters", key, maxSessionTagValueLength)
+		}
+		if !sessionTagPattern.MatchString(key) || !sessionTagPattern.MatchString(value) {
+			return fmt.Errorf("session tag %q contains characters not allowed by AWS", key)
+		}
+	}
+
+	return nil
+}
+
+// EnvRdsTags returns the tag filter used to discover clusters in the given environment.
+// Fields not overridden by the environment fall back to the global RdsTags.
+func (c *Config) EnvRdsTags(env string) TagFilter {
+	tags := c.RdsTags
+	override := c.EnvTag[env].RdsTags
+	if override.TagName != "" {
+		tags.TagName = override.TagName
+	}
+	if override.TagValue != "" {
+		tags.TagValue = override.TagValue
+	}
+	// An unset bool cannot be told from false, so either level can enable it
+	tags.CaseInsensitive = tags.CaseInsensitive || override.CaseInsensitive
+	return tags
+}
+
+// resolveRegions fills in the region of environments that omit it.
+// The fallback order is DefaultRegion, then the AWS_REGION and AWS_DEFAULT_REGION environment variables,
+// then the region of the AWS profile in the shared config file.
+// Returns an error naming the environment if no region can be resolved.
+func (c *Config) resolveRegions() error {
+	fallback := c.DefaultRegion
+	if fallback == "" {
+		fallback = os.Getenv("AWS_REGION")
+	}
+	if fallback == "" {
+		fallback = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	profileResolved := false
+
+	for name, env := range c.EnvTag {
+		if env.Region == "" && len(env.Regions) > 0 {
+			env.Region = env.Regions[0]
+			c.EnvTag[name] = env
+		}
+		if env.Region != "" {
+			continue
+		}
+		// The shared config file is only read if an environment needs it
+		if fallback == "" && !profileResolved {
+			fallback = profileRegion()
+			profileResolved = true
+		}
+		if fallback == "" {
+			return fmt.Errorf("no region configured for environment %q: set its region, defaultRegion, AWS_REGION, "+
+				"or a region in your AWS profile", name)
+		}
+		env.Region = fallback
+		c.EnvTag[name] = env
+	}
+
+	return nil
+}
+
+// profileRegion returns the region of the active AWS profile, as resolved by the AWS SDK from
+// AWS_PROFILE and the shared config file. Returns an empty string if none is configured.
+func profileRegion() string {
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return ""
+	}
+	return cfg.Region
+}
+
+// loadDefaultConfig loads the default configuration from the configuration directory.
+func loadDefaultConfig() (*Config, error) {
+	configDir, err := utils.GetConfigDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get config directory: %w", err)
 	}
 
-	configPath := filepath.Join(cacheDir, "config.yaml")
+	configPath := filepath.Join(configDir, "config.yaml")
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		if err := createDefaultConfig(configPath); err != nil {
 			return nil, err
@@ -101,6 +1012,6 @@ func createDefaultConfig(configPath string) error {
 		return fmt.Errorf("failed to create default config: %w", err)
 	}
 
-	fmt.Printf("Created default config at %s\n", configPath)
+	fmt.Fprintf(os.Stderr, "Created default config at %s\n", configPath)
 	return nil
 }
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSessionTags(t *testing.T) {
+	tooMany := make(map[string]string, maxSessionTags+1)
+	for i := 0; i <= maxSessionTags; i++ {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+
+	tests := []struct {
+		name    string
+		tags    map[string]string
+		wantErr bool
+	}{
+		{name: "valid tags", tags: map[string]string{"team": "payments", "ticket": "OPS-1234"}},
+		{name: "empty value", tags: map[string]string{"team": ""}},
+		{name: "no tags", tags: nil},
+		{name: "too many tags", tags: tooMany, wantErr: true},
+		{name: "empty key", tags: map[string]string{"": "value"}, wantErr: true},
+		{name: "key too long", tags: map[string]string{strings.Repeat("k", maxSessionTagKeyLength+1): "v"}, wantErr: true},
+		{name: "value too long", tags: map[string]string{"team": strings.Repeat("v", maxSessionTagValueLength+1)}, wantErr: true},
+		{name: "reserved prefix", tags: map[string]string{"aws:team": "payments"}, wantErr: true},
+		{name: "invalid characters", tags: map[string]string{"team": "pay;ments"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSessionTags(tt.tags)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEnvRdsTags(t *testing.T) {
+	cfg := &Config{
+		RdsTags: TagFilter{TagName: "Environment", TagValue: "Production"},
+		EnvTag: map[string]EnvConfig{
+			"prod":   {ReleaseState: "prod"},
+			"legacy": {ReleaseState: "prod", RdsTags: TagFilter{TagName: "env"}},
+			"other":  {ReleaseState: "prod", RdsTags: TagFilter{TagName: "team", TagValue: "payments"}},
+			"mixed":  {ReleaseState: "prod", RdsTags: TagFilter{CaseInsensitive: true}},
+		},
+	}
+
+	assert.Equal(t, TagFilter{TagName: "Environment", TagValue: "Production"}, cfg.EnvRdsTags("prod"))
+	assert.Equal(t, TagFilter{TagName: "env", TagValue: "Production"}, cfg.EnvRdsTags("legacy"))
+	assert.Equal(t, TagFilter{TagName: "team", TagValue: "payments"}, cfg.EnvRdsTags("other"))
+	assert.Equal(t, TagFilter{TagName: "Environment", TagValue: "Production", CaseInsensitive: true}, cfg.EnvRdsTags("mixed"))
+}
+
+func TestSettings(t *testing.T) {
+	var cfg Config
+	cfg.RdsTags = TagFilter{TagName: "Environment", TagValue: "Production"}
+	cfg.EnvTag = map[string]EnvConfig{"Prod": {Region: "us-east-1"}}
+	cfg.DataAPI.SecretArn = "arn:aws:secretsmanager:us-east-1:123456789012:secret:db"
+	cfg.SSLMode = SSLModeVerifyCA
+
+	settings := cfg.Settings()
+
+	assert.Equal(t, map[string]any{"tagName": "Environment", "tagValue": "Production", "caseInsensitive": false}, settings["rdsTags"])
+	assert.Contains(t, settings["envTag"], "Prod")
+	assert.Equal(t, redacted, settings["dataAPI"].(map[string]any)["secretArn"])
+	assert.Equal(t, []any{}, settings["externalTokenCommand"])
+	assert.Equal(t, SSLModeVerifyCA, settings["sslMode"])
+	assert.Contains(t, settings, "ssm")
+	assert.Contains(t, settings, "stsEndpoint")
+	assert.Contains(t, settings, "allowedIAMUsers")
+}
+
+func TestAllowedUsers(t *testing.T) {
+	cfg := Config{
+		AllowedIAMUsers: []string{"alice", "bob"},
+		EnvTag: map[string]EnvConfig{
+			"prod": {AllowedIAMUsers: []string{"carol"}},
+			"dev":  {},
+		},
+	}
+
+	assert.Equal(t, []string{"carol"}, cfg.AllowedUsers("prod"))
+	assert.Equal(t, []string{"alice", "bob"}, cfg.AllowedUsers("dev"))
+	assert.True(t, cfg.IsAllowedUser("prod", "carol"))
+	assert.False(t, cfg.IsAllowedUser("prod", "alice"))
+	assert.True(t, cfg.IsAllowedUser("dev", "alice"))
+}
+
+func TestParseCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands map[string][]string
+		wantErr  bool
+	}{
+		{name: "defaults", commands: nil},
+		{name: "custom mysql", commands: map[string][]string{EngineMySQL: {"mariadb", "--host={{.Host}}", "--port={{.Port}}"}}},
+		{name: "unknown field", commands: map[string][]string{EngineMySQL: {"mysql", "{{.Password}}"}}, wantErr: true},
+		{name: "invalid template", commands: map[string][]string{EnginePostgres: {"psql", "{{.Host"}}, wantErr: true},
+		{name: "unknown engine", commands: map[string][]string{"oracle": {"sqlplus"}}, wantErr: true},
+		{name: "empty command", commands: map[string][]string{EngineMySQL: {}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands, err := ClientSettings{Commands: tt.commands}.ParseCommands()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Contains(t, commands, EngineMySQL)
+			assert.Contains(t, commands, EnginePostgres)
+		})
+	}
+}
+
+func TestValidateCharset(t *testing.T) {
+	assert.NoError(t, ValidateCharset(""))
+	assert.NoError(t, ValidateCharset("utf8mb4"))
+	assert.NoError(t, ValidateCharset("LATIN1"))
+	assert.Error(t, ValidateCharset("utf-8"))
+	assert.Error(t, ValidateCharset("latin1; DROP"))
+
+	assert.Equal(t, "UTF8", PostgresEncoding("utf8mb4"))
+	assert.Equal(t, "LATIN1", PostgresEncoding("Latin1"))
+}
+
+func TestAllRegions(t *testing.T) {
+	assert.Equal(t, []string{"us-east-1"}, EnvConfig{Region: "us-east-1"}.AllRegions())
+	assert.Equal(t, []string{"us-east-1", "us-west-2"},
+		EnvConfig{Region: "us-east-1", Regions: []string{"us-east-1", "us-west-2"}}.AllRegions())
+	assert.Equal(t, []string{"eu-west-1"}, EnvConfig{Regions: []string{"eu-west-1"}}.AllRegions())
+
+	cfg := &Config{EnvTag: map[string]EnvConfig{"prod": {Regions: []string{"us-west-2", "us-east-1"}}}}
+	assert.NoError(t, cfg.resolveRegions())
+	assert.Equal(t, "us-west-2", cfg.EnvTag["prod"].Region)
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"reflect"
+	"unicode"
+)
+
+// redacted replaces the value of sensitive fields in config dumps.
+const redacted = "<redacted>"
+
+// Settings returns the configuration as nested maps keyed like the config file, e.g. "rdsTags" or "sslMode".
+// Fields tagged `sensitive:"true"` are replaced with a placeholder when set, so the result is safe to print.
+func (c *Config) Settings() map[string]any {
+	settings, _ := settingsValue(reflect.ValueOf(*c)).(map[string]any)
+	return settings
+}
+
+// settingsValue converts v into maps, slices and plain values.
+func settingsValue(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := make(map[string]any, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Tag.Get("sensitive") == "true" && !v.Field(i).IsZero() {
+				fields[settingKey(field.Name)] = redacted
+				continue
+			}
+			fields[settingKey(field.Name)] = settingsValue(v.Field(i))
+		}
+		return fields
+	case reflect.Map:
+		// Map keys are user-chosen names such as environments, so they are kept as is
+		entries := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			entries[iter.Key().String()] = settingsValue(iter.Value())
+		}
+		return entries
+	case reflect.Slice:
+		items := make([]any, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, settingsValue(v.Index(i)))
+		}
+		return items
+	default:
+		return v.Interface()
+	}
+}
+
+// settingKey converts a field name to the key used in the config file by lower-casing its leading
+// upper-case run, e.g. "RdsTags" to "rdsTags", "SSLMode" to "sslMode" and "UI" to "ui".
+func settingKey(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		// Keep the last capital of an acronym followed by a new word, e.g. the "M" of "SSLMode"
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
@@ -0,0 +1,121 @@
+// Package audit records connections made by the RDS IAM Connect tool in a local JSON lines log.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileMode is the permission mode of the audit log. Use 0600 so only the owner can read it.
+const fileMode os.FileMode = 0600
+
+// Results of a connection recorded in the audit log. A connection is appended with ResultStarted before
+// the client starts, and again with ResultSuccess or ResultFailure and the same Time when it exits, so that
+// connections of a session that is still running or was killed are recorded as well.
+const (
+	ResultStarted = "started"
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Entry is a single connection recorded in the audit log. Entries written before results were
+// recorded have an empty Result.
+type Entry struct {
+	Time        time.Time `json:"time"`
+	Environment string    `json:"environment"`
+	Cluster     string    `json:"cluster"`
+	Endpoint    string    `json:"endpoint"`
+	User        string    `json:"user"`
+	Identity    string    `json:"identity,omitempty"`
+	Result      string    `json:"result,omitempty"`
+}
+
+// Log is an append-only audit log stored as one JSON object per line.
+type Log struct {
+	path string
+}
+
+// New creates a Log that writes to the given file.
+func New(path string) *Log {
+	return &Log{path: filepath.Clean(path)}
+}
+
+// Append adds an entry to the log, creating the file if it doesn't exist.
+func (l *Log) Append(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, fileMode)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return file.Close()
+}
+
+// Since returns the entries recorded at or after t. A missing log has no entries.
+// Lines that cannot be parsed are skipped.
+func (l *Log) Since(t time.Time) ([]Entry, error) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if !entry.Time.Before(t) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Connections merges the entries that record the result of a connection into the entry that recorded its
+// start, matched by Time, Cluster and User, so that every connection appears once with its latest result.
+// Connections without a result entry keep ResultStarted. The order of the entries is kept.
+func Connections(entries []Entry) []Entry {
+	type key struct {
+		time          time.Time
+		cluster, user string
+	}
+
+	connections := make([]Entry, 0, len(entries))
+	started := make(map[key]int)
+	for _, entry := range entries {
+		k := key{entry.Time, entry.Cluster, entry.User}
+		if i, ok := started[k]; ok && entry.Result != ResultStarted {
+			connections[i].Result = entry.Result
+			delete(started, k)
+			continue
+		}
+		if entry.Result == ResultStarted {
+			started[k] = len(connections)
+		}
+		connections = append(connections, entry)
+	}
+	return connections
+}
@@ -0,0 +1,34 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnections(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	log := New(filepath.Join(t.TempDir(), "audit.log"))
+
+	entries := []Entry{
+		{Time: start.Add(-time.Hour), Cluster: "orders", User: "app", Result: ResultSuccess}, // Written at exit only
+		{Time: start, Cluster: "orders", User: "app", Result: ResultStarted},
+		{Time: start.Add(time.Minute), Cluster: "orders", User: "app", Result: ResultStarted},
+		{Time: start, Cluster: "orders", User: "app", Result: ResultFailure},
+	}
+	for _, entry := range entries {
+		require.NoError(t, log.Append(entry))
+	}
+
+	read, err := log.Since(time.Time{})
+	require.NoError(t, err)
+
+	results := make([]string, 0, len(read))
+	for _, entry := range Connections(read) {
+		results = append(results, entry.Result)
+	}
+	assert.Equal(t, []string{ResultSuccess, ResultFailure, ResultStarted}, results)
+}
@@ -6,13 +6,33 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
 )
 
+// STS endpoint modes.
+const (
+	// STSEndpointRegional calls STS in the configured region. It is the default.
+	STSEndpointRegional = "regional"
+	// STSEndpointGlobal calls the legacy global STS endpoint, which is served from us-east-1. It only
+	// exists in the aws partition; elsewhere, e.g. in aws-cn and aws-us-gov, the regional endpoint is used.
+	STSEndpointGlobal = "global"
+)
+
+// defaultRoleSessionName is the session name used when assuming a role without an explicit name.
+const defaultRoleSessionName = "rds-iam-connect"
+
 // STSClient is an interface for AWS STS operations.
 type STSClient interface {
 	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
@@ -23,34 +43,197 @@ type IAMClient interface {
 	SimulatePrincipalPolicy(ctx context.Context, params *iam.SimulatePrincipalPolicyInput, optFns ...func(*iam.Options)) (*iam.SimulatePrincipalPolicyOutput, error)
 }
 
+// SSMClient is an interface for AWS SSM operations.
+type SSMClient interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
 // Config wraps the AWS SDK config and provides additional functionality.
 type Config struct {
 	*aws.Config
 	stsClient STSClient
 	iamClient IAMClient
+	ssmClient SSMClient
+}
+
+// options holds the optional settings applied by CheckAWSCredentials.
+type options struct {
+	roleArn     string
+	sessionName string
+	sessionTags map[string]string
+	stsEndpoint string
+	profile     string
+	instance    bool
+}
+
+// Option configures how CheckAWSCredentials loads AWS credentials.
+type Option func(*options)
+
+// WithAssumeRole makes CheckAWSCredentials assume the given IAM role on top of the default credentials.
+// The session tags are passed to STS so that ABAC policies can match on them.
+func WithAssumeRole(roleArn, sessionName string, sessionTags map[string]string) Option {
+	return func(o *options) {
+		o.roleArn = roleArn
+		o.sessionName = sessionName
+		o.sessionTags = sessionTags
+	}
+}
+
+// WithSTSEndpoint selects the STS endpoint used for identity lookups and role assumption:
+// STSEndpointRegional (the default when empty) or STSEndpointGlobal.
+func WithSTSEndpoint(mode string) Option {
+	return func(o *options) {
+		o.stsEndpoint = mode
+	}
+}
+
+// WithSharedConfigProfile makes LoadCredentials use the named profile of the shared AWS config files.
+func WithSharedConfigProfile(profile string) Option {
+	return func(o *options) {
+		o.profile = profile
+	}
+}
+
+// WithInstanceProfile makes LoadCredentials use the EC2 instance profile credentials from IMDS.
+func WithInstanceProfile() Option {
+	return func(o *options) {
+		o.instance = true
+	}
+}
+
+// NewSTSClient creates an STS client for the given endpoint mode. The regional endpoint is
+// used unless mode is STSEndpointGlobal.
+func NewSTSClient(cfg aws.Config, mode string) *sts.Client {
+	if mode != STSEndpointGlobal {
+		return sts.NewFromConfig(cfg)
+	}
+
+	return sts.NewFromConfig(cfg, func(o *sts.Options) {
+		o.EndpointResolverV2 = globalSTSResolver{sts.NewDefaultEndpointResolverV2()}
+	})
+}
+
+// globalSTSResolver resolves the legacy global STS endpoint. The SDK's endpoint rules only resolve
+// it for the regions of the aws partition it served, signing for us-east-1, and resolve the regional
+// endpoint in other regions and partitions, which have no global endpoint.
+type globalSTSResolver struct {
+	sts.EndpointResolverV2
+}
+
+// ResolveEndpoint resolves the endpoint with the global endpoint enabled.
+func (r globalSTSResolver) ResolveEndpoint(ctx context.Context, params sts.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	params.UseGlobalEndpoint = aws.Bool(true)
+	return r.EndpointResolverV2.ResolveEndpoint(ctx, params)
 }
 
 // CheckAWSCredentials validates and loads AWS credentials for the specified region.
 // It returns a Config instance if successful, or an error if the credentials are invalid.
-func CheckAWSCredentials(region string) (*Config, error) {
+func CheckAWSCredentials(region string, opts ...Option) (*Config, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
+
+	if o.roleArn != "" {
+		cfg.Credentials = assumeRoleCredentials(cfg, o)
+	}
+
 	return &Config{
 		Config:    &cfg,
-		stsClient: sts.NewFromConfig(cfg),
+		stsClient: NewSTSClient(cfg, o.stsEndpoint),
 		iamClient: iam.NewFromConfig(cfg),
+		ssmClient: ssm.NewFromConfig(cfg),
 	}, nil
 }
 
+// InRegion returns a copy of the configuration for another region with the same credentials.
+// The STS and IAM clients are shared, as IAM is global and the caller's identity is the same in every region.
+func (c *Config) InRegion(region string) *Config {
+	cfg := c.Config.Copy()
+	cfg.Region = region
+	return &Config{
+		Config:    &cfg,
+		stsClient: c.stsClient,
+		iamClient: c.iamClient,
+		ssmClient: ssm.NewFromConfig(cfg),
+	}
+}
+
+// LoadCredentials returns a credentials provider for the specified region that is independent of the
+// one loaded by CheckAWSCredentials, e.g. to sign auth tokens with other credentials than discovery uses.
+// It uses the default credential chain unless WithSharedConfigProfile or WithInstanceProfile is given,
+// and assumes the role of WithAssumeRole on top of those credentials.
+func LoadCredentials(region string, opts ...Option) (aws.CredentialsProvider, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	loadOpts := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	if o.profile != "" {
+		loadOpts = append(loadOpts, config.WithSharedConfigProfile(o.profile))
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.TODO(), loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	if o.instance {
+		cfg.Credentials = aws.NewCredentialsCache(ec2rolecreds.New())
+	}
+	if o.roleArn != "" {
+		cfg.Credentials = assumeRoleCredentials(cfg, o)
+	}
+
+	return cfg.Credentials, nil
+}
+
+// assumeRoleCredentials returns a cached credentials provider that assumes the configured role.
+func assumeRoleCredentials(cfg aws.Config, o options) aws.CredentialsProvider {
+	sessionName := o.sessionName
+	if sessionName == "" {
+		sessionName = defaultRoleSessionName
+	}
+
+	provider := stscreds.NewAssumeRoleProvider(NewSTSClient(cfg, o.stsEndpoint), o.roleArn, func(aro *stscreds.AssumeRoleOptions) {
+		aro.RoleSessionName = sessionName
+		aro.Tags = sessionTags(o.sessionTags)
+	})
+
+	return aws.NewCredentialsCache(provider)
+}
+
+// sessionTags converts a tag map into STS session tags, sorted by key for deterministic requests.
+func sessionTags(tags map[string]string) []ststypes.Tag {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]ststypes.Tag, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, ststypes.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(tags[key]),
+		})
+	}
+	return result
+}
+
 // GetCurrentIAMRole retrieves the IAM role ARN of the current AWS identity.
 // It parses the STS caller identity to extract the IAM role information.
 // Returns the IAM role ARN or an error if the operation fails.
 func (c *Config) GetCurrentIAMRole(ctx context.Context) (string, error) {
 	identity, err := c.stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
-		return "", fmt.Errorf("failed to get caller identity: %w", err)
+		return "", WrapError("failed to get caller identity", err)
 	}
 
 	// Regular expression to match and extract components from STS ARN
@@ -66,34 +249,152 @@ func (c *Config) GetCurrentIAMRole(ctx context.Context) (string, error) {
 	return *identity.Arn, nil
 }
 
-// CheckIAMUserAccess verifies if the specified IAM role has permission to connect to the RDS cluster.
-// It uses the IAM policy simulator to check the rds-db:connect permission.
-// Returns an error if the access check fails or if the operation encounters an error.
-func (c *Config) CheckIAMUserAccess(ctx context.Context, iamRole, resourceID, dbUserID string) error {
-	resourceArn := fmt.Sprintf("arn:aws:rds-db:*:*:dbuser:%s/%s", resourceID, dbUserID)
-	fmt.Printf("Checking IAM access for role %s to resource %s\n", iamRole, resourceArn)
+// IdentityName returns the name portion of an IAM role or user ARN, e.g. "alice" for
+// "arn:aws:iam::123456789012:role/alice". Any path prefix is dropped.
+func IdentityName(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
 
+// AccountID returns the account ID of an ARN, e.g. "123456789012" for
+// "arn:aws:iam::123456789012:role/alice". Returns an empty string if the ARN has no account field.
+func AccountID(arn string) string {
+	if parts := strings.Split(arn, ":"); len(parts) >= 5 {
+		return parts[4]
+	}
+	return ""
+}
+
+// DBUserResourceARN returns the rds-db:connect resource ARN for the given resource ID and database user.
+func DBUserResourceARN(resourceID, dbUserID string) string {
+	return fmt.Sprintf("arn:aws:rds-db:*:*:dbuser:%s/%s", resourceID, dbUserID)
+}
+
+// DefaultIAMCheckActions are the actions simulated by CheckIAMUserAccess when none are configured.
+var DefaultIAMCheckActions = []string{"rds-db:connect"}
+
+// ActionDecision is the decision of the IAM policy simulator for a single action.
+type ActionDecision struct {
+	Action   string // The simulated action, e.g. "rds-db:connect".
+	Resource string // The resource ARN the action was simulated on.
+	Decision string // The decision, e.g. "allowed", "implicitDeny" or "explicitDeny".
+}
+
+// Allowed reports whether the action is allowed.
+func (d ActionDecision) Allowed() bool {
+	return d.Decision == string(iamtypes.PolicyEvaluationDecisionTypeAllowed)
+}
+
+// AccessDeniedError is returned by CheckIAMUserAccess when not every simulated action is allowed.
+type AccessDeniedError struct {
+	Decisions []ActionDecision // The decision of every simulated action, allowed or not.
+}
+
+// Error lists the actions that are not allowed with their decisions.
+func (e *AccessDeniedError) Error() string {
+	denied := make([]string, 0, len(e.Decisions))
+	for _, decision := range e.Decisions {
+		if !decision.Allowed() {
+			denied = append(denied, fmt.Sprintf("%s (%s)", decision.Action, decision.Decision))
+		}
+	}
+	return "IAM access denied: " + strings.Join(denied, ", ")
+}
+
+// CheckIAMUserAccess verifies with the IAM policy simulator that the specified IAM role may perform the
+// actions needed to connect to the RDS cluster. rds-db actions are simulated on the DB user's resource
+// ARN and other actions, e.g. rds:DescribeDBClusters, on the cluster ARN. Actions default to
+// DefaultIAMCheckActions. Returns an AccessDeniedError with the decision of each action if any is denied.
+func (c *Config) CheckIAMUserAccess(ctx context.Context, iamRole, clusterArn, resourceID, dbUserID string, actions []string) error {
+	if len(actions) == 0 {
+		actions = DefaultIAMCheckActions
+	}
+
+	dbUserArn := DBUserResourceARN(resourceID, dbUserID)
+	if clusterArn == "" {
+		clusterArn = "*"
+	}
+
+	// The simulator evaluates every action on every resource, so each resource is simulated separately
+	resources := make([]string, 0, 2)
+	resourceActions := make(map[string][]string, 2)
+	for _, action := range actions {
+		resource := clusterArn
+		if strings.HasPrefix(strings.ToLower(action), "rds-db:") {
+			resource = dbUserArn
+		}
+		if _, ok := resourceActions[resource]; !ok {
+			resources = append(resources, resource)
+		}
+		resourceActions[resource] = append(resourceActions[resource], action)
+	}
+
+	decisions := make([]ActionDecision, 0, len(actions))
+	for _, resource := range resources {
+		resourceDecisions, err := c.simulateActions(ctx, iamRole, resource, resourceActions[resource])
+		if err != nil {
+			return err
+		}
+		decisions = append(decisions, resourceDecisions...)
+	}
+
+	for _, decision := range decisions {
+		if !decision.Allowed() {
+			return &AccessDeniedError{Decisions: decisions}
+		}
+	}
+	return nil
+}
+
+// simulateActions simulates the actions of the role on a single resource and returns the decision of
+// each action, in the order of actions.
+func (c *Config) simulateActions(ctx context.Context, iamRole, resource string, actions []string) ([]ActionDecision, error) {
 	input := &iam.SimulatePrincipalPolicyInput{
 		PolicySourceArn: aws.String(iamRole),
-		ActionNames:     []string{"rds-db:connect"},
-		ResourceArns:    []string{resourceArn},
+		ActionNames:     actions,
+		ResourceArns:    []string{resource},
 	}
 
 	output, err := c.iamClient.SimulatePrincipalPolicy(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to simulate IAM policy: %w", err)
+		return nil, WrapError("failed to simulate IAM policy", err)
 	}
 
 	if len(output.EvaluationResults) == 0 {
-		return fmt.Errorf("no evaluation results found")
+		return nil, fmt.Errorf("no evaluation results found")
 	}
 
-	lastResult := output.EvaluationResults[len(output.EvaluationResults)-1]
-	if lastResult.EvalDecision != "allowed" {
-		return fmt.Errorf("IAM access denied: %s", lastResult.EvalDecision)
+	decisions := make([]ActionDecision, 0, len(actions))
+	for _, action := range actions {
+		decision := ActionDecision{Action: action, Resource: resource}
+		for _, result := range output.EvaluationResults {
+			if strings.EqualFold(aws.ToString(result.EvalActionName), action) {
+				decision.Decision = string(result.EvalDecision)
+				break
+			}
+		}
+		if decision.Decision == "" {
+			return nil, fmt.Errorf("no evaluation result found for action %s", action)
+		}
+		decisions = append(decisions, decision)
 	}
+	return decisions, nil
+}
 
-	return nil
+// GetParameter retrieves the decrypted value of an SSM parameter.
+func (c *Config) GetParameter(ctx context.Context, name string) (string, error) {
+	output, err := c.ssmClient.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", WrapError(fmt.Sprintf("failed to get SSM parameter %s", name), err)
+	}
+
+	if output.Parameter == nil || output.Parameter.Value == nil {
+		return "", fmt.Errorf("SSM parameter %s has no value", name)
+	}
+
+	return *output.Parameter.Value, nil
 }
 
 // WithSTSClient sets a custom STS client for testing.
@@ -107,3 +408,9 @@ func (c *Config) WithIAMClient(client IAMClient) *Config {
 	c.iamClient = client
 	return c
 }
+
+// WithSSMClient sets a custom SSM client for testing.
+func (c *Config) WithSSMClient(client SSMClient) *Config {
+	c.ssmClient = client
+	return c
+}
@@ -0,0 +1,85 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIAMClient is an IAMClient that returns fixed evaluation results.
+type fakeIAMClient struct {
+	results []iamtypes.EvaluationResult
+}
+
+func (f *fakeIAMClient) SimulatePrincipalPolicy(_ context.Context, _ *iam.SimulatePrincipalPolicyInput, _ ...func(*iam.Options)) (*iam.SimulatePrincipalPolicyOutput, error) {
+	return &iam.SimulatePrincipalPolicyOutput{EvaluationResults: f.results}, nil
+}
+
+func evaluationResult(action string, decision iamtypes.PolicyEvaluationDecisionType) iamtypes.EvaluationResult {
+	return iamtypes.EvaluationResult{EvalActionName: aws.String(action), EvalDecision: decision}
+}
+
+func TestCheckIAMUserAccessMatchesResultByAction(t *testing.T) {
+	const role = "arn:aws:iam::123456789012:role/app"
+
+	// The result of rds-db:connect is neither the only nor the last one
+	allowed := &Config{}
+	allowed.WithIAMClient(&fakeIAMClient{results: []iamtypes.EvaluationResult{
+		evaluationResult("rds:DescribeDBInstances", iamtypes.PolicyEvaluationDecisionTypeImplicitDeny),
+		evaluationResult("rds-db:connect", iamtypes.PolicyEvaluationDecisionTypeAllowed),
+		evaluationResult("rds:DescribeDBClusters", iamtypes.PolicyEvaluationDecisionTypeExplicitDeny),
+	}})
+	assert.NoError(t, allowed.CheckIAMUserAccess(context.Background(), role, "", "cluster-ABC", "app", nil))
+
+	denied := &Config{}
+	denied.WithIAMClient(&fakeIAMClient{results: []iamtypes.EvaluationResult{
+		evaluationResult("rds:DescribeDBClusters", iamtypes.PolicyEvaluationDecisionTypeAllowed),
+		evaluationResult("rds-db:connect", iamtypes.PolicyEvaluationDecisionTypeImplicitDeny),
+		evaluationResult("rds:DescribeDBInstances", iamtypes.PolicyEvaluationDecisionTypeAllowed),
+	}})
+	err := denied.CheckIAMUserAccess(context.Background(), role, "", "cluster-ABC", "app", nil)
+
+	var accessDenied *AccessDeniedError
+	require.ErrorAs(t, err, &accessDenied)
+	assert.Equal(t, []ActionDecision{{
+		Action:   "rds-db:connect",
+		Resource: "arn:aws:rds-db:*:*:dbuser:cluster-ABC/app",
+		Decision: "implicitDeny",
+	}}, accessDenied.Decisions)
+}
+
+func TestCheckIAMUserAccessWithoutResultForAction(t *testing.T) {
+	cfg := &Config{}
+	cfg.WithIAMClient(&fakeIAMClient{results: []iamtypes.EvaluationResult{
+		evaluationResult("rds:DescribeDBClusters", iamtypes.PolicyEvaluationDecisionTypeAllowed),
+	}})
+
+	err := cfg.CheckIAMUserAccess(context.Background(), "arn:aws:iam::123456789012:role/app", "", "cluster-ABC", "app", nil)
+	assert.ErrorContains(t, err, "no evaluation result found for action rds-db:connect")
+}
+
+func TestGlobalSTSResolver(t *testing.T) {
+	tests := []struct {
+		region string
+		want   string
+	}{
+		{region: "us-east-1", want: "https://sts.amazonaws.com"},
+		{region: "eu-west-1", want: "https://sts.amazonaws.com"},
+		{region: "cn-north-1", want: "https://sts.cn-north-1.amazonaws.com.cn"},
+		{region: "us-gov-west-1", want: "https://sts.us-gov-west-1.amazonaws.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			resolver := globalSTSResolver{sts.NewDefaultEndpointResolverV2()}
+			endpoint, err := resolver.ResolveEndpoint(context.Background(), sts.EndpointParameters{Region: aws.String(tt.region)})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, endpoint.URI.String())
+		})
+	}
+}
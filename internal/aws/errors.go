@@ -0,0 +1,27 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// RequestID extracts the AWS request ID from an API error.
+// Returns an empty string if the error does not carry a request ID.
+func RequestID(err error) string {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.ServiceRequestID()
+	}
+	return ""
+}
+
+// WrapError wraps an AWS API error with the given message.
+// The AWS request ID is included when available so that it can be quoted in AWS support cases.
+func WrapError(msg string, err error) error {
+	if id := RequestID(err); id != "" {
+		return fmt.Errorf("%s (AWS request ID: %s): %w", msg, id, err)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -0,0 +1,59 @@
+// Package certs provides the AWS RDS root CA bundle used to verify server certificates.
+package certs
+
+//go:generate curl -sSfL -o rds-global-bundle.pem https://truststore.pki.rds.amazonaws.com/global/global-bundle.pem
+
+import (
+	"bytes"
+	"crypto/x509"
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"rds-iam-connect/internal/utils"
+)
+
+// bundleFileName is the name of the CA bundle written to the cache directory.
+const bundleFileName = "rds-global-bundle.pem"
+
+// globalBundle is the AWS RDS global root CA bundle. The file is not committed: download it with
+// `go generate ./internal/certs` before building, or the build fails.
+//
+//go:embed rds-global-bundle.pem
+var globalBundle []byte
+
+// BundlePath returns the path of the RDS CA bundle in the cache directory, writing the embedded
+// bundle there on first use. The file is rewritten when it differs from the embedded bundle, so
+// upgrading the binary also refreshes the bundle on disk.
+func BundlePath() (string, error) {
+	if !x509.NewCertPool().AppendCertsFromPEM(globalBundle) {
+		return "", fmt.Errorf("the embedded RDS CA bundle contains no certificates; rebuild after running `go generate ./internal/certs`")
+	}
+
+	cacheDir, err := utils.GetCacheDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(cacheDir, bundleFileName)
+
+	//nolint:gosec // The path is built from the cache directory and a fixed name
+	existing, err := os.ReadFile(path)
+	if err == nil && bytes.Equal(existing, globalBundle) {
+		return path, nil
+	}
+
+	if err := os.WriteFile(path, globalBundle, 0600); err != nil {
+		return "", fmt.Errorf("failed to write RDS CA bundle: %w", err)
+	}
+	return path, nil
+}
+
+// Pool returns a certificate pool of the embedded RDS CA bundle, for verifying server certificates in Go.
+func Pool() (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(globalBundle) {
+		return nil, fmt.Errorf("the embedded RDS CA bundle contains no certificates; rebuild after running `go generate ./internal/certs`")
+	}
+	return pool, nil
+}
@@ -0,0 +1,38 @@
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPool checks that the bundle downloaded with `go generate ./internal/certs` is the AWS RDS global
+// bundle and not an error page or an empty file.
+func TestPool(t *testing.T) {
+	pool, err := Pool()
+	require.NoError(t, err)
+	require.NotNil(t, pool)
+
+	var certs []*x509.Certificate
+	for rest := globalBundle; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		require.NoError(t, err)
+		certs = append(certs, cert)
+	}
+
+	require.NotEmpty(t, certs, "the embedded RDS CA bundle contains no certificates")
+	for _, cert := range certs {
+		assert.True(t, cert.IsCA, "certificate %q is not a CA", cert.Subject)
+	}
+}
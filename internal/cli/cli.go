@@ -30,9 +30,11 @@ func (p *SurveyPrompter) SelectCluster(clusters []rds.Cluster) (rds.Cluster, err
 	clusterMap := make(map[string]rds.Cluster, len(clusters))
 
 	for _, cluster := range clusters {
-		display := fmt.Sprintf("%s (%s:%d)", cluster.Identifier, cluster.Endpoint, cluster.Port)
-		clusterNames = append(clusterNames, display)
-		clusterMap[display] = cluster
+		clusterNames = append(clusterNames, fmt.Sprintf("%s (%s:%d)", cluster.Identifier, cluster.Endpoint, cluster.Port))
+	}
+	clusterNames = UniqueDisplayNames(clusters, clusterNames)
+	for i, display := range clusterNames {
+		clusterMap[display] = clusters[i]
 	}
 
 	var selected string
@@ -47,6 +49,42 @@ func (p *SurveyPrompter) SelectCluster(clusters []rds.Cluster) (rds.Cluster, err
 	return clusterMap[selected], nil
 }
 
+// UniqueDisplayNames makes the display names of the clusters unique, so that a selection maps back to
+// the right cluster when clusters of several accounts or regions are displayed alike. Colliding names
+// get the region of their cluster appended, and names that still collide a running number.
+// names[i] is the display name of clusters[i]; the returned slice is in the same order.
+func UniqueDisplayNames(clusters []rds.Cluster, names []string) []string {
+	unique := append([]string(nil), names...)
+	for _, i := range collisions(unique) {
+		if region := clusters[i].Region; region != "" {
+			unique[i] += " [" + region + "]"
+		}
+	}
+
+	seen := make(map[string]int, len(unique))
+	for _, i := range collisions(unique) {
+		seen[unique[i]]++
+		unique[i] += fmt.Sprintf(" #%d", seen[unique[i]])
+	}
+	return unique
+}
+
+// collisions returns the indexes of the names that occur more than once, in order.
+func collisions(names []string) []int {
+	counts := make(map[string]int, len(names))
+	for _, name := range names {
+		counts[name]++
+	}
+
+	indexes := make([]int, 0)
+	for i, name := range names {
+		if counts[name] > 1 {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
 // SelectUser presents an interactive prompt for selecting an IAM user.
 // Returns the selected user or an error if the selection fails.
 func (p *SurveyPrompter) SelectUser(users []string) (string, error) {
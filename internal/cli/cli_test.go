@@ -67,3 +67,25 @@ func TestSelectUser(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "test-user", selected)
 }
+
+func TestUniqueDisplayNames(t *testing.T) {
+	clusters := []rds.Cluster{
+		{Identifier: "orders", Region: "us-west-2"},
+		{Identifier: "orders", Region: "eu-west-1"},
+		{Identifier: "billing", Region: "us-west-2"},
+		{Identifier: "users", Region: "us-west-2"},
+		{Identifier: "users", Region: "us-west-2"},
+	}
+	names := []string{"orders (db:3306)", "orders (db:3306)", "billing (db:3306)", "users (db:3306)", "users (db:3306)"}
+
+	unique := UniqueDisplayNames(clusters, names)
+
+	assert.Equal(t, []string{
+		"orders (db:3306) [us-west-2]",
+		"orders (db:3306) [eu-west-1]",
+		"billing (db:3306)",
+		"users (db:3306) [us-west-2] #1",
+		"users (db:3306) [us-west-2] #2",
+	}, unique)
+	assert.Equal(t, "orders (db:3306)", names[0], "the given names are not modified")
+}
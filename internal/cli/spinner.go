@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// spinnerInterval is the delay between spinner frames.
+const spinnerInterval = 100 * time.Millisecond
+
+// spinnerFrames are the characters cycled through by the spinner.
+var spinnerFrames = []rune{'|', '/', '-', '\\'}
+
+// Spinner displays a progress indicator with the elapsed time and the number of items scanned.
+// A disabled spinner does nothing, so callers do not need to check whether output is a terminal.
+type Spinner struct {
+	out     io.Writer
+	message string
+	enabled bool
+	scanned atomic.Int64
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewSpinner creates a spinner that writes to out. The spinner is only shown when
+// enabled is true and out is a terminal.
+func NewSpinner(out *os.File, message string, enabled bool) *Spinner {
+	return &Spinner{
+		out:     out,
+		message: message,
+		enabled: enabled && isatty.IsTerminal(out.Fd()),
+	}
+}
+
+// SetScanned updates the number of items scanned so far. It is safe for concurrent use.
+func (s *Spinner) SetScanned(n int) {
+	s.scanned.Store(int64(n))
+}
+
+// Start begins rendering the spinner in the background.
+func (s *Spinner) Start() {
+	if !s.enabled {
+		return
+	}
+
+	s.done = make(chan struct{})
+	start := time.Now()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(spinnerInterval)
+		defer ticker.Stop()
+
+		for frame := 0; ; frame++ {
+			fmt.Fprintf(s.out, "\r%c %s... %s, %d scanned\033[K",
+				spinnerFrames[frame%len(spinnerFrames)], s.message,
+				time.Since(start).Truncate(time.Second), s.scanned.Load())
+
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the spinner and clears its line.
+func (s *Spinner) Stop() {
+	if !s.enabled || s.done == nil {
+		return
+	}
+
+	close(s.done)
+	s.wg.Wait()
+	s.done = nil
+	fmt.Fprint(s.out, "\r\033[K")
+}
@@ -0,0 +1,132 @@
+// Package contexts stores named connections, similar to kubectl contexts, so that other tooling can
+// look up the endpoint, user and environment of a connection by name.
+package contexts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileMode is the permission mode of the contexts file. Use 0600 so only the owner can read it.
+const fileMode os.FileMode = 0600
+
+// Context is a named connection. It holds no auth token, which expires after 15 minutes and is
+// generated for every connection.
+type Context struct {
+	Name        string `yaml:"name" json:"name"`
+	Environment string `yaml:"environment" json:"environment"`
+	Cluster     string `yaml:"cluster" json:"cluster"`
+	Account     string `yaml:"account,omitempty" json:"account,omitempty"`
+	User        string `yaml:"user" json:"user"`
+	Endpoint    string `yaml:"endpoint" json:"endpoint"`
+	Port        int32  `yaml:"port" json:"port"`
+	Region      string `yaml:"region,omitempty" json:"region,omitempty"`
+	Engine      string `yaml:"engine,omitempty" json:"engine,omitempty"`
+}
+
+// file is the layout of the contexts file.
+type file struct {
+	Contexts []Context `yaml:"contexts"`
+}
+
+// Store holds the contexts read from a file.
+type Store struct {
+	path     string
+	contexts []Context
+}
+
+// Load reads the contexts from the file at path. A missing file has no contexts.
+func Load(path string) (*Store, error) {
+	store := &Store{path: filepath.Clean(path)}
+
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read connection contexts: %w", err)
+	}
+
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse connection contexts %s: %w", store.path, err)
+	}
+	store.contexts = f.Contexts
+	return store, nil
+}
+
+// Path returns the path of the contexts file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// List returns the contexts sorted by name.
+func (s *Store) List() []Context {
+	contexts := append([]Context(nil), s.contexts...)
+	sort.Slice(contexts, func(i, j int) bool { return contexts[i].Name < contexts[j].Name })
+	return contexts
+}
+
+// Set adds the context, replacing a context of the same name, and writes the file.
+func (s *Store) Set(ctx Context) error {
+	if ctx.Name == "" {
+		return fmt.Errorf("connection context name must not be empty")
+	}
+
+	for i := range s.contexts {
+		if s.contexts[i].Name == ctx.Name {
+			s.contexts[i] = ctx
+			return s.save()
+		}
+	}
+	s.contexts = append(s.contexts, ctx)
+	return s.save()
+}
+
+// Remove removes the contexts with the given names and writes the file. If any of them does not exist,
+// none is removed.
+func (s *Store) Remove(names ...string) error {
+	remove := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !s.has(name) {
+			return fmt.Errorf("connection context %q not found in %s", name, s.path)
+		}
+		remove[name] = true
+	}
+
+	kept := s.contexts[:0]
+	for _, ctx := range s.contexts {
+		if !remove[ctx.Name] {
+			kept = append(kept, ctx)
+		}
+	}
+	s.contexts = kept
+	return s.save()
+}
+
+// has reports whether a context with the given name exists.
+func (s *Store) has(name string) bool {
+	for _, ctx := range s.contexts {
+		if ctx.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// save writes the contexts to the file, sorted by name.
+func (s *Store) save() error {
+	data, err := yaml.Marshal(file{Contexts: s.List()})
+	if err != nil {
+		return fmt.Errorf("failed to encode connection contexts: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, fileMode); err != nil {
+		return fmt.Errorf("failed to write connection contexts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package contexts
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "connections.yaml")
+
+	store, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, store.List())
+
+	orders := Context{Name: "orders", Environment: "prod", Cluster: "orders", Account: "123456789012", User: "app",
+		Endpoint: "orders.cluster-x.us-east-1.rds.amazonaws.com", Port: 3306, Region: "us-east-1"}
+	billing := Context{Name: "billing", Environment: "prod", Cluster: "billing", User: "app",
+		Endpoint: "billing.cluster-x.us-east-1.rds.amazonaws.com", Port: 5432}
+	require.NoError(t, store.Set(orders))
+	require.NoError(t, store.Set(billing))
+
+	// Setting an existing name replaces the context
+	orders.User = "admin"
+	require.NoError(t, store.Set(orders))
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Context{billing, orders}, loaded.List())
+
+	// Nothing is removed if any name does not exist
+	assert.Error(t, loaded.Remove("orders", "missing"))
+	assert.Len(t, loaded.List(), 2)
+
+	require.NoError(t, loaded.Remove("orders"))
+	reloaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Context{billing}, reloaded.List())
+}
+
+func TestSetRequiresName(t *testing.T) {
+	store, err := Load(filepath.Join(t.TempDir(), "connections.yaml"))
+	require.NoError(t, err)
+	assert.Error(t, store.Set(Context{Cluster: "orders"}))
+}
@@ -11,7 +11,8 @@ import (
 // Logger provides a simple logging interface with debug capabilities.
 type Logger struct {
 	*log.Logger
-	debug bool
+	debug   bool
+	verbose bool
 }
 
 // New creates a new Logger instance.
@@ -28,6 +29,21 @@ func New(debug bool) *Logger {
 	}
 }
 
+// SetVerbose enables the detailed messages logged with Verbosef. They are only written in debug mode.
+func (l *Logger) SetVerbose(verbose bool) {
+	l.verbose = verbose
+}
+
+// Verbosef logs a formatted message if both debug mode and verbose logging are enabled.
+// It is meant for repetitive details, such as per-item messages in loops.
+func (l *Logger) Verbosef(format string, v ...interface{}) {
+	if l.debug && l.verbose {
+		if err := l.Output(2, fmt.Sprintf(format, v...)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing debug log: %v\n", err)
+		}
+	}
+}
+
 // Debug logs a debug message if debug mode is enabled.
 func (l *Logger) Debug(v ...interface{}) {
 	if l.debug {
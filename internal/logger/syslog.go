@@ -0,0 +1,50 @@
+package logger
+
+// DefaultSyslogTag is the tag of syslog messages when none is configured.
+const DefaultSyslogTag = "rds-iam-connect"
+
+// DefaultSyslogFacility is the syslog facility used when none is configured.
+const DefaultSyslogFacility = "user"
+
+// syslogWriter writes messages to the local syslog at a given severity.
+type syslogWriter interface {
+	Info(msg string) error
+	Warning(msg string) error
+	Err(msg string) error
+	Close() error
+}
+
+// Syslog writes the tool's warnings, errors and connections to the local syslog.
+// A nil Syslog discards everything, so callers need not check whether syslog is enabled.
+type Syslog struct {
+	w syslogWriter
+}
+
+// Info writes an informational message, such as a connection.
+func (s *Syslog) Info(msg string) {
+	if s != nil {
+		_ = s.w.Info(msg)
+	}
+}
+
+// Warning writes a warning.
+func (s *Syslog) Warning(msg string) {
+	if s != nil {
+		_ = s.w.Warning(msg)
+	}
+}
+
+// Error writes an error.
+func (s *Syslog) Error(msg string) {
+	if s != nil {
+		_ = s.w.Err(msg)
+	}
+}
+
+// Close closes the connection to the syslog daemon.
+func (s *Syslog) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.w.Close()
+}
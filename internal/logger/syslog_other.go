@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package logger
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// NewSyslog fails on platforms without syslog.
+func NewSyslog(_, _ string) (*Syslog, error) {
+	return nil, fmt.Errorf("syslog logging is not supported on %s", runtime.GOOS)
+}
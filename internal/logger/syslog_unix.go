@@ -0,0 +1,45 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+// syslogFacilities maps the configurable facility names to syslog facilities.
+var syslogFacilities = map[string]syslog.Priority{
+	"user":   syslog.LOG_USER,
+	"daemon": syslog.LOG_DAEMON,
+	"auth":   syslog.LOG_AUTH,
+	"local0": syslog.LOG_LOCAL0,
+	"local1": syslog.LOG_LOCAL1,
+	"local2": syslog.LOG_LOCAL2,
+	"local3": syslog.LOG_LOCAL3,
+	"local4": syslog.LOG_LOCAL4,
+	"local5": syslog.LOG_LOCAL5,
+	"local6": syslog.LOG_LOCAL6,
+	"local7": syslog.LOG_LOCAL7,
+}
+
+// NewSyslog connects to the local syslog daemon with the given tag and facility, e.g. "local0".
+// Empty values use DefaultSyslogTag and DefaultSyslogFacility.
+func NewSyslog(tag, facility string) (*Syslog, error) {
+	if tag == "" {
+		tag = DefaultSyslogTag
+	}
+	if facility == "" {
+		facility = DefaultSyslogFacility
+	}
+
+	priority, ok := syslogFacilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q: must be user, daemon, auth or local0 to local7", facility)
+	}
+
+	w, err := syslog.New(priority|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return &Syslog{w: w}, nil
+}
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// maxPendingWarnings bounds the warnings kept for the syslog before it is set up.
+const maxPendingWarnings = 20
+
+// warnings routes the warnings of the tool to stderr and the syslog.
+var warnings = struct {
+	sync.Mutex
+	out     io.Writer
+	syslog  *Syslog
+	set     bool     // Whether SetSyslog was called.
+	pending []string // Warnings printed before SetSyslog was called.
+}{out: os.Stderr}
+
+// SetSyslog makes Warnf write warnings to the syslog as well. Warnings printed before, such as those
+// about the configuration the syslog settings are read from, are written to it right away. A nil
+// Syslog discards them.
+func SetSyslog(s *Syslog) {
+	warnings.Lock()
+	defer warnings.Unlock()
+
+	warnings.syslog = s
+	warnings.set = true
+	for _, msg := range warnings.pending {
+		s.Warning(msg)
+	}
+	warnings.pending = nil
+}
+
+// Warnf prints a warning to stderr and writes it to the syslog set with SetSyslog. All warnings of the
+// tool go through it, so that none is missing from the syslog.
+func Warnf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+
+	warnings.Lock()
+	defer warnings.Unlock()
+
+	fmt.Fprintf(warnings.out, "Warning: %s\n", msg)
+	if !warnings.set {
+		if len(warnings.pending) < maxPendingWarnings {
+			warnings.pending = append(warnings.pending, msg)
+		}
+		return
+	}
+	warnings.syslog.Warning(msg)
+}
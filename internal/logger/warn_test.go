@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSyslog is a syslogWriter that records the messages written at each severity.
+type fakeSyslog struct {
+	info, warning, err []string
+}
+
+func (f *fakeSyslog) Info(msg string) error    { f.info = append(f.info, msg); return nil }
+func (f *fakeSyslog) Warning(msg string) error { f.warning = append(f.warning, msg); return nil }
+func (f *fakeSyslog) Err(msg string) error     { f.err = append(f.err, msg); return nil }
+func (f *fakeSyslog) Close() error             { return nil }
+
+func TestWarnf(t *testing.T) {
+	var out bytes.Buffer
+	warnings.out = &out
+	t.Cleanup(func() {
+		warnings.out, warnings.syslog, warnings.set, warnings.pending = os.Stderr, nil, false, nil
+	})
+
+	// Warnings printed before the syslog is set up are written to it once it is set
+	Warnf("config file %s is world-readable", "config.yaml")
+	fake := &fakeSyslog{}
+	SetSyslog(&Syslog{w: fake})
+	Warnf("cache is %d minutes in the future", 5)
+
+	assert.Equal(t, "Warning: config file config.yaml is world-readable\nWarning: cache is 5 minutes in the future\n", out.String())
+	assert.Equal(t, []string{"config file config.yaml is world-readable", "cache is 5 minutes in the future"}, fake.warning)
+	assert.Empty(t, fake.info)
+	assert.Empty(t, fake.err)
+}
+
+func TestWarnfWithoutSyslog(t *testing.T) {
+	var out bytes.Buffer
+	warnings.out = &out
+	t.Cleanup(func() {
+		warnings.out, warnings.syslog, warnings.set, warnings.pending = os.Stderr, nil, false, nil
+	})
+
+	SetSyslog(nil)
+	Warnf("no clusters found")
+
+	assert.Equal(t, "Warning: no clusters found\n", out.String())
+	assert.Empty(t, warnings.pending)
+}
+
+func TestSyslogNil(t *testing.T) {
+	var s *Syslog
+	s.Info("connection")
+	s.Warning("warning")
+	s.Error("error")
+	assert.NoError(t, s.Close())
+}
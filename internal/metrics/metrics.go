@@ -0,0 +1,280 @@
+// Package metrics collects counters and timings of a run of the RDS IAM Connect tool and sends them
+// to a statsd server or a Prometheus pushgateway when the run ends.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Prefix is prepended to the name of every metric.
+const Prefix = "rds_iam_connect_"
+
+// pushgatewayJob is the job label the metrics are pushed under.
+const pushgatewayJob = "rds_iam_connect"
+
+// unknownHost is the hostname of the instance label when it cannot be determined.
+const unknownHost = "unknown"
+
+// timingBuckets are the upper bounds, in seconds, of the histogram buckets of timings in the pushgateway.
+var timingBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Labels are the dimensions of a metric, e.g. the environment and cluster of a connection.
+type Labels map[string]string
+
+// sample is a counter increment or a timing recorded during the run.
+type sample struct {
+	name   string
+	labels Labels
+	timing bool
+	value  float64 // The increment of counters, or the duration of timings in seconds.
+}
+
+// Recorder collects metrics during a run. A nil Recorder discards everything, so callers need not
+// check whether metrics are configured.
+type Recorder struct {
+	statsdAddr  string
+	pushgateway string
+	instance    string // The instance grouping label of pushes.
+	client      *http.Client
+
+	mu      sync.Mutex
+	samples []sample
+}
+
+// New returns a Recorder that sends to the statsd server at statsdAddr (host:port) and pushes to the
+// pushgateway at pushgatewayURL. Either may be empty. Returns nil if both are empty.
+func New(statsdAddr, pushgatewayURL string) *Recorder {
+	if statsdAddr == "" && pushgatewayURL == "" {
+		return nil
+	}
+	return &Recorder{
+		statsdAddr:  statsdAddr,
+		pushgateway: strings.TrimSuffix(pushgatewayURL, "/"),
+		instance:    instance(),
+		client:      &http.Client{},
+	}
+}
+
+// instance returns the user and host running the tool, e.g. "alice@laptop", or only the host if the
+// user is unknown.
+func instance() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = unknownHost
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username + "@" + host
+	}
+	return host
+}
+
+// Count increments the counter with the given name and labels.
+func (r *Recorder) Count(name string, labels Labels) {
+	r.add(sample{name: name, labels: labels, value: 1})
+}
+
+// Timing records a duration of the timing with the given name and labels.
+func (r *Recorder) Timing(name string, d time.Duration, labels Labels) {
+	r.add(sample{name: name, labels: labels, timing: true, value: d.Seconds()})
+}
+
+// add records a sample.
+func (r *Recorder) add(s sample) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples = append(r.samples, s)
+}
+
+// Flush sends the metrics recorded so far to the configured backends. Errors of both backends are returned.
+func (r *Recorder) Flush(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	samples := r.samples
+	r.samples = nil
+	r.mu.Unlock()
+
+	if len(samples) == 0 {
+		return nil
+	}
+
+	var errs []string
+	if r.statsdAddr != "" {
+		if err := r.sendStatsd(ctx, samples); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if r.pushgateway != "" {
+		if err := r.push(ctx, samples); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to send metrics: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// sendStatsd sends the samples to the statsd server over UDP, with the labels as DogStatsD tags.
+func (r *Recorder) sendStatsd(ctx context.Context, samples []sample) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", r.statsdAddr)
+	if err != nil {
+		return fmt.Errorf("statsd: %w", err)
+	}
+	defer conn.Close()
+
+	for _, s := range samples {
+		line := fmt.Sprintf("%s%s:%g|c", Prefix, s.name, s.value)
+		if s.timing {
+			line = fmt.Sprintf("%s%s:%d|ms", Prefix, s.name, int64(s.value*1000))
+		}
+		if tags := statsdTags(s.labels); tags != "" {
+			line += "|#" + tags
+		}
+		if _, err := conn.Write([]byte(line)); err != nil {
+			return fmt.Errorf("statsd: %w", err)
+		}
+	}
+	return nil
+}
+
+// statsdTags formats the labels as sorted DogStatsD tags.
+func statsdTags(labels Labels) string {
+	tags := make([]string, 0, len(labels))
+	for _, key := range sortedKeys(labels) {
+		tags = append(tags, key+":"+strings.NewReplacer(",", "_", "|", "_", "#", "_").Replace(labels[key]))
+	}
+	return strings.Join(tags, ",")
+}
+
+// push replaces the metrics of the job in the pushgateway with the samples, counters as totals and
+// timings as histograms. The metrics are grouped by the instance label, the user and host running the
+// tool, so that a push only replaces the metrics of the previous run of the same user on the same host.
+func (r *Recorder) push(ctx context.Context, samples []sample) error {
+	endpoint := r.pushgateway + "/metrics/job/" + url.PathEscape(pushgatewayJob) + "/instance/" + url.PathEscape(r.instance)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewBufferString(exposition(samples)))
+	if err != nil {
+		return fmt.Errorf("pushgateway: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("pushgateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("pushgateway: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// series is the aggregate of the samples of one metric with the same labels.
+type series struct {
+	labels  Labels
+	count   float64   // The counter total, or the number of timings.
+	sum     float64   // The sum of the timings in seconds.
+	buckets []float64 // Cumulative counts per timingBuckets entry.
+}
+
+// exposition formats the samples in the Prometheus text exposition format.
+func exposition(samples []sample) string {
+	type metric struct {
+		timing bool
+		series map[string]*series
+	}
+	metrics := make(map[string]*metric)
+	for _, s := range samples {
+		m, ok := metrics[s.name]
+		if !ok {
+			m = &metric{timing: s.timing, series: make(map[string]*series)}
+			metrics[s.name] = m
+		}
+		key := promLabels(s.labels, "")
+		ser, ok := m.series[key]
+		if !ok {
+			ser = &series{labels: s.labels, buckets: make([]float64, len(timingBuckets))}
+			m.series[key] = ser
+		}
+		if !s.timing {
+			ser.count += s.value
+			continue
+		}
+		ser.count++
+		ser.sum += s.value
+		for i, bound := range timingBuckets {
+			if s.value <= bound {
+				ser.buckets[i]++
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, name := range sortedKeys(metrics) {
+		m := metrics[name]
+		full := Prefix + name
+		if !m.timing {
+			fmt.Fprintf(&b, "# TYPE %s_total counter\n", full)
+			for _, key := range sortedKeys(m.series) {
+				fmt.Fprintf(&b, "%s_total%s %g\n", full, key, m.series[key].count)
+			}
+			continue
+		}
+
+		full += "_seconds"
+		fmt.Fprintf(&b, "# TYPE %s histogram\n", full)
+		for _, key := range sortedKeys(m.series) {
+			ser := m.series[key]
+			for i, bound := range timingBuckets {
+				fmt.Fprintf(&b, "%s_bucket%s %g\n", full, promLabels(ser.labels, fmt.Sprintf("%g", bound)), ser.buckets[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %g\n", full, promLabels(ser.labels, "+Inf"), ser.count)
+			fmt.Fprintf(&b, "%s_sum%s %g\n", full, key, ser.sum)
+			fmt.Fprintf(&b, "%s_count%s %g\n", full, key, ser.count)
+		}
+	}
+	return b.String()
+}
+
+// promLabels formats the labels, plus the le label of histogram buckets if set, as sorted Prometheus labels.
+func promLabels(labels Labels, le string) string {
+	pairs := make([]string, 0, len(labels)+1)
+	for _, key := range sortedKeys(labels) {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", key, labels[key]))
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf("le=%q", le))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// sortedKeys returns the keys of the map in order, so the output is stable.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsdTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels Labels
+		want   string
+	}{
+		{name: "no labels", want: ""},
+		{name: "sorted", labels: Labels{"user": "app", "env": "prod"}, want: "env:prod,user:app"},
+		{name: "separators replaced", labels: Labels{"cluster": "a,b|c#d"}, want: "cluster:a_b_c_d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, statsdTags(tt.labels))
+		})
+	}
+}
+
+func TestExposition(t *testing.T) {
+	samples := []sample{
+		{name: "connections", labels: Labels{"env": "prod", "user": "app"}, value: 1},
+		{name: "connections", labels: Labels{"user": "app", "env": "prod"}, value: 1},
+		{name: "connections", labels: Labels{"env": "staging", "user": "app"}, value: 1},
+		{name: "discovery", labels: Labels{"source": "aws"}, timing: true, value: 0.3},
+		{name: "discovery", labels: Labels{"source": "aws"}, timing: true, value: 40},
+	}
+
+	want := `# TYPE rds_iam_connect_connections_total counter
+rds_iam_connect_connections_total{env="prod",user="app"} 2
+rds_iam_connect_connections_total{env="staging",user="app"} 1
+# TYPE rds_iam_connect_discovery_seconds histogram
+rds_iam_connect_discovery_seconds_bucket{source="aws",le="0.1"} 0
+rds_iam_connect_discovery_seconds_bucket{source="aws",le="0.25"} 0
+rds_iam_connect_discovery_seconds_bucket{source="aws",le="0.5"} 1
+rds_iam_connect_discovery_seconds_bucket{source="aws",le="1"} 1
+rds_iam_connect_discovery_seconds_bucket{source="aws",le="2.5"} 1
+rds_iam_connect_discovery_seconds_bucket{source="aws",le="5"} 1
+rds_iam_connect_discovery_seconds_bucket{source="aws",le="10"} 1
+rds_iam_connect_discovery_seconds_bucket{source="aws",le="30"} 1
+rds_iam_connect_discovery_seconds_bucket{source="aws",le="60"} 2
+rds_iam_connect_discovery_seconds_bucket{source="aws",le="+Inf"} 2
+rds_iam_connect_discovery_seconds_sum{source="aws"} 40.3
+rds_iam_connect_discovery_seconds_count{source="aws"} 2
+`
+	assert.Equal(t, want, exposition(samples))
+}
+
+func TestPushGroupsByInstance(t *testing.T) {
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.EscapedPath(), string(data)
+	}))
+	defer server.Close()
+
+	recorder := New("", server.URL+"/")
+	require.NotNil(t, recorder)
+	recorder.instance = "alice@laptop"
+	recorder.Count("connections", Labels{"env": "prod"})
+	recorder.Timing("discovery", 2*time.Second, nil)
+
+	require.NoError(t, recorder.Flush(context.Background()))
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/metrics/job/rds_iam_connect/instance/alice@laptop", path)
+	assert.Contains(t, body, `rds_iam_connect_connections_total{env="prod"} 1`)
+	assert.Contains(t, body, "rds_iam_connect_discovery_seconds_count 1")
+}
+
+func TestNilRecorder(t *testing.T) {
+	var recorder *Recorder
+	assert.Nil(t, New("", ""))
+	recorder.Count("connections", nil)
+	assert.NoError(t, recorder.Flush(context.Background()))
+}
+
+func TestInstance(t *testing.T) {
+	assert.NotEmpty(t, instance())
+}
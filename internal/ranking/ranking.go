@@ -0,0 +1,110 @@
+// Package ranking keeps a score per cluster that combines how often and how recently it was chosen,
+// so that selection prompts can list the clusters a user keeps coming back to first.
+package ranking
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// fileMode is the permission mode of the ranking file. Use 0600 so only the owner can read it.
+const fileMode os.FileMode = 0600
+
+// halfLife is the time after which the score of a choice has decayed to half, so that clusters that
+// are no longer used sink back.
+const halfLife = 7 * 24 * time.Hour
+
+// maxEntries bounds the number of clusters kept in the file; those with the lowest scores are dropped.
+const maxEntries = 100
+
+// entry is the score of a single cluster as of LastUsed.
+type entry struct {
+	Score    float64   `json:"score"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+// Store holds the scores of the clusters. A nil Store ranks nothing and records nothing, so callers
+// do not need to check whether ranking is enabled.
+type Store struct {
+	path    string
+	entries map[string]entry
+}
+
+// Load reads the scores from the file at path. A missing file has no scores.
+func Load(path string) (*Store, error) {
+	store := &Store{path: filepath.Clean(path), entries: make(map[string]entry)}
+
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read cluster ranking: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster ranking: %w", err)
+	}
+	return store, nil
+}
+
+// Score returns the current score of the cluster with the given key: each choice adds 1, which halves
+// every week since. Clusters that were never chosen score 0.
+func (s *Store) Score(key string) float64 {
+	if s == nil {
+		return 0
+	}
+	return s.entries[key].decayed(time.Now())
+}
+
+// Record adds a choice of the cluster with the given key and writes the scores to the file.
+func (s *Store) Record(key string) error {
+	if s == nil {
+		return nil
+	}
+
+	now := time.Now()
+	s.entries[key] = entry{Score: s.entries[key].decayed(now) + 1, LastUsed: now.UTC()}
+	s.trim(now)
+
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cluster ranking: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, fileMode); err != nil {
+		return fmt.Errorf("failed to write cluster ranking: %w", err)
+	}
+	return nil
+}
+
+// trim drops the clusters with the lowest scores beyond maxEntries.
+func (s *Store) trim(now time.Time) {
+	if len(s.entries) <= maxEntries {
+		return
+	}
+
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return s.entries[keys[i]].decayed(now) > s.entries[keys[j]].decayed(now)
+	})
+	for _, key := range keys[maxEntries:] {
+		delete(s.entries, key)
+	}
+}
+
+// decayed returns the score of the entry at the given time.
+func (e entry) decayed(now time.Time) float64 {
+	if e.Score == 0 {
+		return 0
+	}
+	elapsed := max(now.Sub(e.LastUsed), 0)
+	return e.Score * math.Pow(0.5, float64(elapsed)/float64(halfLife))
+}
@@ -0,0 +1,76 @@
+package ranking
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecayed(t *testing.T) {
+	now := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		entry entry
+		want  float64
+	}{
+		{name: "never chosen", entry: entry{}, want: 0},
+		{name: "just chosen", entry: entry{Score: 1, LastUsed: now}, want: 1},
+		{name: "one half-life ago", entry: entry{Score: 2, LastUsed: now.Add(-halfLife)}, want: 1},
+		{name: "two half-lives ago", entry: entry{Score: 1, LastUsed: now.Add(-2 * halfLife)}, want: 0.25},
+		{name: "in the future", entry: entry{Score: 1, LastUsed: now.Add(time.Hour)}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, tt.entry.decayed(now), 1e-9)
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	now := time.Now()
+	store := &Store{entries: make(map[string]entry)}
+	for i := range maxEntries + 5 {
+		store.entries[fmt.Sprintf("cluster-%03d", i)] = entry{Score: float64(i + 1), LastUsed: now}
+	}
+
+	store.trim(now)
+
+	assert.Len(t, store.entries, maxEntries)
+	for i := range 5 {
+		assert.NotContains(t, store.entries, fmt.Sprintf("cluster-%03d", i), "lowest scores are dropped")
+	}
+	assert.Contains(t, store.entries, fmt.Sprintf("cluster-%03d", maxEntries+4))
+}
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cluster-ranking.json")
+
+	store, err := Load(path)
+	require.NoError(t, err)
+	assert.Zero(t, store.Score("orders"))
+
+	require.NoError(t, store.Record("orders"))
+	require.NoError(t, store.Record("orders"))
+	require.NoError(t, store.Record("billing"))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, fileMode, info.Mode().Perm())
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.InDelta(t, 2, loaded.Score("orders"), 0.01)
+	assert.InDelta(t, 1, loaded.Score("billing"), 0.01)
+}
+
+func TestNilStore(t *testing.T) {
+	var store *Store
+	assert.Zero(t, store.Score("orders"))
+	assert.NoError(t, store.Record("orders"))
+}
@@ -1,28 +1,140 @@
 package rds
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/rds/auth"
+
+	awsutil "rds-iam-connect/internal/aws"
 )
 
+// TokenLifetime is how long an IAM authentication token is valid after it is generated.
+// The SDK does not report the expiry, so it is fixed at the 15 minutes documented by AWS.
+const TokenLifetime = 15 * time.Minute
+
+// externalTokenTimeout bounds how long an external token command may run.
+const externalTokenTimeout = 30 * time.Second
+
+// tokenOptions holds the optional settings applied by GenerateAuthToken.
+type tokenOptions struct {
+	command     []string
+	credentials aws.CredentialsProvider
+}
+
+// TokenOption configures how GenerateAuthToken obtains a token.
+type TokenOption func(*tokenOptions)
+
+// WithExternalCommand makes GenerateAuthToken run the given command instead of signing the token
+// with the SDK. The command receives the connection details in the RDS_ENDPOINT, RDS_PORT, RDS_USER
+// and RDS_REGION environment variables and must print the token to stdout. An empty command is ignored.
+func WithExternalCommand(command []string) TokenOption {
+	return func(o *tokenOptions) {
+		o.command = command
+	}
+}
+
+// WithCredentials makes GenerateAuthToken sign the token with the given credentials instead of
+// the credentials of the AWS config. A nil provider is ignored.
+func WithCredentials(credentials aws.CredentialsProvider) TokenOption {
+	return func(o *tokenOptions) {
+		o.credentials = credentials
+	}
+}
+
 // GenerateAuthToken generates an authentication token for connecting to an RDS cluster.
-func GenerateAuthToken(cfg aws.Config, cluster Cluster, user string, logger *log.Logger) (string, error) {
+func GenerateAuthToken(ctx context.Context, cfg aws.Config, cluster Cluster, user string, logger *log.Logger, opts ...TokenOption) (string, error) {
 	if user == "" {
 		return "", fmt.Errorf("user cannot be empty")
 	}
 
+	var o tokenOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if len(o.command) > 0 {
+		logger.Printf("running external token command %s for endpoint: %s:%d, user: %s",
+			o.command[0], cluster.Endpoint, cluster.Port, user)
+		token, err := externalAuthToken(ctx, o.command, cluster, cfg.Region, user)
+		if err != nil {
+			return "", err
+		}
+		logTokenGenerated(logger, token, cfg.Region)
+		return token, nil
+	}
+
 	logger.Printf("generating auth token for endpoint: %s:%d, user: %s",
 		cluster.Endpoint, cluster.Port, user)
 
-	return auth.BuildAuthToken(
-		context.Background(),
+	credentials := cfg.Credentials
+	if o.credentials != nil {
+		credentials = o.credentials
+	}
+
+	token, err := auth.BuildAuthToken(
+		ctx,
 		fmt.Sprintf("%s:%d", cluster.Endpoint, cluster.Port),
 		cfg.Region,
 		user,
-		cfg.Credentials,
+		credentials,
 	)
+	if err != nil {
+		return "", awsutil.WrapError("failed to build auth token", err)
+	}
+
+	logTokenGenerated(logger, token, cfg.Region)
+	return token, nil
+}
+
+// logTokenGenerated logs that a token was produced, with its length and signing region.
+// The token itself is a credential and must never be logged.
+func logTokenGenerated(logger *log.Logger, token, region string) {
+	logger.Printf("generated auth token of %d characters for region %s", len(token), region)
+}
+
+// externalAuthToken runs the external token command and returns the token it prints.
+func externalAuthToken(ctx context.Context, command []string, cluster Cluster, region, user string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, externalTokenTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	//nolint:gosec // The command comes from the user's configuration
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Env = append(os.Environ(),
+		"RDS_ENDPOINT="+cluster.Endpoint,
+		"RDS_PORT="+strconv.Itoa(int(cluster.Port)),
+		"RDS_USER="+user,
+		"RDS_REGION="+region,
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("external token command timed out after %s", externalTokenTimeout)
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return "", fmt.Errorf("external token command failed: %w: %s", err, detail)
+		}
+		return "", fmt.Errorf("external token command failed: %w", err)
+	}
+
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", fmt.Errorf("external token command printed no token")
+	}
+	if strings.ContainsAny(token, "\n\r") {
+		return "", fmt.Errorf("external token command printed more than one line")
+	}
+	return token, nil
 }
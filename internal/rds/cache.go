@@ -3,25 +3,42 @@
 package rds
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
+	"rds-iam-connect/internal/logger"
 	"rds-iam-connect/internal/utils"
 )
 
 // Constants for cache operations.
 const (
 	// 0600 is more secure as it only allows the owner to read/write.
-	cacheFileMode = 0600
+	cacheFileMode os.FileMode = 0600
 )
 
-// GetCacheFileName returns the name of the cache file for a specific environment.
-func GetCacheFileName(env string) string {
-	return fmt.Sprintf("rds-clusters-cache-%s.json", env)
+// EmptyCacheDuration is the longest time a discovery without clusters is cached with CacheConfig.CacheEmpty.
+const EmptyCacheDuration = 5 * time.Minute
+
+// cacheFilePattern matches the cache files of all environments.
+const cacheFilePattern = "rds-clusters-cache-*.json"
+
+// GetCacheFileName returns the name of the cache file for a specific environment and region, e.g.
+// "rds-clusters-cache-prod-us-east-1.json", so that the clusters of an environment name in different
+// regions are cached apart. Without a region, it returns the name used before caches were keyed by region.
+func GetCacheFileName(env, region string) string {
+	if region == "" {
+		return fmt.Sprintf("rds-clusters-cache-%s.json", env)
+	}
+	return fmt.Sprintf("rds-clusters-cache-%s-%s.json", env, region)
 }
 
 // validateCacheFile checks if the cache file exists and is valid.
@@ -63,12 +80,37 @@ func (svc *DatabaseService) parseCacheData(cacheFile string, cacheDir string) (*
 	return &cache, nil
 }
 
+// jitteredDuration returns duration adjusted by a random offset in [-jitter, +jitter].
+// The result is never negative.
+func jitteredDuration(duration, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return duration
+	}
+
+	//nolint:gosec // Jitter only spreads cache expiry and does not need a secure source
+	offset := time.Duration(rand.Int64N(int64(2*jitter)+1)) - jitter
+	if duration+offset < 0 {
+		return 0
+	}
+	return duration + offset
+}
+
 // isCacheExpired checks if the cache is expired based on duration and current time.
 // Duration should be a valid Go duration string (e.g., "24h", "30m", "1h30m").
 // Valid time units are "ns", "us" (or "µs"), "ms", "s", "m", "h".
-func (svc *DatabaseService) isCacheExpired(cache *CacheData, duration time.Duration) bool {
+// A non-zero jitter randomizes the effective duration to avoid synchronized refreshes.
+func (svc *DatabaseService) isCacheExpired(cache *CacheData, duration, jitter time.Duration) bool {
+	duration = jitteredDuration(duration, jitter)
 	now := time.Now()
-	expired := now.Sub(cache.Timestamp) > duration || cache.Timestamp.After(now)
+	if cache.Timestamp.After(now) {
+		// A cache from the future usually means clock skew or a cache copied from another host
+		logger.Warnf("cache timestamp %s is %s in the future; the system clock may be skewed "+
+			"or the cache was copied from another host. Refreshing clusters from AWS.",
+			cache.Timestamp.Format(time.RFC3339), cache.Timestamp.Sub(now).Round(time.Second))
+		return true
+	}
+
+	expired := now.Sub(cache.Timestamp) > duration
 	if expired {
 		svc.logger.Debugf("Cache is expired. Cache timestamp: %v, Current time: %v, Duration: %v",
 			cache.Timestamp, now, duration)
@@ -79,22 +121,97 @@ func (svc *DatabaseService) isCacheExpired(cache *CacheData, duration time.Durat
 	return expired
 }
 
+// discoveryParams are the parameters that determine which clusters a discovery finds.
+type discoveryParams struct {
+	Env           string       `json:"env"`
+	Region        string       `json:"region"`
+	TagName       string       `json:"tagName"`
+	TagValue      string       `json:"tagValue"`
+	EnvTagName    string       `json:"envTagName"`
+	EnvTagValue   string       `json:"envTagValue"`
+	ExcludeTags   []ExcludeTag `json:"excludeTags"`
+	ResourceGroup string       `json:"resourceGroup"`
+	// Standalone is set when standalone instances are discovered, so that earlier caches without them are refreshed.
+	Standalone bool `json:"standalone,omitempty"`
+	// CaseInsensitive is omitted when false, so that it does not change the hash of earlier caches.
+	CaseInsensitive bool `json:"caseInsensitive,omitempty"`
+}
+
+// hash returns a hex-encoded SHA-256 hash of the parameters.
+func (p discoveryParams) hash() string {
+	// Marshaling a struct of strings and slices cannot fail
+	data, _ := json.Marshal(p)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// discoveryHash returns the hash of the parameters of a discovery with the given tags in the environment.
+// Caches written with other parameters, e.g. before the tag filter was changed, are not used.
+func (svc *DatabaseService) discoveryHash(tagName, tagValue, envTagName, envTagValue, env string) string {
+	return discoveryParams{
+		Env:             env,
+		Region:          svc.config.Region,
+		TagName:         tagName,
+		TagValue:        tagValue,
+		EnvTagName:      envTagName,
+		EnvTagValue:     envTagValue,
+		ExcludeTags:     svc.excludeTags,
+		ResourceGroup:   svc.group,
+		Standalone:      svc.group == "",
+		CaseInsensitive: svc.foldTagCase,
+	}.hash()
+}
+
+// cacheFileSuffix matches what follows the environment in a cache file name: the account ID of clusters
+// discovered in another account and the region, each optional, e.g. "-123456789012-us-east-1.json".
+var cacheFileSuffix = regexp.MustCompile(`^(-\d{12})?(-[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-\d+)?\.json$`)
+
+// cacheFileName matches a cache file name like cacheFileSuffix, capturing the environment.
+var cacheFileName = regexp.MustCompile(`^rds-clusters-cache-(.+?)(-\d{12})?(-[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-\d+)?\.json$`)
+
+// CacheFiles returns the paths of the cluster cache files in cacheDir. With environments, it only returns
+// those of the given environments, in all accounts and regions; the files of an environment named like
+// the prefix of another, e.g. "prod" and "prod-eu", are told apart.
+func CacheFiles(cacheDir string, envs ...string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(cacheDir, cacheFilePattern))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache files: %w", err)
+	}
+	if len(envs) == 0 {
+		return paths, nil
+	}
+
+	var matched []string
+	for _, path := range paths {
+		name := filepath.Base(path)
+		for _, env := range envs {
+			prefix := "rds-clusters-cache-" + env
+			if strings.HasPrefix(name, prefix) && cacheFileSuffix.MatchString(name[len(prefix):]) {
+				matched = append(matched, path)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
 // loadFromCache attempts to load RDS clusters from the cache file.
 // Returns the clusters and a boolean indicating if the cache was valid and loaded successfully.
+// A cache written with discovery parameters other than paramsHash is treated as a miss.
 // The cache duration should be a valid Go duration string (e.g., "24h", "30m", "1h30m").
-func (svc *DatabaseService) loadFromCache(env string) ([]Cluster, bool) {
+func (svc *DatabaseService) loadFromCache(env, paramsHash string) ([]Cluster, bool) {
 	if !svc.cacheConfig.Enabled {
 		svc.logger.Debugln("Cache is disabled")
 		return nil, false
 	}
 
-	cacheDir, err := utils.GetCacheDir()
+	cacheDir, err := utils.GetCacheDirWithMode(svc.cacheConfig.DirMode)
 	if err != nil {
 		svc.logger.Debugf("Failed to get cache directory: %v", err)
 		return nil, false
 	}
 
-	cacheFile := filepath.Join(cacheDir, GetCacheFileName(env))
+	cacheFile := filepath.Join(cacheDir, GetCacheFileName(env, svc.config.Region))
 	if _, err := svc.validateCacheFile(cacheFile); err != nil {
 		return nil, false
 	}
@@ -104,6 +221,11 @@ func (svc *DatabaseService) loadFromCache(env string) ([]Cluster, bool) {
 		return nil, false
 	}
 
+	if cache.ParamsHash != paramsHash {
+		svc.logger.Debugf("Cache for environment %s was written with other discovery parameters, ignoring it", env)
+		return nil, false
+	}
+
 	duration, err := time.ParseDuration(svc.cacheConfig.Duration)
 	if err != nil {
 		svc.logger.Debugf("Invalid cache duration format '%s'. Use a valid Go duration (e.g., '24h', '30m'): %v",
@@ -111,36 +233,82 @@ func (svc *DatabaseService) loadFromCache(env string) ([]Cluster, bool) {
 		return nil, false
 	}
 
-	if svc.isCacheExpired(cache, duration) {
+	var jitter time.Duration
+	if svc.cacheConfig.Jitter != "" {
+		jitter, err = time.ParseDuration(svc.cacheConfig.Jitter)
+		if err != nil {
+			svc.logger.Debugf("Invalid cache jitter format '%s', ignoring jitter: %v", svc.cacheConfig.Jitter, err)
+			jitter = 0
+		}
+	}
+
+	// Empty results are kept briefly, so that a misconfiguration being debugged does not hit AWS on every run
+	// while newly created clusters still show up soon
+	if len(cache.Clusters) == 0 {
+		if !svc.cacheConfig.CacheEmpty {
+			svc.logger.Debugf("Cache for environment %s has no clusters, ignoring it", env)
+			return nil, false
+		}
+		duration, jitter = min(duration, EmptyCacheDuration), 0
+	}
+
+	if svc.isCacheExpired(cache, duration, jitter) {
+		return nil, false
+	}
+
+	// Let the caller decide whether to use a cache past half its lifetime
+	if age := time.Since(cache.Timestamp); svc.staleCache != nil && age > duration/2 && !svc.staleCache(age) {
+		svc.logger.Debugf("Refreshing cache for environment %s (age: %v) on request", env, age)
 		return nil, false
 	}
 
+	// A mismatch means the cache was corrupted or edited since it was written
+	for _, cluster := range cache.Clusters {
+		warnEndpointRegion(cluster, "cache")
+	}
+
 	svc.logger.Debugf("Successfully loaded %d clusters from cache for environment %s", len(cache.Clusters), env)
 	return cache.Clusters, true
 }
 
-// saveToCache saves the RDS clusters to the cache file.
+// saveToCache saves the RDS clusters to the cache file along with the hash of their discovery parameters.
 // Returns an error if the operation fails.
-func (svc *DatabaseService) saveToCache(clusters []Cluster, env string) error {
+func (svc *DatabaseService) saveToCache(clusters []Cluster, env, paramsHash string) error {
 	if !svc.cacheConfig.Enabled {
 		svc.logger.Debugln("Cache is disabled, skipping save")
 		return nil
 	}
 
-	cacheDir, err := utils.GetCacheDir()
+	cacheDir, err := utils.GetCacheDirWithMode(svc.cacheConfig.DirMode)
 	if err != nil {
 		svc.logger.Debugf("Failed to get cache directory: %v", err)
 		return fmt.Errorf("failed to get cache directory: %w", err)
 	}
 
-	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+	if err := os.MkdirAll(cacheDir, svc.cacheConfig.DirMode); err != nil {
 		svc.logger.Debugf("Failed to create cache directory: %v", err)
 		return fmt.Errorf("failed to create cache directory: %w", err)
 	}
 
+	// The cache file named without the region is superseded by the one of the region
+	if err := os.Remove(filepath.Join(cacheDir, GetCacheFileName(env, ""))); err == nil {
+		svc.logger.Debugf("Removed the cache file of environment %s named without its region", env)
+	}
+
+	cacheFile := filepath.Join(cacheDir, GetCacheFileName(env, svc.config.Region))
+	if len(clusters) == 0 && !svc.cacheConfig.CacheEmpty {
+		// A cache of earlier clusters would otherwise outlive them
+		svc.logger.Debugf("No clusters found for environment %s, not caching the empty result", env)
+		if err := os.Remove(cacheFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove cache file: %w", err)
+		}
+		return nil
+	}
+
 	cache := CacheData{
-		Clusters:  clusters,
-		Timestamp: time.Now().UTC(),
+		Clusters:   clusters,
+		Timestamp:  time.Now().UTC(),
+		ParamsHash: paramsHash,
 	}
 
 	data, err := json.MarshalIndent(cache, "", "  ")
@@ -149,12 +317,74 @@ func (svc *DatabaseService) saveToCache(clusters []Cluster, env string) error {
 		return fmt.Errorf("failed to marshal cache data: %w", err)
 	}
 
-	cacheFile := filepath.Join(cacheDir, GetCacheFileName(env))
-	if err := os.WriteFile(cacheFile, data, cacheFileMode); err != nil {
+	if err := os.WriteFile(cacheFile, data, svc.cacheConfig.FileMode); err != nil {
 		svc.logger.Debugf("Failed to write cache file: %v", err)
 		return fmt.Errorf("failed to write cache file: %w", err)
 	}
 
+	// WriteFile only applies the mode on creation, so enforce it for existing files too
+	if err := os.Chmod(cacheFile, svc.cacheConfig.FileMode); err != nil {
+		svc.logger.Debugf("Failed to set cache file mode: %v", err)
+		return fmt.Errorf("failed to set cache file mode: %w", err)
+	}
+
 	svc.logger.Debugf("Successfully saved %d clusters to cache for environment %s: %s", len(clusters), env, cacheFile)
+
+	if svc.cacheConfig.MaxFiles > 0 {
+		svc.evictCacheFiles(cacheDir, svc.cacheConfig.MaxFiles)
+	}
 	return nil
 }
+
+// evictCacheFiles deletes the cache files in cacheDir of the least recently written environments beyond
+// the newest maxEnvs, e.g. those of environments that are no longer used. The files of an environment,
+// one per account and region, are kept or deleted together, by the newest of them. Failures are only
+// logged, as the cache still works.
+func (svc *DatabaseService) evictCacheFiles(cacheDir string, maxEnvs int) {
+	paths, err := filepath.Glob(filepath.Join(cacheDir, cacheFilePattern))
+	if err != nil || len(paths) <= maxEnvs {
+		return
+	}
+
+	type cacheEnv struct {
+		name    string
+		paths   []string
+		modTime time.Time
+	}
+	envs := make(map[string]*cacheEnv)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		name := filepath.Base(path)
+		if match := cacheFileName.FindStringSubmatch(name); match != nil {
+			name = match[1]
+		}
+		env, ok := envs[name]
+		if !ok {
+			env = &cacheEnv{name: name}
+			envs[name] = env
+		}
+		env.paths = append(env.paths, path)
+		if info.ModTime().After(env.modTime) {
+			env.modTime = info.ModTime()
+		}
+	}
+
+	sorted := make([]*cacheEnv, 0, len(envs))
+	for _, env := range envs {
+		sorted = append(sorted, env)
+	}
+	// Newest first, so the environments beyond maxEnvs are the least recently written
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].modTime.After(sorted[j].modTime) })
+	for _, env := range sorted[min(maxEnvs, len(sorted)):] {
+		for _, path := range env.paths {
+			if err := os.Remove(path); err != nil {
+				svc.logger.Debugf("Failed to evict cache file %s: %v", path, err)
+			}
+		}
+		svc.logger.Debugf("Evicted cache of environment %s (last written %s)", env.name, env.modTime.Format(time.RFC3339))
+	}
+}
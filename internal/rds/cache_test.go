@@ -0,0 +1,166 @@
+package rds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rds-iam-connect/internal/logger"
+)
+
+func TestIsCacheExpired(t *testing.T) {
+	svc := &DatabaseService{logger: logger.New(false)}
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		want      bool
+	}{
+		{name: "fresh cache", timestamp: time.Now().Add(-time.Minute), want: false},
+		{name: "stale cache", timestamp: time.Now().Add(-2 * time.Hour), want: true},
+		{name: "future timestamp from clock skew", timestamp: time.Now().Add(time.Hour), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &CacheData{Timestamp: tt.timestamp}
+			assert.Equal(t, tt.want, svc.isCacheExpired(cache, time.Hour, 0))
+		})
+	}
+}
+
+func TestEvictCacheFiles(t *testing.T) {
+	dir := t.TempDir()
+	svc := &DatabaseService{logger: logger.New(false)}
+
+	// An environment is kept by its newest file, including its older files in other regions
+	files := map[string]time.Duration{
+		GetCacheFileName("older", "us-east-1"):                    3 * time.Hour,
+		GetCacheFileName("old", "us-east-1"):                      2 * time.Hour,
+		GetCacheFileName("old-eu", "eu-west-1"):                   2 * time.Hour,
+		GetCacheFileName("new", "us-east-1"):                      4 * time.Hour,
+		GetCacheFileName("new-123456789012", "us-west-2"):         time.Hour,
+		GetCacheFileName("newest", "us-east-1"):                   0,
+		GetCacheFileName("newest", ""):                            5 * time.Hour,
+		GetCacheFileName("newest-210987654321", "ap-southeast-2"): 6 * time.Hour,
+	}
+	for name, age := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("{}"), 0600))
+		modTime := time.Now().Add(-age)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	// Files that are not environment caches are left alone
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "audit.log"), nil, 0600))
+
+	svc.evictCacheFiles(dir, 2)
+
+	remaining, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, GetCacheFileName("new", "us-east-1")),
+		filepath.Join(dir, GetCacheFileName("new-123456789012", "us-west-2")),
+		filepath.Join(dir, GetCacheFileName("newest", "us-east-1")),
+		filepath.Join(dir, GetCacheFileName("newest", "")),
+		filepath.Join(dir, GetCacheFileName("newest-210987654321", "ap-southeast-2")),
+		filepath.Join(dir, "audit.log"),
+	}, remaining)
+}
+
+func TestLoadFromCacheChecksDiscoveryParams(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	svc := &DatabaseService{
+		config:      aws.Config{Region: "us-east-1"},
+		cacheConfig: CacheConfig{Enabled: true, Duration: "1h", FileMode: 0600, DirMode: 0700},
+		logger:      logger.New(false),
+	}
+
+	paramsHash := svc.discoveryHash("Environment", "Production", "ReleaseState", "prod", "prod")
+	require.NoError(t, svc.saveToCache([]Cluster{{Identifier: "orders"}}, "prod", paramsHash))
+
+	clusters, ok := svc.loadFromCache("prod", paramsHash)
+	assert.True(t, ok)
+	assert.Equal(t, []Cluster{{Identifier: "orders"}}, clusters)
+
+	// Another tag filter stored under the same environment must not be served from the cache
+	otherHash := svc.discoveryHash("Environment", "Staging", "ReleaseState", "prod", "prod")
+	assert.NotEqual(t, paramsHash, otherHash)
+	_, ok = svc.loadFromCache("prod", otherHash)
+	assert.False(t, ok)
+}
+
+func TestGetCacheFileName(t *testing.T) {
+	assert.Equal(t, "rds-clusters-cache-prod-us-east-1.json", GetCacheFileName("prod", "us-east-1"))
+	assert.Equal(t, "rds-clusters-cache-prod.json", GetCacheFileName("prod", ""))
+	assert.NotEqual(t, GetCacheFileName("prod", "us-east-1"), GetCacheFileName("prod", "us-west-2"))
+}
+
+func TestCacheFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		GetCacheFileName("prod", ""),
+		GetCacheFileName("prod", "us-east-1"),
+		GetCacheFileName("prod-123456789012", "eu-west-1"),
+		GetCacheFileName("prod-eu", "eu-west-1"),
+		GetCacheFileName("staging", "us-gov-west-1"),
+		"audit.log",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0600))
+	}
+
+	tests := []struct {
+		name string
+		envs []string
+		want []string
+	}{
+		{
+			name: "all environments",
+			want: []string{
+				"rds-clusters-cache-prod-123456789012-eu-west-1.json",
+				"rds-clusters-cache-prod-eu-eu-west-1.json",
+				"rds-clusters-cache-prod-us-east-1.json",
+				"rds-clusters-cache-prod.json",
+				"rds-clusters-cache-staging-us-gov-west-1.json",
+			},
+		},
+		{
+			name: "environment with accounts and regions",
+			envs: []string{"prod"},
+			want: []string{
+				"rds-clusters-cache-prod-123456789012-eu-west-1.json",
+				"rds-clusters-cache-prod-us-east-1.json",
+				"rds-clusters-cache-prod.json",
+			},
+		},
+		{
+			name: "environment named like the prefix of another",
+			envs: []string{"prod-eu"},
+			want: []string{"rds-clusters-cache-prod-eu-eu-west-1.json"},
+		},
+		{
+			name: "several environments",
+			envs: []string{"prod-eu", "staging"},
+			want: []string{"rds-clusters-cache-prod-eu-eu-west-1.json", "rds-clusters-cache-staging-us-gov-west-1.json"},
+		},
+		{
+			name: "unknown environment",
+			envs: []string{"dev"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := CacheFiles(dir, tt.envs...)
+			require.NoError(t, err)
+			var names []string
+			for _, path := range paths {
+				names = append(names, filepath.Base(path))
+			}
+			assert.ElementsMatch(t, tt.want, names)
+		})
+	}
+}
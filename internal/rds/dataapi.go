@@ -0,0 +1,98 @@
+package rds
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+// DataAPIClient defines the interface for the RDS Data API operations.
+type DataAPIClient interface {
+	ExecuteStatement(ctx context.Context, params *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error)
+}
+
+// StatementResult holds the result of a statement executed through the RDS Data API.
+type StatementResult struct {
+	Columns        []string   // The column names of the result set, if any.
+	Rows           [][]string // The rows of the result set, formatted as strings.
+	RecordsUpdated int64      // The number of records updated by the statement.
+}
+
+// ExecuteStatement runs a SQL statement on the cluster through the RDS Data API in the cluster's region.
+// The caller is authorized with IAM and the database session uses the credentials in the given
+// Secrets Manager secret.
+func ExecuteStatement(ctx context.Context, cfg aws.Config, cluster Cluster, secretArn, database, sql string) (*StatementResult, error) {
+	client := rdsdata.NewFromConfig(cfg, func(o *rdsdata.Options) {
+		if cluster.Region != "" {
+			o.Region = cluster.Region
+		}
+	})
+	return executeStatement(ctx, client, cluster, secretArn, database, sql)
+}
+
+// executeStatement runs a SQL statement on the cluster with the given Data API client.
+func executeStatement(ctx context.Context, client DataAPIClient, cluster Cluster, secretArn, database, sql string) (*StatementResult, error) {
+	if cluster.Arn == "" {
+		return nil, fmt.Errorf("cluster %s has no ARN", cluster.Identifier)
+	}
+	if secretArn == "" {
+		return nil, fmt.Errorf("a Secrets Manager secret ARN is required for the Data API")
+	}
+
+	input := &rdsdata.ExecuteStatementInput{
+		ResourceArn:           aws.String(cluster.Arn),
+		SecretArn:             aws.String(secretArn),
+		Sql:                   aws.String(sql),
+		IncludeResultMetadata: true,
+	}
+	if database != "" {
+		input.Database = aws.String(database)
+	}
+
+	out, err := client.ExecuteStatement(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("data API request failed: %w", err)
+	}
+
+	result := &StatementResult{RecordsUpdated: out.NumberOfRecordsUpdated}
+	for _, column := range out.ColumnMetadata {
+		result.Columns = append(result.Columns, aws.ToString(column.Name))
+	}
+	for _, record := range out.Records {
+		row := make([]string, 0, len(record))
+		for _, field := range record {
+			row = append(row, formatField(field))
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	return result, nil
+}
+
+// formatField formats a value of a record returned by the Data API for display.
+func formatField(field types.Field) string {
+	switch v := field.(type) {
+	case *types.FieldMemberIsNull:
+		if v.Value {
+			return "NULL"
+		}
+		return ""
+	case *types.FieldMemberStringValue:
+		return v.Value
+	case *types.FieldMemberLongValue:
+		return strconv.FormatInt(v.Value, 10)
+	case *types.FieldMemberDoubleValue:
+		return strconv.FormatFloat(v.Value, 'g', -1, 64)
+	case *types.FieldMemberBooleanValue:
+		return strconv.FormatBool(v.Value)
+	case *types.FieldMemberBlobValue:
+		return base64.StdEncoding.EncodeToString(v.Value)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,50 @@
+package rds
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDataAPIClient is a DataAPIClient that records the request and returns a fixed response.
+type fakeDataAPIClient struct {
+	input  *rdsdata.ExecuteStatementInput
+	output *rdsdata.ExecuteStatementOutput
+}
+
+func (f *fakeDataAPIClient) ExecuteStatement(_ context.Context, params *rdsdata.ExecuteStatementInput, _ ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+	f.input = params
+	return f.output, nil
+}
+
+func TestExecuteStatement(t *testing.T) {
+	client := &fakeDataAPIClient{output: &rdsdata.ExecuteStatementOutput{
+		ColumnMetadata: []types.ColumnMetadata{{Name: aws.String("id")}, {Name: aws.String("name")}, {Name: aws.String("ratio")}},
+		Records: [][]types.Field{
+			{&types.FieldMemberLongValue{Value: 1}, &types.FieldMemberStringValue{Value: "orders"}, &types.FieldMemberDoubleValue{Value: 0.5}},
+			{&types.FieldMemberLongValue{Value: 2}, &types.FieldMemberIsNull{Value: true}, &types.FieldMemberBooleanValue{Value: true}},
+		},
+	}}
+	cluster := Cluster{Identifier: "orders", Arn: "arn:aws:rds:us-east-1:123456789012:cluster:orders"}
+
+	result, err := executeStatement(context.Background(), client, cluster, "arn:secret", "app", "SELECT 1")
+	require.NoError(t, err)
+
+	assert.Equal(t, cluster.Arn, aws.ToString(client.input.ResourceArn))
+	assert.Equal(t, "arn:secret", aws.ToString(client.input.SecretArn))
+	assert.Equal(t, "app", aws.ToString(client.input.Database))
+	assert.True(t, client.input.IncludeResultMetadata)
+	assert.Equal(t, []string{"id", "name", "ratio"}, result.Columns)
+	assert.Equal(t, [][]string{{"1", "orders", "0.5"}, {"2", "NULL", "true"}}, result.Rows)
+}
+
+func TestExecuteStatementRequiresSecret(t *testing.T) {
+	cluster := Cluster{Identifier: "orders", Arn: "arn:aws:rds:us-east-1:123456789012:cluster:orders"}
+	_, err := executeStatement(context.Background(), &fakeDataAPIClient{}, cluster, "", "", "SELECT 1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+
+	awsutil "rds-iam-connect/internal/aws"
+)
+
+// GlobalMember is a regional cluster of an Aurora global database.
+type GlobalMember struct {
+	Arn     string // The ARN of the regional cluster.
+	Region  string // The region of the cluster.
+	Primary bool   // Whether the cluster is the primary (writer) cluster of the global database.
+}
+
+// GlobalClusterMembers returns the identifier and the regional clusters of the Aurora global database
+// the cluster belongs to. The identifier is empty if the cluster is not part of a global database.
+func (svc *DatabaseService) GlobalClusterMembers(ctx context.Context, cluster Cluster) (string, []GlobalMember, error) {
+	svc.logger.Debugf("Fetching global database of cluster %s", cluster.Identifier)
+
+	// DescribeGlobalClusters supports no filter by member cluster, so all global databases are scanned
+	input := &rds.DescribeGlobalClustersInput{}
+
+	for {
+		output, err := svc.client.DescribeGlobalClusters(ctx, input)
+		if err != nil {
+			return "", nil, awsutil.WrapError("failed to describe global clusters", err)
+		}
+
+		for _, global := range output.GlobalClusters {
+			members := make([]GlobalMember, 0, len(global.GlobalClusterMembers))
+			found := false
+			for _, member := range global.GlobalClusterMembers {
+				arn := aws.ToString(member.DBClusterArn)
+				found = found || arn == cluster.Arn
+				members = append(members, GlobalMember{
+					Arn:     arn,
+					Region:  extractRegionFromARN(arn),
+					Primary: aws.ToBool(member.IsWriter),
+				})
+			}
+			if found {
+				return aws.ToString(global.GlobalClusterIdentifier), members, nil
+			}
+		}
+
+		if aws.ToString(output.Marker) == "" {
+			return "", nil, nil
+		}
+		input.Marker = output.Marker
+	}
+}
+
+// DescribeCluster returns the cluster with the given identifier or ARN in the region of the service,
+// without checking its tags. Clusters without an available endpoint return ErrEndpointUnavailable.
+func (svc *DatabaseService) DescribeCluster(ctx context.Context, identifier string) (Cluster, error) {
+	output, err := svc.client.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(identifier),
+	})
+	if err != nil {
+		return Cluster{}, awsutil.WrapError(fmt.Sprintf("failed to describe RDS cluster %s", identifier), err)
+	}
+	if len(output.DBClusters) == 0 {
+		return Cluster{}, fmt.Errorf("RDS cluster %s not found", identifier)
+	}
+
+	dbCluster := output.DBClusters[0]
+	cluster := newCluster(dbCluster)
+	if dbCluster.Endpoint == nil || dbCluster.Port == nil {
+		return cluster, ErrEndpointUnavailable
+	}
+	return cluster, nil
+}
@@ -0,0 +1,145 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+
+	awsutil "rds-iam-connect/internal/aws"
+)
+
+// Instance represents a DB instance that belongs to an RDS cluster.
+type Instance struct {
+	Identifier string // The unique identifier of the DB instance.
+	Endpoint   string // The instance endpoint address.
+	Port       int32  // The port number the instance is listening on.
+	Writer     bool   // Whether the instance is the cluster writer.
+	// IAMAuthEnabled reports whether the instance itself has IAM database authentication enabled.
+	IAMAuthEnabled bool
+}
+
+// Role returns a human-readable role of the instance within its cluster.
+func (i Instance) Role() string {
+	if i.Writer {
+		return "writer"
+	}
+	return "reader"
+}
+
+// AsCluster returns a copy of the cluster that targets the instance endpoint.
+// Auth tokens generated for the returned cluster are signed for the instance endpoint.
+// The ConnectPort override of the cluster does not apply to instance endpoints.
+func (i Instance) AsCluster(cluster Cluster) Cluster {
+	cluster.Endpoint = i.Endpoint
+	cluster.Port = i.Port
+	cluster.ConnectPort = 0
+	return cluster
+}
+
+// Readers returns the reader instances from the given list.
+func Readers(instances []Instance) []Instance {
+	readers := make([]Instance, 0, len(instances))
+	for _, instance := range instances {
+		if !instance.Writer {
+			readers = append(readers, instance)
+		}
+	}
+	return readers
+}
+
+// IAMAuthDisabled returns the instances from the given list that report IAM database authentication
+// as disabled. Connections to such members of an IAM-enabled cluster may fail intermittently.
+func IAMAuthDisabled(instances []Instance) []Instance {
+	disabled := make([]Instance, 0)
+	for _, instance := range instances {
+		if !instance.IAMAuthEnabled {
+			disabled = append(disabled, instance)
+		}
+	}
+	return disabled
+}
+
+// clusterWriters returns the set of writer instance identifiers for the given cluster.
+func (svc *DatabaseService) clusterWriters(ctx context.Context, cluster Cluster) (map[string]bool, error) {
+	output, err := svc.client.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(cluster.Identifier),
+	})
+	if err != nil {
+		return nil, awsutil.WrapError(fmt.Sprintf("failed to describe RDS cluster %s", cluster.Identifier), err)
+	}
+
+	writers := make(map[string]bool)
+	for _, dbCluster := range output.DBClusters {
+		for _, member := range dbCluster.DBClusterMembers {
+			if member.DBInstanceIdentifier != nil && member.IsClusterWriter != nil && *member.IsClusterWriter {
+				writers[*member.DBInstanceIdentifier] = true
+			}
+		}
+	}
+	return writers, nil
+}
+
+// processDBInstance converts a DB instance into an Instance.
+// Returns false if the instance has no endpoint available yet.
+func processDBInstance(dbInstance types.DBInstance, writers map[string]bool) (Instance, bool) {
+	if dbInstance.DBInstanceIdentifier == nil || dbInstance.Endpoint == nil ||
+		dbInstance.Endpoint.Address == nil || dbInstance.Endpoint.Port == nil {
+		return Instance{}, false
+	}
+
+	return Instance{
+		Identifier:     *dbInstance.DBInstanceIdentifier,
+		Endpoint:       *dbInstance.Endpoint.Address,
+		Port:           *dbInstance.Endpoint.Port,
+		Writer:         writers[*dbInstance.DBInstanceIdentifier],
+		IAMAuthEnabled: aws.ToBool(dbInstance.IAMDatabaseAuthenticationEnabled),
+	}, true
+}
+
+// GetClusterInstances retrieves the member instances of the given cluster.
+// Instances without an available endpoint are skipped.
+func (svc *DatabaseService) GetClusterInstances(ctx context.Context, cluster Cluster) ([]Instance, error) {
+	svc.logger.Debugf("Fetching member instances for cluster %s", cluster.Identifier)
+
+	writers, err := svc.clusterWriters(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]Instance, 0)
+	input := &rds.DescribeDBInstancesInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("db-cluster-id"),
+				Values: []string{cluster.Identifier},
+			},
+		},
+	}
+	paginator := rds.NewDescribeDBInstancesPaginator(svc.client, input)
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			svc.logger.Debugf("Error describing RDS instances: %v", err)
+			return nil, awsutil.WrapError("failed to describe RDS instances", err)
+		}
+
+		for _, dbInstance := range page.DBInstances {
+			instance, ok := processDBInstance(dbInstance, writers)
+			if !ok {
+				svc.logger.Debugf("Skipping instance without endpoint in cluster %s", cluster.Identifier)
+				continue
+			}
+			if !instance.IAMAuthEnabled {
+				svc.logger.Debugf("Instance %s of cluster %s has IAM authentication disabled", instance.Identifier, cluster.Identifier)
+			}
+			instances = append(instances, instance)
+		}
+	}
+
+	svc.logger.Debugf("Found %d member instances for cluster %s", len(instances), cluster.Identifier)
+	return instances, nil
+}
@@ -0,0 +1,63 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+
+	awsutil "rds-iam-connect/internal/aws"
+)
+
+// describeInstance returns the DB instance with the given identifier or ARN.
+func (svc *DatabaseService) describeInstance(ctx context.Context, identifier string) (types.DBInstance, error) {
+	output, err := svc.client.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(identifier),
+	})
+	if err != nil {
+		return types.DBInstance{}, awsutil.WrapError(fmt.Sprintf("failed to describe RDS instance %s", identifier), err)
+	}
+	if len(output.DBInstances) == 0 {
+		return types.DBInstance{}, fmt.Errorf("RDS instance %s not found", identifier)
+	}
+	return output.DBInstances[0], nil
+}
+
+// ReadReplicaIdentifiers returns the read replicas of the standalone DB instance with the given identifier.
+// Replicas in the same region are given by identifier, cross-region replicas by ARN.
+func (svc *DatabaseService) ReadReplicaIdentifiers(ctx context.Context, identifier string) ([]string, error) {
+	svc.logger.Debugf("Fetching read replicas of instance %s", identifier)
+
+	dbInstance, err := svc.describeInstance(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+	return dbInstance.ReadReplicaDBInstanceIdentifiers, nil
+}
+
+// DescribeInstance returns the DB instance with the given identifier or ARN in the region of the service
+// as a standalone connection target. Instances without an available endpoint return ErrEndpointUnavailable.
+func (svc *DatabaseService) DescribeInstance(ctx context.Context, identifier string) (Cluster, error) {
+	dbInstance, err := svc.describeInstance(ctx, identifier)
+	if err != nil {
+		return Cluster{}, err
+	}
+
+	target, ok := newStandaloneTarget(dbInstance)
+	if !ok {
+		return target, ErrEndpointUnavailable
+	}
+	return target, nil
+}
+
+// ReplicaRegion returns the region of a read replica given by ARN, or "" for replicas given by identifier,
+// which are in the region of their source.
+func ReplicaRegion(replica string) string {
+	if !strings.HasPrefix(replica, "arn:") {
+		return ""
+	}
+	return extractRegionFromARN(replica)
+}
@@ -0,0 +1,48 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroups"
+	rgtypes "github.com/aws/aws-sdk-go-v2/service/resourcegroups/types"
+
+	awsutil "rds-iam-connect/internal/aws"
+)
+
+// dbClusterResourceType is the resource type of RDS DB clusters in Resource Groups.
+const dbClusterResourceType = "AWS::RDS::DBCluster"
+
+// ResourceGroupsClient defines the interface for the AWS Resource Groups operations.
+type ResourceGroupsClient interface {
+	ListGroupResources(ctx context.Context, params *resourcegroups.ListGroupResourcesInput, optFns ...func(*resourcegroups.Options)) (*resourcegroups.ListGroupResourcesOutput, error)
+}
+
+// listGroupClusterARNs returns the ARNs of the DB clusters in the given resource group, which is
+// given by name or ARN.
+func (svc *DatabaseService) listGroupClusterARNs(ctx context.Context, group string) ([]string, error) {
+	input := &resourcegroups.ListGroupResourcesInput{
+		Group: aws.String(group),
+		Filters: []rgtypes.ResourceFilter{
+			{Name: rgtypes.ResourceFilterNameResourceType, Values: []string{dbClusterResourceType}},
+		},
+	}
+
+	arns := make([]string, 0)
+	paginator := resourcegroups.NewListGroupResourcesPaginator(svc.groups, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, awsutil.WrapError(fmt.Sprintf("failed to list resources of group %s", group), err)
+		}
+
+		for _, resource := range page.Resources {
+			if resource.Identifier == nil || resource.Identifier.ResourceArn == nil {
+				continue
+			}
+			arns = append(arns, *resource.Identifier.ResourceArn)
+		}
+	}
+	return arns, nil
+}
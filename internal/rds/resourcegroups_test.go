@@ -0,0 +1,118 @@
+package rds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroups"
+	rgtypes "github.com/aws/aws-sdk-go-v2/service/resourcegroups/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rds-iam-connect/internal/logger"
+)
+
+// fakeResourceGroupsClient is a ResourceGroupsClient that serves pages of resource ARNs, marked by their index.
+type fakeResourceGroupsClient struct {
+	pages [][]string
+	err   error
+
+	inputs []*resourcegroups.ListGroupResourcesInput // The requests received, in order.
+}
+
+func (f *fakeResourceGroupsClient) ListGroupResources(_ context.Context, params *resourcegroups.ListGroupResourcesInput, _ ...func(*resourcegroups.Options)) (*resourcegroups.ListGroupResourcesOutput, error) {
+	f.inputs = append(f.inputs, params)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if len(f.pages) == 0 {
+		return &resourcegroups.ListGroupResourcesOutput{}, nil
+	}
+
+	page, _ := strconv.Atoi(aws.ToString(params.NextToken))
+	output := &resourcegroups.ListGroupResourcesOutput{}
+	for _, arn := range f.pages[page] {
+		output.Resources = append(output.Resources, rgtypes.ListGroupResourcesItem{
+			Identifier: &rgtypes.ResourceIdentifier{ResourceArn: aws.String(arn), ResourceType: aws.String(dbClusterResourceType)},
+		})
+	}
+	if page+1 < len(f.pages) {
+		output.NextToken = aws.String(strconv.Itoa(page + 1))
+	}
+	return output, nil
+}
+
+func TestListGroupClusterARNs(t *testing.T) {
+	client := &fakeResourceGroupsClient{pages: [][]string{
+		{"arn:aws:rds:us-east-1:123456789012:cluster:orders", "arn:aws:rds:us-east-1:123456789012:cluster:billing"},
+		{"arn:aws:rds:us-east-1:123456789012:cluster:reports"},
+	}}
+	svc := &DatabaseService{groups: client, logger: logger.New(false)}
+
+	arns, err := svc.listGroupClusterARNs(context.Background(), "analytics-databases")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"arn:aws:rds:us-east-1:123456789012:cluster:orders",
+		"arn:aws:rds:us-east-1:123456789012:cluster:billing",
+		"arn:aws:rds:us-east-1:123456789012:cluster:reports",
+	}, arns)
+
+	require.Len(t, client.inputs, 2)
+	for _, input := range client.inputs {
+		assert.Equal(t, "analytics-databases", aws.ToString(input.Group))
+		assert.Equal(t, []rgtypes.ResourceFilter{
+			{Name: rgtypes.ResourceFilterNameResourceType, Values: []string{dbClusterResourceType}},
+		}, input.Filters)
+	}
+	assert.Nil(t, client.inputs[0].NextToken)
+	assert.Equal(t, "1", aws.ToString(client.inputs[1].NextToken))
+
+	t.Run("error", func(t *testing.T) {
+		svc := &DatabaseService{groups: &fakeResourceGroupsClient{err: errors.New("group not found")}, logger: logger.New(false)}
+		_, err := svc.listGroupClusterARNs(context.Background(), "missing")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to list resources of group missing")
+		assert.Contains(t, err.Error(), "group not found")
+	})
+}
+
+func TestDescribeInputs(t *testing.T) {
+	arns := make([]string, 0, maxClusterFilterValues+1)
+	for i := 0; i <= maxClusterFilterValues; i++ {
+		arns = append(arns, fmt.Sprintf("arn:aws:rds:us-east-1:123456789012:cluster:db-%d", i))
+	}
+
+	tests := []struct {
+		name      string
+		group     string
+		pages     [][]string
+		wantSizes []int // The number of filter values of each request, or 0 for an unfiltered request.
+	}{
+		{name: "no group", wantSizes: []int{0}},
+		{name: "empty group", group: "empty"},
+		{name: "batched", group: "large", pages: [][]string{arns[:60], arns[60:]}, wantSizes: []int{maxClusterFilterValues, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &DatabaseService{group: tt.group, groups: &fakeResourceGroupsClient{pages: tt.pages}, logger: logger.New(false)}
+
+			inputs, err := svc.describeInputs(context.Background())
+			require.NoError(t, err)
+			require.Len(t, inputs, len(tt.wantSizes))
+			for i, input := range inputs {
+				if tt.wantSizes[i] == 0 {
+					assert.Empty(t, input.Filters)
+					continue
+				}
+				require.Len(t, input.Filters, 1)
+				assert.Equal(t, "db-cluster-id", aws.ToString(input.Filters[0].Name))
+				assert.Len(t, input.Filters[0].Values, tt.wantSizes[i])
+			}
+		})
+	}
+}
@@ -2,33 +2,160 @@ package rds
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
 	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroups"
 
+	awsutil "rds-iam-connect/internal/aws"
 	"rds-iam-connect/internal/logger"
+	"rds-iam-connect/internal/metrics"
+	"rds-iam-connect/internal/utils"
 )
 
+// resourceIDTimeout bounds the cluster resource ID lookup used by IAM permission checks.
+const resourceIDTimeout = 10 * time.Second
+
 // NewService creates a new instance of DatabaseService.
-func NewService(cfg aws.Config, cacheEnabled bool, cacheDuration string, debug bool) *DatabaseService {
+// Zero cache file and directory modes are replaced with the secure defaults.
+func NewService(cfg aws.Config, cacheConfig CacheConfig, debug bool) *DatabaseService {
+	if cacheConfig.FileMode == 0 {
+		cacheConfig.FileMode = cacheFileMode
+	}
+	if cacheConfig.DirMode == 0 {
+		cacheConfig.DirMode = utils.DefaultDirMode
+	}
+
 	return &DatabaseService{
-		client: rds.NewFromConfig(cfg),
-		config: cfg,
-		cacheConfig: struct {
-			Enabled  bool
-			Duration string
-		}{
-			Enabled:  cacheEnabled,
-			Duration: cacheDuration,
-		},
-		logger: logger.New(debug),
+		client:      rds.NewFromConfig(cfg),
+		groups:      resourcegroups.NewFromConfig(cfg),
+		config:      cfg,
+		cacheConfig: cacheConfig,
+		logger:      logger.New(debug),
 	}
 }
 
+// SetProgressFunc registers a function that is called with the number of clusters scanned
+// so far while clusters are fetched from AWS.
+func (svc *DatabaseService) SetProgressFunc(fn func(scanned int)) {
+	svc.progress = fn
+}
+
+// SetVerboseLogging enables per-cluster debug messages during discovery, such as clusters
+// skipped for being in another region. Without it, such skips are summarized in a single line.
+func (svc *DatabaseService) SetVerboseLogging(verbose bool) {
+	svc.logger.SetVerbose(verbose)
+}
+
+// SetBestEffort makes discovery return the clusters gathered so far when fetching a later page
+// fails, instead of failing altogether. The error is then reported by IncompleteError and the
+// partial result is not cached.
+func (svc *DatabaseService) SetBestEffort(bestEffort bool) {
+	svc.bestEffort = bestEffort
+}
+
+// SetMetrics records the duration of each discovery with the recorder, labelled with the environment and
+// whether the clusters came from the cache or AWS.
+func (svc *DatabaseService) SetMetrics(recorder *metrics.Recorder) {
+	svc.metrics = recorder
+}
+
+// SetCaseInsensitiveTags makes discovery match the required tags regardless of the case of their keys and values.
+func (svc *DatabaseService) SetCaseInsensitiveTags(caseInsensitive bool) {
+	svc.foldTagCase = caseInsensitive
+}
+
+// SetRawDump writes every page of the DescribeDBClusters responses of a discovery from AWS to w as
+// indented JSON, before the clusters are filtered, for debugging clusters that do not show up.
+func (svc *DatabaseService) SetRawDump(w io.Writer) {
+	svc.rawDump = w
+}
+
+// dumpRawPage writes a page of DescribeDBClusters to the raw dump, if set, together with the region
+// it was described in.
+func (svc *DatabaseService) dumpRawPage(page *rds.DescribeDBClustersOutput) {
+	if svc.rawDump == nil {
+		return
+	}
+
+	data, err := json.MarshalIndent(struct {
+		Region string                        `json:"region"`
+		Output *rds.DescribeDBClustersOutput `json:"output"`
+	}{svc.config.Region, page}, "", "  ")
+	if err != nil {
+		svc.logger.Debugf("Failed to encode raw DescribeDBClusters response: %v", err)
+		return
+	}
+	// A single write keeps pages of concurrent discoveries apart
+	if _, err := svc.rawDump.Write(append(data, '\n')); err != nil {
+		svc.logger.Debugf("Failed to write raw DescribeDBClusters response: %v", err)
+	}
+}
+
+// IncompleteError returns the error that cut the last fetch from AWS short in best-effort mode,
+// or nil if it was complete.
+func (svc *DatabaseService) IncompleteError() error {
+	return svc.incomplete
+}
+
+// SetExcludeTags sets tags that exclude clusters from discovery even if they match the required tags.
+func (svc *DatabaseService) SetExcludeTags(tags []ExcludeTag) {
+	svc.excludeTags = tags
+}
+
+// SetResourceGroup makes discovery return the clusters of the given resource group, by name or ARN,
+// instead of the clusters carrying the required tags. Excluded tags still apply. Empty restores tag matching.
+func (svc *DatabaseService) SetResourceGroup(group string) {
+	svc.group = group
+}
+
+// SetRefresh makes discovery fetch the clusters from AWS even if the cache is still valid.
+// The fetched clusters are written to the cache as usual.
+func (svc *DatabaseService) SetRefresh(refresh bool) {
+	svc.refresh = refresh
+}
+
+// SetTagKeys sets the tags whose values are carried on discovered clusters in Cluster.Tags.
+// Clusters loaded from the cache carry the tags that were requested when the cache was written.
+func (svc *DatabaseService) SetTagKeys(keys []string) {
+	svc.tagKeys = keys
+}
+
+// selectTags returns the values of the given tag keys that are present in tags.
+// Returns nil if none of the keys are present.
+func selectTags(tags []types.Tag, keys []string) map[string]string {
+	var selected map[string]string
+	for _, tag := range tags {
+		if tag.Key == nil || tag.Value == nil {
+			continue
+		}
+		for _, key := range keys {
+			if *tag.Key == key {
+				if selected == nil {
+					selected = make(map[string]string, len(keys))
+				}
+				selected[key] = *tag.Value
+			}
+		}
+	}
+	return selected
+}
+
+// SetStaleCacheFunc registers a function that is called when the cache is still valid but older
+// than half its duration. The function receives the cache age and returns true to use the cached
+// clusters, or false to fetch fresh ones from AWS.
+func (svc *DatabaseService) SetStaleCacheFunc(fn func(age time.Duration) bool) {
+	svc.staleCache = fn
+}
+
 // validateTags checks if the required tags are provided.
 func validateTags(tagName, tagValue, envTagName, envTagValue string) error {
 	if tagName == "" || tagValue == "" || envTagName == "" || envTagValue == "" {
@@ -37,16 +164,22 @@ func validateTags(tagName, tagValue, envTagName, envTagValue string) error {
 	return nil
 }
 
-// hasRequiredTags checks if a cluster has both specified tags.
-func hasRequiredTags(tags []types.Tag, tagName, tagValue, envTagName, envTagValue string) bool {
+// hasRequiredTags checks if a cluster has both specified tags. With foldCase, keys and values are
+// compared regardless of case.
+func hasRequiredTags(tags []types.Tag, tagName, tagValue, envTagName, envTagValue string, foldCase bool) bool {
 	hasTagName := false
 	hasEnvTag := false
 
+	equal := func(a, b string) bool { return a == b }
+	if foldCase {
+		equal = strings.EqualFold
+	}
+
 	for _, tag := range tags {
-		if *tag.Key == tagName && *tag.Value == tagValue {
+		if equal(*tag.Key, tagName) && equal(*tag.Value, tagValue) {
 			hasTagName = true
 		}
-		if *tag.Key == envTagName && *tag.Value == envTagValue {
+		if equal(*tag.Key, envTagName) && equal(*tag.Value, envTagValue) {
 			hasEnvTag = true
 		}
 	}
@@ -54,6 +187,53 @@ func hasRequiredTags(tags []types.Tag, tagName, tagValue, envTagName, envTagValu
 	return hasTagName && hasEnvTag
 }
 
+// hasExcludedTags checks if a cluster carries any of the excluded tags.
+func hasExcludedTags(tags []types.Tag, excluded []ExcludeTag) bool {
+	for _, tag := range tags {
+		if tag.Key == nil || tag.Value == nil {
+			continue
+		}
+		for _, exclude := range excluded {
+			if *tag.Key == exclude.Name && (exclude.Value == "" || *tag.Value == exclude.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// connectPortTag is the tag that overrides the port clients connect to, e.g. for a proxy in front of the cluster.
+const connectPortTag = "ConnectPort"
+
+// connectPort returns the port from the ConnectPort tag, or 0 if the tag is missing or not a valid port.
+func (svc *DatabaseService) connectPort(identifier string, tags []types.Tag) int32 {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) != connectPortTag {
+			continue
+		}
+		port, err := strconv.ParseUint(aws.ToString(tag.Value), 10, 16)
+		if err != nil || port == 0 {
+			svc.logger.Debugf("Ignoring invalid %s tag %q on cluster %s", connectPortTag, aws.ToString(tag.Value), identifier)
+			return 0
+		}
+		return int32(port)
+	}
+	return 0
+}
+
+// OpsNoteTag is the tag carrying an operational note of the cluster, e.g. its owner or maintenance window.
+const OpsNoteTag = "OpsNote"
+
+// opsNote returns the value of the OpsNote tag, or "" if the tag is missing.
+func opsNote(tags []types.Tag) string {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == OpsNoteTag {
+			return aws.ToString(tag.Value)
+		}
+	}
+	return ""
+}
+
 // extractRegionFromARN extracts the region from an ARN.
 func extractRegionFromARN(arn string) string {
 	if arnParts := strings.Split(arn, ":"); len(arnParts) >= 4 {
@@ -62,117 +242,308 @@ func extractRegionFromARN(arn string) string {
 	return ""
 }
 
+// rdsEndpointSuffixes are the DNS suffixes of RDS endpoints, preceded by the region.
+var rdsEndpointSuffixes = []string{".rds.amazonaws.com", ".rds.amazonaws.com.cn"}
+
+// endpointRegion returns the region embedded in an RDS endpoint hostname such as
+// "orders.cluster-abc123.us-west-2.rds.amazonaws.com", or "" for other hostnames.
+func endpointRegion(host string) string {
+	for _, suffix := range rdsEndpointSuffixes {
+		if prefix, ok := strings.CutSuffix(strings.ToLower(host), suffix); ok {
+			if i := strings.LastIndex(prefix, "."); i >= 0 {
+				return prefix[i+1:]
+			}
+		}
+	}
+	return ""
+}
+
+// warnEndpointRegion prints a warning if the region of the cluster's endpoint differs from the region
+// of its ARN, as the auth token would be signed for the wrong region. source names where the cluster
+// came from, e.g. "cache".
+func warnEndpointRegion(cluster Cluster, source string) {
+	region := endpointRegion(cluster.Endpoint)
+	if region == "" || cluster.Region == "" || region == cluster.Region {
+		return
+	}
+	hint := ""
+	if source == "cache" {
+		hint = "; delete the cluster cache to rediscover it"
+	}
+	logger.Warnf("endpoint %s of cluster %s from %s is in region %s, but its ARN is in %s, so auth tokens may fail%s",
+		cluster.Endpoint, cluster.Identifier, source, region, cluster.Region, hint)
+}
+
 // processDBCluster processes a single DB cluster and returns a Cluster if it matches the criteria.
-// Returns ErrClusterSkipped if the cluster doesn't meet the criteria.
+// Returns ErrClusterSkipped if the cluster doesn't meet the criteria, or the cluster together with
+// ErrEndpointUnavailable if it matches but has no endpoint yet.
 func (svc *DatabaseService) processDBCluster(ctx context.Context, dbCluster types.DBCluster, tagName, tagValue, envTagName, envTagValue string) (*Cluster, error) {
 	if dbCluster.IAMDatabaseAuthenticationEnabled == nil || !*dbCluster.IAMDatabaseAuthenticationEnabled {
 		return nil, ErrClusterSkipped
 	}
 
-	if dbCluster.DBClusterIdentifier == nil || dbCluster.Endpoint == nil || dbCluster.Port == nil {
+	if dbCluster.DBClusterIdentifier == nil || dbCluster.DBClusterArn == nil {
 		return nil, ErrClusterSkipped
 	}
 
+	// The region is checked first, as it needs no API call
+	region := extractRegionFromARN(*dbCluster.DBClusterArn)
+	if region != svc.config.Region {
+		return nil, ErrOtherRegion
+	}
+
 	tagsInput := &rds.ListTagsForResourceInput{
 		ResourceName: dbCluster.DBClusterArn,
 	}
 	tagsOutput, err := svc.client.ListTagsForResource(ctx, tagsInput)
 	if err != nil {
-		return nil, fmt.Errorf("listing tags for resource: %w", err)
+		return nil, awsutil.WrapError("failed to list tags for resource", err)
 	}
 
-	if !hasRequiredTags(tagsOutput.TagList, tagName, tagValue, envTagName, envTagValue) {
+	// Membership in the resource group replaces the required tags
+	if svc.group == "" && !hasRequiredTags(tagsOutput.TagList, tagName, tagValue, envTagName, envTagValue, svc.foldTagCase) {
 		return nil, ErrClusterSkipped
 	}
 
-	region := extractRegionFromARN(*dbCluster.DBClusterArn)
-	if region != svc.config.Region {
-		return nil, ErrClusterSkipped
+	if hasExcludedTags(tagsOutput.TagList, svc.excludeTags) {
+		return nil, ErrExcluded
+	}
+
+	cluster := newCluster(dbCluster)
+	cluster.Tags = selectTags(tagsOutput.TagList, svc.tagKeys)
+	cluster.ConnectPort = svc.connectPort(*dbCluster.DBClusterIdentifier, tagsOutput.TagList)
+	cluster.Note = opsNote(tagsOutput.TagList)
+
+	// The endpoint is checked last so that only clusters matching the criteria are reported as unavailable
+	if dbCluster.Endpoint == nil || dbCluster.Port == nil {
+		return &cluster, ErrEndpointUnavailable
 	}
+	warnEndpointRegion(cluster, "AWS")
 
-	return &Cluster{
-		Identifier: *dbCluster.DBClusterIdentifier,
-		Endpoint:   *dbCluster.Endpoint,
-		Port:       *dbCluster.Port,
-		Arn:        *dbCluster.DBClusterArn,
-		Region:     region,
-	}, nil
+	return &cluster, nil
+}
+
+// newCluster converts a DB cluster into a Cluster with its connection details.
+func newCluster(dbCluster types.DBCluster) Cluster {
+	arn := aws.ToString(dbCluster.DBClusterArn)
+	return Cluster{
+		Identifier:     aws.ToString(dbCluster.DBClusterIdentifier),
+		Endpoint:       aws.ToString(dbCluster.Endpoint),
+		ReaderEndpoint: aws.ToString(dbCluster.ReaderEndpoint),
+		Port:           aws.ToInt32(dbCluster.Port),
+		Arn:            arn,
+		Region:         extractRegionFromARN(arn),
+		Engine:         aws.ToString(dbCluster.Engine),
+		Status:         aws.ToString(dbCluster.Status),
+	}
 }
 
 // fetchClustersFromAWS retrieves clusters from AWS RDS and processes them.
 func (svc *DatabaseService) fetchClustersFromAWS(ctx context.Context, tagName, tagValue, envTagName, envTagValue string) ([]Cluster, error) {
 	svc.logger.Debugf("Fetching RDS clusters from AWS (region: %s)", svc.config.Region)
 	clusters := make([]Cluster, 0)
-	input := &rds.DescribeDBClustersInput{}
-	paginator := rds.NewDescribeDBClustersPaginator(svc.client, input)
+	svc.unavailable = nil
+	svc.incomplete = nil
+	scanned := 0
+	otherRegion := 0
 
-	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(ctx)
-		if err != nil {
-			svc.logger.Debugf("Error describing RDS clusters: %v", err)
-			return nil, fmt.Errorf("describing RDS clusters: %w", err)
+	inputs, err := svc.describeInputs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, input := range inputs {
+		if svc.incomplete != nil {
+			break
 		}
 
-		svc.logger.Debugf("Processing %d clusters from AWS", len(page.DBClusters))
-		for _, dbCluster := range page.DBClusters {
-			cluster, err := svc.processDBCluster(ctx, dbCluster, tagName, tagValue, envTagName, envTagValue)
+		paginator := rds.NewDescribeDBClustersPaginator(svc.client, input)
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
 			if err != nil {
-				if errors.Is(err, ErrClusterSkipped) {
-					svc.logger.Debugf("Skipping cluster %s: %v", *dbCluster.DBClusterIdentifier, err)
-					continue
+				svc.logger.Debugf("Error describing RDS clusters: %v", err)
+				err = awsutil.WrapError("failed to describe RDS clusters", err)
+				if svc.bestEffort && scanned > 0 && ctx.Err() == nil {
+					svc.logger.Debugf("Returning %d clusters found in the first %d scanned", len(clusters), scanned)
+					svc.incomplete = err
+					break
 				}
-				svc.logger.Debugf("Error processing cluster %s: %v", *dbCluster.DBClusterIdentifier, err)
 				return nil, err
 			}
-			if cluster != nil {
-				svc.logger.Debugf("Found matching cluster: %s", cluster.Identifier)
-				clusters = append(clusters, *cluster)
+
+			svc.dumpRawPage(page)
+			svc.logger.Debugf("Processing %d clusters from AWS", len(page.DBClusters))
+			for _, dbCluster := range page.DBClusters {
+				scanned++
+				if svc.progress != nil {
+					svc.progress(scanned)
+				}
+
+				cluster, err := svc.processDBCluster(ctx, dbCluster, tagName, tagValue, envTagName, envTagValue)
+				if err != nil {
+					if errors.Is(err, ErrEndpointUnavailable) {
+						svc.logger.Debugf("Cluster %s is not connectable: %v (status: %s)", cluster.Identifier, err, cluster.Status)
+						svc.unavailable = append(svc.unavailable, *cluster)
+						continue
+					}
+					if errors.Is(err, ErrOtherRegion) {
+						otherRegion++
+						svc.logger.Verbosef("Skipping cluster %s: %v", aws.ToString(dbCluster.DBClusterIdentifier), err)
+						continue
+					}
+					if errors.Is(err, ErrClusterSkipped) {
+						svc.logger.Debugf("Skipping cluster %s: %v", aws.ToString(dbCluster.DBClusterIdentifier), err)
+						continue
+					}
+					svc.logger.Debugf("Error processing cluster %s: %v", *dbCluster.DBClusterIdentifier, err)
+					return nil, err
+				}
+				if cluster != nil {
+					svc.logger.Debugf("Found matching cluster: %s", cluster.Identifier)
+					clusters = append(clusters, *cluster)
+				}
 			}
 		}
 	}
-	svc.logger.Debugf("Found %d matching RDS clusters in AWS", len(clusters))
+
+	// Resource groups only list clusters, so standalone instances are only discovered by their tags
+	if svc.group == "" && svc.incomplete == nil {
+		instances, instancesOtherRegion, err := svc.fetchStandaloneInstances(ctx, tagName, tagValue, envTagName, envTagValue, scanned)
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, instances...)
+		otherRegion += instancesOtherRegion
+	}
+	if otherRegion > 0 {
+		svc.logger.Debugf("Skipped %d clusters and instances in other regions", otherRegion)
+	}
+	svc.logger.Debugf("Found %d matching RDS clusters and instances in AWS", len(clusters))
 	return clusters, nil
 }
 
+// maxClusterFilterValues is the maximum number of values of a DescribeDBClusters filter.
+const maxClusterFilterValues = 100
+
+// describeInputs returns the DescribeDBClusters requests of a discovery: a single request for all clusters,
+// or requests for the clusters of the resource group in batches. A group without clusters needs no request.
+func (svc *DatabaseService) describeInputs(ctx context.Context) ([]*rds.DescribeDBClustersInput, error) {
+	if svc.group == "" {
+		return []*rds.DescribeDBClustersInput{{}}, nil
+	}
+
+	arns, err := svc.listGroupClusterARNs(ctx, svc.group)
+	if err != nil {
+		return nil, err
+	}
+	svc.logger.Debugf("Found %d clusters in resource group %s", len(arns), svc.group)
+
+	inputs := make([]*rds.DescribeDBClustersInput, 0, len(arns)/maxClusterFilterValues+1)
+	for start := 0; start < len(arns); start += maxClusterFilterValues {
+		end := min(start+maxClusterFilterValues, len(arns))
+		inputs = append(inputs, &rds.DescribeDBClustersInput{
+			Filters: []types.Filter{{Name: aws.String("db-cluster-id"), Values: arns[start:end]}},
+		})
+	}
+	return inputs, nil
+}
+
 // GetClusters retrieves RDS clusters based on the provided tags and environment.
 func (svc *DatabaseService) GetClusters(ctx context.Context, tagName, tagValue, envTagName, envTagValue, env string) ([]Cluster, error) {
-	if err := validateTags(tagName, tagValue, envTagName, envTagValue); err != nil {
+	// Clusters of a resource group need no tags
+	if err := validateTags(tagName, tagValue, envTagName, envTagValue); err != nil && svc.group == "" {
 		svc.logger.Debugf("Invalid tags provided: %v", err)
 		return nil, err
 	}
 
-	// Try to load from cache first
-	svc.logger.Debugln("Attempting to load clusters from cache")
-	if clusters, ok := svc.loadFromCache(env); ok {
-		svc.logger.Debugf("Successfully loaded %d clusters from cache", len(clusters))
-		return clusters, nil
+	paramsHash := svc.discoveryHash(tagName, tagValue, envTagName, envTagValue, env)
+	start := time.Now()
+
+	// Try to load from cache first, unless a refresh was requested
+	if svc.refresh {
+		svc.logger.Debugln("Refresh requested, fetching from AWS")
+	} else {
+		svc.logger.Debugln("Attempting to load clusters from cache")
+		if clusters, ok := svc.loadFromCache(env, paramsHash); ok {
+			svc.logger.Debugf("Successfully loaded %d clusters from cache", len(clusters))
+			svc.metrics.Timing("discovery", time.Since(start), metrics.Labels{"env": env, "source": "cache"})
+			return clusters, nil
+		}
+		svc.logger.Debugln("Cache miss or invalid, fetching from AWS")
 	}
-	svc.logger.Debugln("Cache miss or invalid, fetching from AWS")
 
 	// Fetch clusters from AWS
 	clusters, err := svc.fetchClustersFromAWS(ctx, tagName, tagValue, envTagName, envTagValue)
 	if err != nil {
 		return nil, err
 	}
+	svc.metrics.Timing("discovery", time.Since(start), metrics.Labels{"env": env, "source": "aws"})
+
+	// Partial results would hide the missing clusters until the cache expires
+	if svc.incomplete != nil {
+		return clusters, nil
+	}
 
 	// Save to cache before returning
-	if err := svc.saveToCache(clusters, env); err != nil {
+	if err := svc.saveToCache(clusters, env, paramsHash); err != nil {
 		svc.logger.Debugf("Warning: Failed to save clusters to cache: %v", err)
 	}
 
 	return clusters, nil
 }
 
-// GetRDSInstanceIdentifier gets the RDS instance identifier.
-func (svc *DatabaseService) GetRDSInstanceIdentifier(cluster Cluster) string {
+// UnavailableClusters returns the clusters that matched the criteria in the last fetch from AWS
+// but were skipped because their endpoint is not yet available. It is empty when the clusters
+// were loaded from the cache.
+func (svc *DatabaseService) UnavailableClusters() []Cluster {
+	return svc.unavailable
+}
+
+// GetRDSInstanceIdentifier gets the resource ID used in rds-db:connect resource ARNs: the cluster
+// resource ID for clusters and their instances, or the DB instance resource ID for standalone instances.
+// The lookup is bounded by resourceIDTimeout and aborts when ctx is cancelled.
+func (svc *DatabaseService) GetRDSInstanceIdentifier(ctx context.Context, cluster Cluster) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, resourceIDTimeout)
+	defer cancel()
+
+	if cluster.Standalone {
+		return svc.instanceResourceID(ctx, cluster.Identifier)
+	}
+	return svc.clusterResourceID(ctx, cluster.Identifier)
+}
+
+// clusterResourceID gets the resource ID of the cluster with the given identifier.
+func (svc *DatabaseService) clusterResourceID(ctx context.Context, identifier string) (string, error) {
 	input := &rds.DescribeDBClustersInput{
-		DBClusterIdentifier: aws.String(cluster.Identifier),
+		DBClusterIdentifier: aws.String(identifier),
 	}
 
-	output, err := svc.client.DescribeDBClusters(context.Background(), input)
+	output, err := svc.client.DescribeDBClusters(ctx, input)
 	if err != nil {
-		return ""
+		return "", awsutil.WrapError(fmt.Sprintf("failed to describe RDS cluster %s", identifier), err)
+	}
+
+	if len(output.DBClusters) == 0 || output.DBClusters[0].DbClusterResourceId == nil {
+		return "", fmt.Errorf("no resource ID found for RDS cluster %s", identifier)
+	}
+
+	return *output.DBClusters[0].DbClusterResourceId, nil
+}
+
+// instanceResourceID gets the resource ID of the DB instance with the given identifier.
+func (svc *DatabaseService) instanceResourceID(ctx context.Context, identifier string) (string, error) {
+	input := &rds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(identifier),
+	}
+
+	output, err := svc.client.DescribeDBInstances(ctx, input)
+	if err != nil {
+		return "", awsutil.WrapError(fmt.Sprintf("failed to describe RDS instance %s", identifier), err)
+	}
+
+	if len(output.DBInstances) == 0 || output.DBInstances[0].DbiResourceId == nil {
+		return "", fmt.Errorf("no resource ID found for RDS instance %s", identifier)
 	}
 
-	return *output.DBClusters[0].DbClusterResourceId
+	return *output.DBInstances[0].DbiResourceId, nil
 }
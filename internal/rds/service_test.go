@@ -0,0 +1,200 @@
+package rds
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	awsutil "rds-iam-connect/internal/aws"
+	"rds-iam-connect/internal/logger"
+)
+
+// fakeClient is a Client that serves fixed clusters and instances.
+type fakeClient struct {
+	clusters  []types.DBCluster
+	instances []types.DBInstance
+
+	globalClusters [][]types.GlobalCluster // Pages of global clusters, marked by their index.
+	globalFilters  []types.Filter          // The filters of the last DescribeGlobalClusters call.
+}
+
+func (f *fakeClient) DescribeDBClusters(_ context.Context, params *rds.DescribeDBClustersInput, _ ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error) {
+	var matches []types.DBCluster
+	for _, cluster := range f.clusters {
+		if params.DBClusterIdentifier == nil || aws.ToString(cluster.DBClusterIdentifier) == *params.DBClusterIdentifier {
+			matches = append(matches, cluster)
+		}
+	}
+	return &rds.DescribeDBClustersOutput{DBClusters: matches}, nil
+}
+
+func (f *fakeClient) DescribeDBInstances(_ context.Context, params *rds.DescribeDBInstancesInput, _ ...func(*rds.Options)) (*rds.DescribeDBInstancesOutput, error) {
+	var matches []types.DBInstance
+	for _, instance := range f.instances {
+		if params.DBInstanceIdentifier == nil || aws.ToString(instance.DBInstanceIdentifier) == *params.DBInstanceIdentifier {
+			matches = append(matches, instance)
+		}
+	}
+	return &rds.DescribeDBInstancesOutput{DBInstances: matches}, nil
+}
+
+func (f *fakeClient) DescribeGlobalClusters(_ context.Context, params *rds.DescribeGlobalClustersInput, _ ...func(*rds.Options)) (*rds.DescribeGlobalClustersOutput, error) {
+	f.globalFilters = params.Filters
+	if len(f.globalClusters) == 0 {
+		return &rds.DescribeGlobalClustersOutput{}, nil
+	}
+
+	page, _ := strconv.Atoi(aws.ToString(params.Marker))
+	output := &rds.DescribeGlobalClustersOutput{GlobalClusters: f.globalClusters[page]}
+	if page+1 < len(f.globalClusters) {
+		output.Marker = aws.String(strconv.Itoa(page + 1))
+	}
+	return output, nil
+}
+
+func (f *fakeClient) ListTagsForResource(_ context.Context, _ *rds.ListTagsForResourceInput, _ ...func(*rds.Options)) (*rds.ListTagsForResourceOutput, error) {
+	return &rds.ListTagsForResourceOutput{}, nil
+}
+
+func TestGetRDSInstanceIdentifier(t *testing.T) {
+	svc := &DatabaseService{
+		client: &fakeClient{
+			clusters: []types.DBCluster{
+				{DBClusterIdentifier: aws.String("orders"), DbClusterResourceId: aws.String("cluster-ABC123")},
+			},
+			instances: []types.DBInstance{
+				{DBInstanceIdentifier: aws.String("orders"), DbiResourceId: aws.String("db-XYZ789")},
+			},
+		},
+		logger: logger.New(false),
+	}
+
+	tests := []struct {
+		name    string
+		cluster Cluster
+		wantID  string
+		wantARN string
+	}{
+		{
+			name:    "cluster",
+			cluster: Cluster{Identifier: "orders"},
+			wantID:  "cluster-ABC123",
+			wantARN: "arn:aws:rds-db:*:*:dbuser:cluster-ABC123/app",
+		},
+		{
+			name:    "standalone instance",
+			cluster: Cluster{Identifier: "orders", Standalone: true},
+			wantID:  "db-XYZ789",
+			wantARN: "arn:aws:rds-db:*:*:dbuser:db-XYZ789/app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := svc.GetRDSInstanceIdentifier(context.Background(), tt.cluster)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantID, id)
+			assert.Equal(t, tt.wantARN, awsutil.DBUserResourceARN(id, "app"))
+		})
+	}
+
+	t.Run("unknown instance", func(t *testing.T) {
+		_, err := svc.GetRDSInstanceIdentifier(context.Background(), Cluster{Identifier: "missing", Standalone: true})
+		assert.Error(t, err)
+	})
+}
+
+func TestEndpointRegion(t *testing.T) {
+	tests := map[string]string{
+		"orders.cluster-abc123.us-west-2.rds.amazonaws.com":     "us-west-2",
+		"orders.cluster-ro-abc123.eu-west-1.rds.amazonaws.com":  "eu-west-1",
+		"orders-1.abc123.us-east-1.rds.amazonaws.com":           "us-east-1",
+		"orders.cluster-abc123.cn-north-1.rds.amazonaws.com.cn": "cn-north-1",
+		"ORDERS.CLUSTER-ABC123.US-WEST-2.RDS.AMAZONAWS.COM":     "us-west-2",
+		"db.internal.example.com":                               "",
+		"localhost":                                             "",
+		"rds.amazonaws.com":                                     "",
+	}
+	for host, want := range tests {
+		assert.Equal(t, want, endpointRegion(host), host)
+	}
+}
+
+func TestHasRequiredTagsCase(t *testing.T) {
+	tag := func(key, value string) types.Tag {
+		return types.Tag{Key: aws.String(key), Value: aws.String(value)}
+	}
+	exact := []types.Tag{tag("Environment", "Production"), tag("ReleaseState", "prod")}
+	mixed := []types.Tag{tag("environment", "PRODUCTION"), tag("releasestate", "Prod")}
+	other := []types.Tag{tag("environment", "staging"), tag("ReleaseState", "prod")}
+
+	tests := []struct {
+		name     string
+		tags     []types.Tag
+		foldCase bool
+		want     bool
+	}{
+		{name: "exact case, case-sensitive", tags: exact, want: true},
+		{name: "mixed case, case-sensitive", tags: mixed, want: false},
+		{name: "exact case, case-insensitive", tags: exact, foldCase: true, want: true},
+		{name: "mixed case, case-insensitive", tags: mixed, foldCase: true, want: true},
+		{name: "other value, case-insensitive", tags: other, foldCase: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hasRequiredTags(tt.tags, "Environment", "Production", "ReleaseState", "prod", tt.foldCase)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGlobalClusterMembers(t *testing.T) {
+	const (
+		primary   = "arn:aws:rds:us-east-1:123456789012:cluster:orders"
+		secondary = "arn:aws:rds:eu-west-1:123456789012:cluster:orders-eu"
+	)
+	client := &fakeClient{
+		globalClusters: [][]types.GlobalCluster{
+			{
+				{
+					GlobalClusterIdentifier: aws.String("billing-global"),
+					GlobalClusterMembers: []types.GlobalClusterMember{
+						{DBClusterArn: aws.String("arn:aws:rds:us-east-1:123456789012:cluster:billing"), IsWriter: aws.Bool(true)},
+					},
+				},
+			},
+			{
+				{
+					GlobalClusterIdentifier: aws.String("orders-global"),
+					GlobalClusterMembers: []types.GlobalClusterMember{
+						{DBClusterArn: aws.String(primary), IsWriter: aws.Bool(true)},
+						{DBClusterArn: aws.String(secondary), IsWriter: aws.Bool(false)},
+					},
+				},
+			},
+		},
+	}
+	svc := &DatabaseService{client: client, logger: logger.New(false)}
+
+	// The global database is found by the members' ARNs on a later page, without a filter
+	id, members, err := svc.GlobalClusterMembers(context.Background(), Cluster{Identifier: "orders-eu", Arn: secondary})
+	require.NoError(t, err)
+	assert.Equal(t, "orders-global", id)
+	assert.Equal(t, []GlobalMember{
+		{Arn: primary, Region: "us-east-1", Primary: true},
+		{Arn: secondary, Region: "eu-west-1"},
+	}, members)
+	assert.Empty(t, client.globalFilters)
+
+	id, members, err = svc.GlobalClusterMembers(context.Background(), Cluster{Identifier: "reports", Arn: "arn:aws:rds:us-east-1:123456789012:cluster:reports"})
+	require.NoError(t, err)
+	assert.Empty(t, id)
+	assert.Empty(t, members)
+}
@@ -0,0 +1,121 @@
+package rds
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+
+	awsutil "rds-iam-connect/internal/aws"
+)
+
+// newStandaloneTarget converts a DB instance into a standalone connection target.
+// Returns false if the instance has no endpoint available yet.
+func newStandaloneTarget(dbInstance types.DBInstance) (Cluster, bool) {
+	instance, ok := processDBInstance(dbInstance, nil)
+	arn := aws.ToString(dbInstance.DBInstanceArn)
+	return Cluster{
+		Identifier: aws.ToString(dbInstance.DBInstanceIdentifier),
+		Endpoint:   instance.Endpoint,
+		Port:       instance.Port,
+		Arn:        arn,
+		Region:     extractRegionFromARN(arn),
+		Engine:     aws.ToString(dbInstance.Engine),
+		Status:     aws.ToString(dbInstance.DBInstanceStatus),
+		Standalone: true,
+	}, ok
+}
+
+// processStandaloneInstance processes a single DB instance like processDBCluster and returns it as a
+// standalone target if it matches the criteria. Members of a cluster are reached through their cluster
+// and read replicas through their source instance, so both are skipped. Unlike clusters, the tags
+// come with the instance and need no API call.
+func (svc *DatabaseService) processStandaloneInstance(dbInstance types.DBInstance, tagName, tagValue, envTagName, envTagValue string) (*Cluster, error) {
+	if dbInstance.DBClusterIdentifier != nil || dbInstance.ReadReplicaSourceDBInstanceIdentifier != nil {
+		return nil, ErrClusterSkipped
+	}
+
+	if !aws.ToBool(dbInstance.IAMDatabaseAuthenticationEnabled) {
+		return nil, ErrClusterSkipped
+	}
+
+	if dbInstance.DBInstanceIdentifier == nil || dbInstance.DBInstanceArn == nil {
+		return nil, ErrClusterSkipped
+	}
+
+	if extractRegionFromARN(*dbInstance.DBInstanceArn) != svc.config.Region {
+		return nil, ErrOtherRegion
+	}
+
+	if !hasRequiredTags(dbInstance.TagList, tagName, tagValue, envTagName, envTagValue, svc.foldTagCase) {
+		return nil, ErrClusterSkipped
+	}
+
+	if hasExcludedTags(dbInstance.TagList, svc.excludeTags) {
+		return nil, ErrExcluded
+	}
+
+	target, ok := newStandaloneTarget(dbInstance)
+	target.Tags = selectTags(dbInstance.TagList, svc.tagKeys)
+	target.ConnectPort = svc.connectPort(target.Identifier, dbInstance.TagList)
+	target.Note = opsNote(dbInstance.TagList)
+
+	// The endpoint is checked last so that only instances matching the criteria are reported as unavailable
+	if !ok {
+		return &target, ErrEndpointUnavailable
+	}
+	warnEndpointRegion(target, "AWS")
+
+	return &target, nil
+}
+
+// fetchStandaloneInstances retrieves the standalone DB instances matching the tags from AWS RDS, together
+// with the number of instances skipped in other regions. scanned is the number of targets scanned so far,
+// which the progress function continues from.
+func (svc *DatabaseService) fetchStandaloneInstances(ctx context.Context, tagName, tagValue, envTagName, envTagValue string, scanned int) ([]Cluster, int, error) {
+	svc.logger.Debugf("Fetching standalone RDS instances from AWS (region: %s)", svc.config.Region)
+	targets := make([]Cluster, 0)
+	otherRegion := 0
+
+	paginator := rds.NewDescribeDBInstancesPaginator(svc.client, &rds.DescribeDBInstancesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			svc.logger.Debugf("Error describing RDS instances: %v", err)
+			err = awsutil.WrapError("failed to describe RDS instances", err)
+			if svc.bestEffort && scanned > 0 && ctx.Err() == nil {
+				svc.logger.Debugf("Returning %d instances found before the error", len(targets))
+				svc.incomplete = err
+				return targets, otherRegion, nil
+			}
+			return nil, otherRegion, err
+		}
+
+		for _, dbInstance := range page.DBInstances {
+			scanned++
+			if svc.progress != nil {
+				svc.progress(scanned)
+			}
+
+			target, err := svc.processStandaloneInstance(dbInstance, tagName, tagValue, envTagName, envTagValue)
+			switch {
+			case errors.Is(err, ErrEndpointUnavailable):
+				svc.logger.Debugf("Instance %s is not connectable: %v (status: %s)", target.Identifier, err, target.Status)
+				svc.unavailable = append(svc.unavailable, *target)
+			case errors.Is(err, ErrOtherRegion):
+				otherRegion++
+				svc.logger.Verbosef("Skipping instance %s: %v", aws.ToString(dbInstance.DBInstanceIdentifier), err)
+			case errors.Is(err, ErrClusterSkipped):
+				svc.logger.Debugf("Skipping instance %s: %v", aws.ToString(dbInstance.DBInstanceIdentifier), err)
+			case err != nil:
+				return nil, otherRegion, err
+			default:
+				svc.logger.Debugf("Found matching standalone instance: %s", target.Identifier)
+				targets = append(targets, *target)
+			}
+		}
+	}
+	return targets, otherRegion, nil
+}
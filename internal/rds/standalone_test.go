@@ -0,0 +1,103 @@
+package rds
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rds-iam-connect/internal/logger"
+)
+
+// testInstance returns a standalone DB instance in us-east-1 with IAM authentication, an endpoint
+// and the tags of the prod environment, changed by modify.
+func testInstance(identifier string, modify func(*types.DBInstance)) types.DBInstance {
+	instance := types.DBInstance{
+		DBInstanceIdentifier:             aws.String(identifier),
+		DBInstanceArn:                    aws.String("arn:aws:rds:us-east-1:123456789012:db:" + identifier),
+		DBInstanceStatus:                 aws.String("available"),
+		Engine:                           aws.String("mysql"),
+		IAMDatabaseAuthenticationEnabled: aws.Bool(true),
+		Endpoint:                         &types.Endpoint{Address: aws.String(identifier + ".abc.us-east-1.rds.amazonaws.com"), Port: aws.Int32(3306)},
+		TagList: []types.Tag{
+			{Key: aws.String("rds-iam-connect"), Value: aws.String("true")},
+			{Key: aws.String("Environment"), Value: aws.String("prod")},
+			{Key: aws.String("team"), Value: aws.String("payments")},
+		},
+	}
+	if modify != nil {
+		modify(&instance)
+	}
+	return instance
+}
+
+func TestFetchStandaloneInstances(t *testing.T) {
+	client := &fakeClient{instances: []types.DBInstance{
+		testInstance("orders", nil),
+		testInstance("orders-member", func(i *types.DBInstance) { i.DBClusterIdentifier = aws.String("orders-cluster") }),
+		testInstance("orders-replica", func(i *types.DBInstance) { i.ReadReplicaSourceDBInstanceIdentifier = aws.String("orders") }),
+		testInstance("no-iam", func(i *types.DBInstance) { i.IAMDatabaseAuthenticationEnabled = aws.Bool(false) }),
+		testInstance("west", func(i *types.DBInstance) {
+			i.DBInstanceArn = aws.String("arn:aws:rds:us-west-2:123456789012:db:west")
+		}),
+		testInstance("untagged", func(i *types.DBInstance) { i.TagList = i.TagList[:1] }),
+		testInstance("retired", func(i *types.DBInstance) {
+			i.TagList = append(i.TagList, types.Tag{Key: aws.String("retired"), Value: aws.String("yes")})
+		}),
+		testInstance("creating", func(i *types.DBInstance) {
+			i.Endpoint = nil
+			i.DBInstanceStatus = aws.String("creating")
+		}),
+	}}
+
+	svc := &DatabaseService{
+		client:      client,
+		config:      aws.Config{Region: "us-east-1"},
+		logger:      logger.New(false),
+		tagKeys:     []string{"team"},
+		excludeTags: []ExcludeTag{{Name: "retired"}},
+	}
+
+	targets, err := svc.fetchClustersFromAWS(context.Background(), "rds-iam-connect", "true", "Environment", "prod")
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	assert.Equal(t, Cluster{
+		Identifier: "orders",
+		Endpoint:   "orders.abc.us-east-1.rds.amazonaws.com",
+		Port:       3306,
+		Arn:        "arn:aws:rds:us-east-1:123456789012:db:orders",
+		Region:     "us-east-1",
+		Engine:     "mysql",
+		Status:     "available",
+		Tags:       map[string]string{"team": "payments"},
+		Standalone: true,
+	}, targets[0])
+
+	unavailable := svc.UnavailableClusters()
+	require.Len(t, unavailable, 1)
+	assert.Equal(t, "creating", unavailable[0].Identifier)
+	assert.True(t, unavailable[0].Standalone)
+
+	t.Run("other regions", func(t *testing.T) {
+		_, otherRegion, err := svc.fetchStandaloneInstances(context.Background(), "rds-iam-connect", "true", "Environment", "prod", 0)
+		require.NoError(t, err)
+		assert.Equal(t, 1, otherRegion)
+	})
+
+	t.Run("resource group", func(t *testing.T) {
+		svc := &DatabaseService{
+			client: client,
+			groups: &fakeResourceGroupsClient{},
+			config: aws.Config{Region: "us-east-1"},
+			logger: logger.New(false),
+			group:  "analytics-databases",
+		}
+
+		targets, err := svc.fetchClustersFromAWS(context.Background(), "rds-iam-connect", "true", "Environment", "prod")
+		require.NoError(t, err)
+		assert.Empty(t, targets)
+	})
+}
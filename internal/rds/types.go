@@ -3,45 +3,129 @@ package rds
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
 
 	"rds-iam-connect/internal/logger"
+	"rds-iam-connect/internal/metrics"
 )
 
 // Client defines the interface for AWS RDS operations.
 type Client interface {
 	DescribeDBClusters(ctx context.Context, params *rds.DescribeDBClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error)
+	DescribeDBInstances(ctx context.Context, params *rds.DescribeDBInstancesInput, optFns ...func(*rds.Options)) (*rds.DescribeDBInstancesOutput, error)
 	ListTagsForResource(ctx context.Context, params *rds.ListTagsForResourceInput, optFns ...func(*rds.Options)) (*rds.ListTagsForResourceOutput, error)
+	DescribeGlobalClusters(ctx context.Context, params *rds.DescribeGlobalClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeGlobalClustersOutput, error)
 }
 
 // Cluster represents an RDS database cluster with its connection details.
 type Cluster struct {
-	Identifier string // The unique identifier of the RDS cluster.
-	Endpoint   string // The endpoint URL to connect to the cluster.
-	Port       int32  // The port number the cluster is listening on.
-	Arn        string // The Amazon Resource Name of the cluster.
-	Region     string // The AWS region where the cluster is located.
+	Identifier     string            // The unique identifier of the RDS cluster.
+	Endpoint       string            // The endpoint URL to connect to the cluster.
+	ReaderEndpoint string            // The load-balanced reader endpoint of the cluster, if any.
+	Port           int32             // The port number the cluster is listening on.
+	Arn            string            // The Amazon Resource Name of the cluster.
+	Region         string            // The AWS region where the cluster is located.
+	Engine         string            // The database engine, e.g. "aurora-mysql" or "aurora-postgresql".
+	Tags           map[string]string // The values of the tags requested with SetTagKeys, if present.
+	Status         string            // The cluster status reported by AWS, e.g. "available" or "creating".
+	Standalone     bool              // Whether the target is a standalone DB instance rather than a cluster.
+	ConnectPort    int32             // The port clients connect to, from the ConnectPort tag. 0 means Port.
+	Account        string            // The ID of the AWS account the cluster was discovered in through EnvConfig.Accounts.
+	GlobalCluster  string            // The Aurora global database of a cluster returned by the global member lookup.
+	GlobalPrimary  bool              // Whether the cluster is the primary region of GlobalCluster.
+	Note           string            // The operational note from the OpsNote tag, if present.
+}
+
+// ClientPort returns the port clients connect to: ConnectPort if set, otherwise Port.
+// Auth tokens are always signed for Port.
+func (c Cluster) ClientPort() int32 {
+	if c.ConnectPort != 0 {
+		return c.ConnectPort
+	}
+	return c.Port
+}
+
+// GlobalRole returns "primary" or "secondary" for regional clusters of a global database, and "" otherwise.
+func (c Cluster) GlobalRole() string {
+	switch {
+	case c.GlobalCluster == "":
+		return ""
+	case c.GlobalPrimary:
+		return "primary"
+	default:
+		return "secondary"
+	}
+}
+
+// IsPostgres reports whether the cluster runs a PostgreSQL-compatible engine.
+func (c Cluster) IsPostgres() bool {
+	return strings.Contains(c.Engine, "postgres")
+}
+
+// CacheConfig controls how discovered clusters are cached on disk.
+type CacheConfig struct {
+	Enabled  bool        // Whether caching is enabled.
+	Duration string      // The duration for which cached data is valid.
+	Jitter   string      // The maximum random adjustment applied to Duration (e.g., "30m").
+	FileMode os.FileMode // The permission mode of cache files (defaults to 0600).
+	DirMode  os.FileMode // The permission mode of the cache directory (defaults to 0700).
+	MaxFiles int         // The number of environments whose cache files are kept, evicting the least recently written. 0 keeps all.
+	// CacheEmpty caches discoveries without clusters for at most EmptyCacheDuration. Otherwise they are not cached,
+	// so that newly created clusters are found on the next run.
+	CacheEmpty bool
 }
 
 // DatabaseService provides functionality for interacting with AWS RDS clusters.
 type DatabaseService struct {
-	client      *rds.Client
+	client      Client
 	config      aws.Config
-	cacheConfig struct {
-		Enabled  bool
-		Duration string
-	}
-	logger *logger.Logger
+	cacheConfig CacheConfig
+	logger      *logger.Logger
+	progress    func(scanned int)            // Called as clusters are scanned during discovery, if set.
+	staleCache  func(age time.Duration) bool // Decides whether to use a cache past half its lifetime, if set.
+	tagKeys     []string                     // Tags carried on discovered clusters.
+	excludeTags []ExcludeTag                 // Tags that exclude clusters from discovery.
+	unavailable []Cluster                    // Matching clusters without an endpoint, from the last fetch.
+	bestEffort  bool                         // Keeps the clusters gathered so far when a later page fails.
+	incomplete  error                        // The error that cut the last fetch short in best-effort mode.
+	group       string                       // Resource group whose clusters are discovered instead of matching tags.
+	groups      ResourceGroupsClient         // Lists the clusters of the resource group.
+	refresh     bool                         // Fetches clusters from AWS even if the cache is valid.
+	metrics     *metrics.Recorder            // Records discovery timings, if set.
+	rawDump     io.Writer                    // Receives the raw DescribeDBClusters responses, if set.
+	foldTagCase bool                         // Matches the required tags regardless of case.
 }
 
 // CacheData represents the structure of cached RDS cluster data.
 type CacheData struct {
 	Timestamp time.Time `json:"timestamp"`
-	Clusters  []Cluster `json:"clusters"`
+	// ParamsHash identifies the discovery parameters the clusters were found with, see discoveryParams.
+	ParamsHash string    `json:"paramsHash"`
+	Clusters   []Cluster `json:"clusters"`
+}
+
+// ExcludeTag is a tag that excludes clusters from discovery.
+type ExcludeTag struct {
+	Name  string // The tag key.
+	Value string // The tag value. Empty matches any value.
 }
 
 // ErrClusterSkipped is returned when a cluster is skipped due to not meeting criteria.
 var ErrClusterSkipped = errors.New("cluster skipped")
+
+// ErrOtherRegion is returned for a cluster outside the region of the service. It wraps ErrClusterSkipped.
+var ErrOtherRegion = fmt.Errorf("%w: cluster is in another region", ErrClusterSkipped)
+
+// ErrExcluded is returned for a cluster that carries an excluded tag. It wraps ErrClusterSkipped.
+var ErrExcluded = fmt.Errorf("%w: cluster has an excluded tag", ErrClusterSkipped)
+
+// ErrEndpointUnavailable is returned for a cluster that matches the criteria but has no endpoint yet,
+// e.g. while it is being created. It wraps ErrClusterSkipped.
+var ErrEndpointUnavailable = fmt.Errorf("%w: endpoint not yet available", ErrClusterSkipped)
@@ -8,20 +8,68 @@ import (
 	"path/filepath"
 )
 
+// appName is the name of the application directories.
+const appName = "rds-iam-connect"
+
+// DefaultDirMode is the default permission mode of the cache directory.
+// Use 0700 permissions to ensure only the owner has access.
+const DefaultDirMode os.FileMode = 0700
+
 // GetCacheDir returns the path to the cache directory for the RDS IAM Connect tool.
 // It creates the directory if it doesn't exist, with secure permissions (0700).
 // Returns the absolute path to the cache directory or an error if the operation fails.
 func GetCacheDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	return GetCacheDirWithMode(DefaultDirMode)
+}
+
+// GetCacheDirWithMode returns the path to the cache directory, creating it with the given
+// permissions if it doesn't exist.
+func GetCacheDirWithMode(mode os.FileMode) (string, error) {
+	cacheDir, err := CacheDirPath()
 	if err != nil {
-		return "", fmt.Errorf("failed to get user home directory: %w", err)
+		return "", err
 	}
 
-	cacheDir := filepath.Join(homeDir, ".rds-iam-connect")
-	// Use 0700 permissions to ensure only the owner has access
-	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+	if err := os.MkdirAll(cacheDir, mode); err != nil {
 		return "", fmt.Errorf("failed to create cache directory: %w", err)
 	}
 
 	return cacheDir, nil
 }
+
+// GetConfigDir returns the path to the configuration directory, creating it with secure
+// permissions (0700) if it doesn't exist.
+func GetConfigDir() (string, error) {
+	configDir, err := appDir("XDG_CONFIG_HOME")
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(configDir, DefaultDirMode); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	return configDir, nil
+}
+
+// CacheDirPath returns the path to the cache directory without creating it.
+// It is $XDG_CACHE_HOME/rds-iam-connect when XDG_CACHE_HOME is set, and ~/.rds-iam-connect otherwise.
+func CacheDirPath() (string, error) {
+	return appDir("XDG_CACHE_HOME")
+}
+
+// appDir returns the application directory under the XDG base directory named by xdgVar,
+// falling back to ~/.rds-iam-connect when the variable is unset. Relative XDG paths are
+// ignored, as required by the XDG base directory specification.
+func appDir(xdgVar string) (string, error) {
+	if base := os.Getenv(xdgVar); base != "" && filepath.IsAbs(base) {
+		return filepath.Join(base, appName), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	return filepath.Join(homeDir, "."+appName), nil
+}
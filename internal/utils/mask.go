@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maskedAccountID replaces AWS account IDs in masked output.
+const maskedAccountID = "xxxxxxxxxxxx"
+
+// maskedValue replaces the masked part of ARNs and endpoints.
+const maskedValue = "***"
+
+// arnAccountPattern matches the account portion of an ARN (arn:partition:service:region:account:...).
+var arnAccountPattern = regexp.MustCompile(`(arn:[^:\s]*:[^:\s]*:[^:\s]*:)(\d{12})(:)`)
+
+// arnPattern matches a whole ARN, capturing its partition and service. Punctuation that commonly
+// follows an ARN in messages is not part of the match.
+var arnPattern = regexp.MustCompile(`(arn:[^:\s]*:[^:\s]*:)[^\s,;()"']*`)
+
+// MaskAccountID replaces the account ID portion of every ARN in s with a fixed placeholder.
+func MaskAccountID(s string) string {
+	return arnAccountPattern.ReplaceAllString(s, "${1}"+maskedAccountID+"${3}")
+}
+
+// MaskedAccountID returns the placeholder used in place of a bare account ID.
+func MaskedAccountID() string {
+	return maskedAccountID
+}
+
+// MaskARNs replaces every ARN in s with its partition and service followed by a placeholder,
+// e.g. "arn:aws:rds:***".
+func MaskARNs(s string) string {
+	return arnPattern.ReplaceAllString(s, "${1}"+maskedValue)
+}
+
+// MaskEndpoint keeps the first label of a hostname, which is usually the cluster or instance
+// identifier, and replaces the rest with a placeholder, e.g. "orders-db.***".
+func MaskEndpoint(host string) string {
+	if host == "" {
+		return host
+	}
+	if i := strings.IndexByte(host, '.'); i > 0 {
+		return host[:i+1] + maskedValue
+	}
+	return maskedValue
+}
@@ -0,0 +1,114 @@
+package connect
+
+import (
+	"fmt"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/internal/aws"
+	"rds-iam-connect/internal/rds"
+)
+
+// account is an AWS account that clusters are discovered in. An environment that spans several
+// regions has an account per region, which share the credentials.
+type account struct {
+	id      string // The account ID, empty for the account of the default credentials.
+	roleArn string // The role assumed in the account, empty for the default credentials.
+	region  string // The region discovered in, only set when the environment spans several regions.
+	aws     *aws.Config
+	rds     *rds.DatabaseService
+}
+
+// newAccounts assumes the role of each account configured for the environment.
+// Accounts without a region are discovered in every region of the environment.
+func newAccounts(cfg *config.Config, envCfg config.EnvConfig) ([]*account, error) {
+	accounts := make([]*account, 0, len(envCfg.Accounts))
+	for _, acct := range envCfg.Accounts {
+		region := acct.Region
+		if region == "" {
+			region = envCfg.Region
+		}
+
+		id := aws.AccountID(acct.RoleArn)
+		awsCfg, err := aws.CheckAWSCredentials(region,
+			aws.WithSTSEndpoint(cfg.STSEndpoint),
+			aws.WithAssumeRole(acct.RoleArn, cfg.AssumeRole.SessionName, cfg.AssumeRole.SessionTags))
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize AWS credentials for account %s: %w", id, err)
+		}
+
+		configured := &account{
+			id:      id,
+			roleArn: acct.RoleArn,
+			aws:     awsCfg,
+			rds:     newService(cfg, envCfg, *awsCfg.Config),
+		}
+		if acct.Region != "" {
+			accounts = append(accounts, configured)
+			continue
+		}
+		accounts = append(accounts, configured.inRegions(cfg, envCfg)...)
+	}
+	return accounts, nil
+}
+
+// inRegions returns the account once per region of the environment, each discovering the clusters of
+// its region with the account's credentials. Environments with a single region just use the account.
+func (a *account) inRegions(cfg *config.Config, envCfg config.EnvConfig) []*account {
+	regions := envCfg.AllRegions()
+	if len(regions) < 2 {
+		return []*account{a}
+	}
+
+	accounts := make([]*account, 0, len(regions))
+	for _, region := range regions {
+		regional := &account{id: a.id, roleArn: a.roleArn, region: region, aws: a.aws, rds: a.rds}
+		if region != a.aws.Config.Region {
+			regional.aws = a.aws.InRegion(region)
+			regional.rds = newService(cfg, envCfg, *regional.aws.Config)
+		}
+		accounts = append(accounts, regional)
+	}
+	return accounts
+}
+
+// cacheKey returns the key of the account's cluster cache for the environment.
+// Each account has its own cache so that their clusters are not mixed up; caches are also kept
+// apart by region, see rds.GetCacheFileName.
+func (a *account) cacheKey(env string) string {
+	if a.id == "" {
+		return env
+	}
+	return env + "-" + a.id
+}
+
+// annotate sets the account of the clusters.
+func (a *account) annotate(clusters []Cluster) []Cluster {
+	for i := range clusters {
+		clusters[i].Account = a.id
+	}
+	return clusters
+}
+
+// wrap adds the account and region to an error of the account's discovery.
+func (a *account) wrap(err error) error {
+	switch {
+	case a.id != "" && a.region != "":
+		return fmt.Errorf("account %s in %s: %w", a.id, a.region, err)
+	case a.id != "":
+		return fmt.Errorf("account %s: %w", a.id, err)
+	case a.region != "":
+		return fmt.Errorf("region %s: %w", a.region, err)
+	}
+	return err
+}
+
+// accountFor returns the account the cluster was discovered in, matching its region when the environment
+// spans several regions. Clusters without an account, e.g. ones built by callers of the API, use the first account.
+func (c *Connector) accountFor(cluster Cluster) *account {
+	for _, acct := range c.accounts {
+		if acct.id == cluster.Account && (acct.region == "" || cluster.Region == "" || acct.region == cluster.Region) {
+			return acct
+		}
+	}
+	return c.accounts[0]
+}
@@ -0,0 +1,507 @@
+// Package connect provides a Go API for discovering RDS clusters and connecting to them
+// using IAM authentication, so that other tools can reuse the logic of rds-iam-connect.
+package connect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	sdkaws "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	"rds-iam-connect/config"
+	"rds-iam-connect/internal/aws"
+	"rds-iam-connect/internal/logger"
+	"rds-iam-connect/internal/metrics"
+	"rds-iam-connect/internal/rds"
+)
+
+// Cluster represents an RDS cluster that supports IAM authentication.
+type Cluster = rds.Cluster
+
+// Instance represents a DB instance that belongs to an RDS cluster.
+type Instance = rds.Instance
+
+// StatementResult holds the result of a statement executed through the RDS Data API.
+type StatementResult = rds.StatementResult
+
+// Connector discovers the RDS clusters of a single environment and issues IAM auth tokens for them.
+// It is safe for concurrent use.
+type Connector struct {
+	cfg    *config.Config
+	env    string
+	aws    *aws.Config
+	rds    *rds.DatabaseService
+	logger *logger.Logger
+
+	tokenCreds sdkaws.CredentialsProvider // Credentials that sign auth tokens, nil to use the AWS config's.
+	accounts   []*account                 // The accounts clusters are discovered in.
+
+	mu          sync.Mutex
+	identity    string            // Cached ARN of the current IAM role.
+	resourceIDs map[string]string // Cached cluster resource IDs keyed by cluster identifier.
+}
+
+// New creates a Connector for the given environment of the configuration.
+// AWS credentials are resolved for the environment's region, assuming the configured role if any.
+func New(cfg *config.Config, env string) (*Connector, error) {
+	envCfg, ok := cfg.EnvTag[env]
+	if !ok {
+		return nil, fmt.Errorf("unknown environment: %s", env)
+	}
+
+	awsCfg, err := aws.CheckAWSCredentials(envCfg.Region, awsOptions(cfg)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AWS credentials: %w", err)
+	}
+
+	var tokenCreds sdkaws.CredentialsProvider
+	if cfg.HasTokenCredentials() {
+		tokenCreds, err = aws.LoadCredentials(envCfg.Region, tokenCredentialOptions(cfg)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize token credentials: %w", err)
+		}
+	}
+
+	svc := newService(cfg, envCfg, *awsCfg.Config)
+
+	// Without configured accounts, clusters are discovered with the default credentials
+	accounts := (&account{aws: awsCfg, rds: svc}).inRegions(cfg, envCfg)
+	if len(envCfg.Accounts) > 0 {
+		if accounts, err = newAccounts(cfg, envCfg); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Connector{
+		cfg:         cfg,
+		env:         env,
+		aws:         awsCfg,
+		rds:         svc,
+		logger:      logger.New(cfg.Debug),
+		tokenCreds:  tokenCreds,
+		accounts:    accounts,
+		resourceIDs: make(map[string]string),
+	}, nil
+}
+
+// newService creates an RDS service with the discovery settings of the configuration and environment.
+func newService(cfg *config.Config, envCfg config.EnvConfig, awsCfg sdkaws.Config) *rds.DatabaseService {
+	svc := rds.NewService(awsCfg, cacheConfig(cfg), cfg.Debug)
+	svc.SetResourceGroup(envCfg.ResourceGroup)
+	svc.SetTagKeys(cfg.UI.DisplayTags)
+	svc.SetExcludeTags(excludeTags(cfg))
+	svc.SetVerboseLogging(cfg.VerboseDebug)
+	svc.SetBestEffort(cfg.Discovery.BestEffort)
+	return svc
+}
+
+// tokenCredentialOptions builds the options of the credentials that sign auth tokens from the configuration.
+func tokenCredentialOptions(cfg *config.Config) []aws.Option {
+	creds := cfg.TokenCredentials
+	opts := []aws.Option{aws.WithSTSEndpoint(cfg.STSEndpoint)}
+	if creds.Profile != "" {
+		opts = append(opts, aws.WithSharedConfigProfile(creds.Profile))
+	}
+	if creds.InstanceProfile {
+		opts = append(opts, aws.WithInstanceProfile())
+	}
+	if creds.RoleArn != "" {
+		opts = append(opts, aws.WithAssumeRole(creds.RoleArn, creds.SessionName, nil))
+	}
+	return opts
+}
+
+// awsOptions builds the AWS credential options from the configuration.
+func awsOptions(cfg *config.Config) []aws.Option {
+	opts := []aws.Option{aws.WithSTSEndpoint(cfg.STSEndpoint)}
+	if cfg.AssumeRole.RoleArn != "" {
+		opts = append(opts, aws.WithAssumeRole(cfg.AssumeRole.RoleArn, cfg.AssumeRole.SessionName, cfg.AssumeRole.SessionTags))
+	}
+	return opts
+}
+
+// excludeTags converts the configured ExcludeTags for the RDS service.
+func excludeTags(cfg *config.Config) []rds.ExcludeTag {
+	tags := make([]rds.ExcludeTag, 0, len(cfg.ExcludeTags))
+	for _, tag := range cfg.ExcludeTags {
+		tags = append(tags, rds.ExcludeTag{Name: tag.TagName, Value: tag.TagValue})
+	}
+	return tags
+}
+
+// cacheConfig builds the RDS cache settings from the configuration.
+// The cache modes are validated when the configuration is loaded.
+func cacheConfig(cfg *config.Config) rds.CacheConfig {
+	fileMode, _ := cfg.CacheFileMode()
+	dirMode, _ := cfg.CacheDirMode()
+
+	return rds.CacheConfig{
+		Enabled:    cfg.Caching.Enabled,
+		Duration:   cfg.Caching.Duration,
+		Jitter:     cfg.Caching.Jitter,
+		FileMode:   fileMode,
+		DirMode:    dirMode,
+		MaxFiles:   cfg.Caching.MaxFiles,
+		CacheEmpty: cfg.Caching.CacheEmpty,
+	}
+}
+
+// Environment returns the name of the environment the connector operates on.
+func (c *Connector) Environment() string {
+	return c.env
+}
+
+// AWSConfig returns the AWS SDK configuration used by the connector.
+func (c *Connector) AWSConfig() sdkaws.Config {
+	return *c.aws.Config
+}
+
+// ClusterAWSConfig returns the AWS SDK configuration for calls about the cluster: that of the account
+// the cluster was discovered in, for the cluster's region, e.g. for a secondary cluster of a global database.
+func (c *Connector) ClusterAWSConfig(cluster Cluster) sdkaws.Config {
+	awsCfg := *c.accountFor(cluster).aws.Config
+	if cluster.Region != "" {
+		awsCfg.Region = cluster.Region
+	}
+	return awsCfg
+}
+
+// STSClient returns an STS client for the configured STS endpoint.
+func (c *Connector) STSClient() *sts.Client {
+	return aws.NewSTSClient(*c.aws.Config, c.cfg.STSEndpoint)
+}
+
+// SetProgressFunc registers a function that is called with the number of clusters scanned
+// so far during discovery. It is not called when clusters are served from the cache.
+// With several accounts, it receives the combined number of clusters scanned in all accounts.
+func (c *Connector) SetProgressFunc(fn func(scanned int)) {
+	// Accounts are scanned concurrently
+	var mu sync.Mutex
+	scanned := make([]int, len(c.accounts))
+	for i, acct := range c.accounts {
+		acct.rds.SetProgressFunc(func(n int) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			scanned[i] = n
+			total := 0
+			for _, count := range scanned {
+				total += count
+			}
+			fn(total)
+		})
+	}
+}
+
+// SetStaleCacheFunc registers a function that decides whether to use cached clusters that are
+// older than half the cache duration. It receives the cache age and returns true to use the cache.
+// With several accounts, it is called once per account, never concurrently.
+func (c *Connector) SetStaleCacheFunc(fn func(age time.Duration) bool) {
+	var mu sync.Mutex
+	serialized := func(age time.Duration) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return fn(age)
+	}
+	for _, acct := range c.accounts {
+		acct.rds.SetStaleCacheFunc(serialized)
+	}
+}
+
+// SetRefresh makes Discover fetch the clusters from AWS instead of serving them from the cache.
+// The cache is still updated with the fetched clusters.
+func (c *Connector) SetRefresh(refresh bool) {
+	for _, acct := range c.accounts {
+		acct.rds.SetRefresh(refresh)
+	}
+}
+
+// LoadAllowedUsers merges the users stored in the configured SSM parameter into the
+// allowed users of the configuration. It does nothing if no parameter is configured.
+func (c *Connector) LoadAllowedUsers(ctx context.Context) error {
+	if c.cfg.AllowedIAMUsersParam == "" {
+		return nil
+	}
+
+	value, err := c.aws.GetParameter(ctx, c.cfg.AllowedIAMUsersParam)
+	if err != nil {
+		return fmt.Errorf("failed to load allowed IAM users: %w", err)
+	}
+
+	users, err := config.ParseUserList(value)
+	if err != nil {
+		return fmt.Errorf("failed to parse allowed IAM users from SSM parameter %s: %w", c.cfg.AllowedIAMUsersParam, err)
+	}
+
+	c.cfg.MergeAllowedIAMUsers(users)
+	return nil
+}
+
+// SetMetrics records the discovery timings of every account with the recorder. A nil recorder records nothing.
+func (c *Connector) SetMetrics(recorder *metrics.Recorder) {
+	for _, acct := range c.accounts {
+		acct.rds.SetMetrics(recorder)
+	}
+}
+
+// SetRawDump writes the raw DescribeDBClusters responses of the discoveries of every account to w,
+// before the clusters are filtered. Discoveries served from the cache write nothing, see SetRefresh.
+func (c *Connector) SetRawDump(w io.Writer) {
+	for _, acct := range c.accounts {
+		acct.rds.SetRawDump(w)
+	}
+}
+
+// Discover returns the clusters of the environment that match the configured tags and have IAM authentication enabled.
+// The environment's tag overrides take precedence over the global RdsTags.
+// Results are served from the cache when caching is enabled.
+// With several accounts or regions, the clusters of all accounts and regions are discovered concurrently
+// and merged, each annotated with its account ID and carrying its region.
+func (c *Connector) Discover(ctx context.Context) ([]Cluster, error) {
+	results := make([][]Cluster, len(c.accounts))
+	errs := make([]error, len(c.accounts))
+
+	var wg sync.WaitGroup
+	for i, acct := range c.accounts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = c.discoverAccount(ctx, acct)
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("failed to get RDS clusters: %w", err)
+	}
+
+	clusters := make([]Cluster, 0)
+	for _, result := range results {
+		clusters = append(clusters, result...)
+	}
+	return clusters, nil
+}
+
+// discoverAccount returns the clusters of the environment in a single account.
+func (c *Connector) discoverAccount(ctx context.Context, acct *account) ([]Cluster, error) {
+	tags := c.cfg.EnvRdsTags(c.env)
+	acct.rds.SetCaseInsensitiveTags(tags.CaseInsensitive)
+	clusters, err := acct.rds.GetClusters(ctx, tags.TagName, tags.TagValue,
+		"ReleaseState", c.cfg.EnvTag[c.env].ReleaseState, acct.cacheKey(c.env))
+	if err != nil {
+		return nil, acct.wrap(err)
+	}
+	return acct.annotate(clusters), nil
+}
+
+// UnavailableClusters returns the clusters that matched in the last discovery from AWS but are not
+// connectable because their endpoint is not yet available, e.g. while being created.
+// It is empty when the last discovery was served from the cache.
+func (c *Connector) UnavailableClusters() []Cluster {
+	clusters := make([]Cluster, 0)
+	for _, acct := range c.accounts {
+		clusters = append(clusters, acct.annotate(acct.rds.UnavailableClusters())...)
+	}
+	return clusters
+}
+
+// IncompleteError returns the error that cut the last discovery short when Discovery.BestEffort is set,
+// or nil if the discovered clusters are complete.
+func (c *Connector) IncompleteError() error {
+	errs := make([]error, 0, len(c.accounts))
+	for _, acct := range c.accounts {
+		if err := acct.rds.IncompleteError(); err != nil {
+			errs = append(errs, acct.wrap(err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Instances returns the member instances of the cluster that have an available endpoint.
+func (c *Connector) Instances(ctx context.Context, cluster Cluster) ([]Instance, error) {
+	instances, err := c.accountFor(cluster).rds.GetClusterInstances(ctx, cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster instances: %w", err)
+	}
+	return instances, nil
+}
+
+// GlobalMembers returns the regional clusters of the Aurora global database the cluster belongs to,
+// primary first, each with its endpoints described in its own region. Members without an available
+// endpoint are skipped. It is an error if the cluster is not part of a global database.
+func (c *Connector) GlobalMembers(ctx context.Context, cluster Cluster) ([]Cluster, error) {
+	acct := c.accountFor(cluster)
+	global, members, err := acct.rds.GlobalClusterMembers(ctx, cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get global database: %w", err)
+	}
+	if global == "" {
+		return nil, fmt.Errorf("cluster %s is not part of an Aurora global database", cluster.Identifier)
+	}
+
+	clusters := make([]Cluster, 0, len(members))
+	for _, member := range members {
+		svc := acct.rds
+		if member.Region != acct.aws.Config.Region {
+			regionalCfg := acct.aws.Config.Copy()
+			regionalCfg.Region = member.Region
+			svc = newService(c.cfg, c.cfg.EnvTag[c.env], regionalCfg)
+		}
+
+		memberCluster, err := svc.DescribeCluster(ctx, member.Arn)
+		if errors.Is(err, rds.ErrEndpointUnavailable) {
+			c.logger.Debugf("Skipping global database member %s without an available endpoint", member.Arn)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe global database member %s: %w", member.Arn, err)
+		}
+
+		memberCluster.Account = cluster.Account
+		memberCluster.GlobalCluster = global
+		memberCluster.GlobalPrimary = member.Primary
+		if member.Primary {
+			clusters = append([]Cluster{memberCluster}, clusters...)
+		} else {
+			clusters = append(clusters, memberCluster)
+		}
+	}
+	return clusters, nil
+}
+
+// ReadReplicas returns the read replicas of a standalone DB instance, each described in its own region so
+// that its auth token is signed for the replica's endpoint and region. Replicas without an available
+// endpoint are skipped.
+func (c *Connector) ReadReplicas(ctx context.Context, instance Cluster) ([]Cluster, error) {
+	acct := c.accountFor(instance)
+	identifiers, err := acct.rds.ReadReplicaIdentifiers(ctx, instance.Identifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get read replicas: %w", err)
+	}
+
+	replicas := make([]Cluster, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		svc := acct.rds
+		// Cross-region replicas are given by ARN
+		if region := rds.ReplicaRegion(identifier); region != "" && region != acct.aws.Config.Region {
+			regionalCfg := acct.aws.Config.Copy()
+			regionalCfg.Region = region
+			svc = newService(c.cfg, c.cfg.EnvTag[c.env], regionalCfg)
+		}
+
+		replica, err := svc.DescribeInstance(ctx, identifier)
+		if errors.Is(err, rds.ErrEndpointUnavailable) {
+			c.logger.Debugf("Skipping read replica %s without an available endpoint", identifier)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe read replica %s: %w", identifier, err)
+		}
+
+		replica.Account = instance.Account
+		replicas = append(replicas, replica)
+	}
+	return replicas, nil
+}
+
+// Token generates an IAM authentication token for connecting to the cluster endpoint as the given DB user.
+// Tokens for clusters of a configured account are signed with the credentials of that account.
+func (c *Connector) Token(ctx context.Context, cluster Cluster, user string) (string, error) {
+	tokenCreds := c.tokenCreds
+	if c.accountFor(cluster).id != "" {
+		tokenCreds = nil
+	}
+
+	// Clusters in another region, such as secondary clusters of a global database, need tokens signed for it
+	token, err := rds.GenerateAuthToken(ctx, c.ClusterAWSConfig(cluster), cluster, user, c.logger.Logger,
+		rds.WithExternalCommand(c.cfg.ExternalTokenCommand), rds.WithCredentials(tokenCreds))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate IAM auth token: %w", err)
+	}
+	return token, nil
+}
+
+// Identity returns the ARN of the current IAM role. The result is cached for the lifetime of the connector.
+func (c *Connector) Identity(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.identity != "" {
+		return c.identity, nil
+	}
+
+	identity, err := c.aws.GetCurrentIAMRole(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get IAM role: %w", err)
+	}
+	c.identity = identity
+	return identity, nil
+}
+
+// ResourceARN returns the rds-db:connect resource ARN that grants access to the cluster as the given DB user.
+func (c *Connector) ResourceARN(ctx context.Context, cluster Cluster, user string) (string, error) {
+	resourceID, err := c.resourceID(ctx, cluster)
+	if err != nil {
+		return "", err
+	}
+	return aws.DBUserResourceARN(resourceID, user), nil
+}
+
+// resourceID returns the resource ID of the cluster, or of the instance for standalone instances.
+// The result is cached per resource.
+func (c *Connector) resourceID(ctx context.Context, cluster Cluster) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The ARN tells clusters and instances with the same identifier apart
+	key := cluster.Arn
+	if key == "" {
+		key = cluster.Identifier
+	}
+	if id, ok := c.resourceIDs[key]; ok {
+		return id, nil
+	}
+
+	id, err := c.accountFor(cluster).rds.GetRDSInstanceIdentifier(ctx, cluster)
+	if err != nil {
+		return "", fmt.Errorf("failed to get resource ID of %s: %w", cluster.Identifier, err)
+	}
+	c.resourceIDs[key] = id
+	return id, nil
+}
+
+// CheckAccess verifies with the IAM policy simulator that the current IAM role may
+// connect to the cluster as the given DB user, simulating the configured IAMCheckActions.
+// For clusters of a configured account, the role assumed in that account is checked.
+func (c *Connector) CheckAccess(ctx context.Context, cluster Cluster, user string) error {
+	acct := c.accountFor(cluster)
+	iamRole := acct.roleArn
+	if acct.id == "" {
+		var err error
+		if iamRole, err = c.Identity(ctx); err != nil {
+			return err
+		}
+	}
+
+	resourceID, err := c.resourceID(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	return acct.aws.CheckIAMUserAccess(ctx, iamRole, cluster.Arn, resourceID, user, c.cfg.IAMCheckActions)
+}
+
+// Execute runs a SQL statement on the cluster through the RDS Data API, using the
+// Secrets Manager secret configured in DataAPI.SecretArn for the database session.
+func (c *Connector) Execute(ctx context.Context, cluster Cluster, sql string) (*StatementResult, error) {
+	result, err := rds.ExecuteStatement(ctx, *c.accountFor(cluster).aws.Config, cluster, c.cfg.DataAPI.SecretArn, c.cfg.DataAPI.Database, sql)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute statement on cluster %s: %w", cluster.Identifier, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,145 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	sdkaws "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rds-iam-connect/internal/aws"
+	"rds-iam-connect/internal/rds"
+)
+
+// newTestAccount returns an account whose AWS config has the region and credentials named after id.
+func newTestAccount(id, region string) *account {
+	return &account{
+		id:     id,
+		region: region,
+		aws: &aws.Config{Config: &sdkaws.Config{
+			Region:      region,
+			Credentials: sdkaws.AnonymousCredentials{},
+			AppID:       "account-" + id + "-" + region,
+		}},
+	}
+}
+
+func TestClusterAWSConfig(t *testing.T) {
+	conn := &Connector{accounts: []*account{
+		newTestAccount("", "us-east-1"),
+		newTestAccount("210987654321", "us-east-1"),
+		newTestAccount("210987654321", "eu-west-1"),
+	}}
+
+	tests := []struct {
+		name       string
+		cluster    Cluster
+		wantApp    string
+		wantRegion string
+	}{
+		{
+			name:       "cluster of the default credentials",
+			cluster:    Cluster{Identifier: "orders", Region: "us-east-1"},
+			wantApp:    "account--us-east-1",
+			wantRegion: "us-east-1",
+		},
+		{
+			name:       "cluster of another account",
+			cluster:    Cluster{Identifier: "billing", Account: "210987654321", Region: "eu-west-1"},
+			wantApp:    "account-210987654321-eu-west-1",
+			wantRegion: "eu-west-1",
+		},
+		{
+			name:       "cluster in another region than its account",
+			cluster:    Cluster{Identifier: "orders-ap", Region: "ap-southeast-2"},
+			wantApp:    "account--us-east-1",
+			wantRegion: "ap-southeast-2",
+		},
+		{
+			name:       "cluster without a region",
+			cluster:    Cluster{Identifier: "reports", Account: "210987654321"},
+			wantApp:    "account-210987654321-us-east-1",
+			wantRegion: "us-east-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			awsCfg := conn.ClusterAWSConfig(tt.cluster)
+			assert.Equal(t, tt.wantApp, awsCfg.AppID)
+			assert.Equal(t, tt.wantRegion, awsCfg.Region)
+		})
+	}
+
+	// The account's config is not modified
+	assert.Equal(t, "us-east-1", conn.accounts[0].aws.Region)
+}
+
+// fakeRDSHTTPClient answers the RDS DescribeDBClusters and DescribeDBInstances calls with the resource
+// ID of a cluster and an instance, and records the actions called.
+type fakeRDSHTTPClient struct {
+	actions []string
+}
+
+func (f *fakeRDSHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	action := form.Get("Action")
+	f.actions = append(f.actions, action)
+
+	var result string
+	switch action {
+	case "DescribeDBClusters":
+		result = "<DBClusters><DBCluster><DbClusterResourceId>cluster-ABC123</DbClusterResourceId></DBCluster></DBClusters>"
+	case "DescribeDBInstances":
+		result = "<DBInstances><DBInstance><DbiResourceId>db-XYZ789</DbiResourceId></DBInstance></DBInstances>"
+	default:
+		return nil, fmt.Errorf("unexpected action %s", action)
+	}
+	xml := fmt.Sprintf(`<%[1]sResponse xmlns="http://rds.amazonaws.com/doc/2014-10-31/"><%[1]sResult>%[2]s</%[1]sResult></%[1]sResponse>`,
+		action, result)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/xml"}},
+		Body:       io.NopCloser(strings.NewReader(xml)),
+		Request:    req,
+	}, nil
+}
+
+func TestResourceARN(t *testing.T) {
+	httpClient := &fakeRDSHTTPClient{}
+	awsCfg := sdkaws.Config{Region: "us-east-1", Credentials: sdkaws.AnonymousCredentials{}, HTTPClient: httpClient}
+	conn := &Connector{
+		accounts:    []*account{{aws: &aws.Config{Config: &awsCfg}, rds: rds.NewService(awsCfg, rds.CacheConfig{}, false)}},
+		resourceIDs: make(map[string]string),
+	}
+
+	// A standalone instance named like a cluster is told apart by its ARN
+	cluster := Cluster{Identifier: "orders", Arn: "arn:aws:rds:us-east-1:123456789012:cluster:orders", Region: "us-east-1"}
+	instance := Cluster{Identifier: "orders", Arn: "arn:aws:rds:us-east-1:123456789012:db:orders", Region: "us-east-1", Standalone: true}
+
+	arn, err := conn.ResourceARN(context.Background(), cluster, "app")
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:rds-db:*:*:dbuser:cluster-ABC123/app", arn)
+
+	arn, err = conn.ResourceARN(context.Background(), instance, "app")
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:rds-db:*:*:dbuser:db-XYZ789/app", arn)
+
+	// Resource IDs are cached per resource
+	arn, err = conn.ResourceARN(context.Background(), instance, "report")
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:rds-db:*:*:dbuser:db-XYZ789/report", arn)
+	assert.Equal(t, []string{"DescribeDBClusters", "DescribeDBInstances"}, httpClient.actions)
+}
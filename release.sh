@@ -92,6 +92,10 @@ build_binaries() {
         return
     fi
     
+    # Download the RDS CA bundle embedded in the binary
+    print_message "Downloading the RDS CA bundle..."
+    go generate ./internal/certs
+
     # Build only for macOS ARM64
     print_message "Building for macOS ARM64..."
     GOOS=darwin GOARCH=arm64 go build -o bin/rds-iam-connect-darwin-arm64